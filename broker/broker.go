@@ -0,0 +1,343 @@
+// Package broker implements the client of the sky-tailscale key broker,
+// the service that exchanges an account and password for a Tailscale
+// authentication key.
+package broker
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	neturl "net/url"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"tailscale/config"
+	"time"
+)
+
+const (
+	// DefaultMaxRetries is the number of retries after a failed attempt when Options.MaxRetries is zero
+	DefaultMaxRetries = 3
+
+	// DefaultInitialBackoff is the delay before the first retry
+	DefaultInitialBackoff = 500 * time.Millisecond
+
+	// DefaultMaxBackoff caps the delay between two retries
+	DefaultMaxBackoff = 8 * time.Second
+
+	// maxErrorBody limits how much of an error response is read
+	maxErrorBody = 64 << 10
+)
+
+// Options configures a Client.
+type Options struct {
+	URL            string        // Login endpoint of the broker
+	RefreshURL     string        // Refresh endpoint, derived from URL when empty
+	Timeout        time.Duration // Timeout of a single attempt, 0 for config.DefaultBrokerTimeout
+	CABundle       string        // Optional PEM file of CAs trusted for the broker
+	PinnedKeys     []string      // Optional base64 SHA-256 hashes of trusted public keys (SPKI)
+	MaxRetries     int           // Retries on network errors and 5xx responses, negative to disable
+	InitialBackoff time.Duration // Delay before the first retry, doubled after each retry
+	MaxBackoff     time.Duration // Upper bound of the retry delay; a longer Retry-After fails at once
+}
+
+// Client talks to a key broker. It retries transient failures with
+// exponential backoff, honours HTTPS_PROXY and can pin the broker's key.
+type Client struct {
+	url            string
+	refreshURL     string
+	httpClient     *http.Client
+	maxRetries     int
+	initialBackoff time.Duration
+	maxBackoff     time.Duration
+}
+
+// New creates a Client from opts.
+func New(opts Options) (*Client, error) {
+	if opts.URL == "" {
+		return nil, errors.New("broker URL is required")
+	}
+	refreshURL := opts.RefreshURL
+	if refreshURL == "" {
+		var err error
+		if refreshURL, err = defaultRefreshURL(opts.URL); err != nil {
+			return nil, err
+		}
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = http.ProxyFromEnvironment
+
+	tlsConfig := &tls.Config{MinVersion: tls.VersionTLS12}
+	if opts.CABundle != "" {
+		pem, err := os.ReadFile(opts.CABundle)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read CA bundle: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in CA bundle %s", opts.CABundle)
+		}
+		tlsConfig.RootCAs = pool
+	}
+	if len(opts.PinnedKeys) > 0 {
+		tlsConfig.VerifyConnection = verifyPinnedKeys(opts.PinnedKeys)
+	}
+	transport.TLSClientConfig = tlsConfig
+
+	timeout := opts.Timeout
+	if timeout <= 0 {
+		timeout = config.DefaultBrokerTimeout
+	}
+	maxRetries := opts.MaxRetries
+	switch {
+	case maxRetries == 0:
+		maxRetries = DefaultMaxRetries
+	case maxRetries < 0:
+		maxRetries = 0
+	}
+	initialBackoff := opts.InitialBackoff
+	if initialBackoff <= 0 {
+		initialBackoff = DefaultInitialBackoff
+	}
+	maxBackoff := opts.MaxBackoff
+	if maxBackoff <= 0 {
+		maxBackoff = DefaultMaxBackoff
+	}
+
+	return &Client{
+		url:            opts.URL,
+		refreshURL:     refreshURL,
+		httpClient:     &http.Client{Timeout: timeout, Transport: transport},
+		maxRetries:     maxRetries,
+		initialBackoff: initialBackoff,
+		maxBackoff:     maxBackoff,
+	}, nil
+}
+
+// NewFromProfile creates a Client for a broker profile of the config file.
+func NewFromProfile(profile *config.Profile) (*Client, error) {
+	return New(Options{
+		URL:        profile.URL,
+		RefreshURL: profile.RefreshURL,
+		Timeout:    profile.RequestTimeout(),
+		CABundle:   profile.CABundle,
+		PinnedKeys: profile.PinnedKeys,
+		MaxRetries: profile.MaxRetries,
+	})
+}
+
+// errPinMismatch is returned by the TLS handshake when no pinned key matches.
+var errPinMismatch = errors.New("broker certificate does not match any pinned key")
+
+// verifyPinnedKeys returns a TLS verification callback accepting only
+// certificate chains that contain one of the pinned public keys.
+func verifyPinnedKeys(pins []string) func(tls.ConnectionState) error {
+	allowed := make(map[string]bool, len(pins))
+	for _, pin := range pins {
+		allowed[strings.TrimPrefix(pin, "sha256/")] = true
+	}
+	return func(state tls.ConnectionState) error {
+		for _, cert := range state.PeerCertificates {
+			sum := sha256.Sum256(cert.RawSubjectPublicKeyInfo)
+			if allowed[base64.StdEncoding.EncodeToString(sum[:])] {
+				return nil
+			}
+		}
+		return errPinMismatch
+	}
+}
+
+// Grant is what the broker returns for a successful login or refresh.
+type Grant struct {
+	Key          string `json:"key"`                    // Tailscale authentication key
+	RefreshToken string `json:"refreshToken,omitempty"` // Token to obtain new keys without the password, if issued
+}
+
+// Login exchanges account and password for a Tailscale authentication key
+// and, if the broker issues one, a refresh token.
+func (c *Client) Login(ctx context.Context, account, password string) (*Grant, error) {
+	payload, err := json.Marshal(map[string]string{
+		"account":  account,
+		"password": password,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal credentials: %w", err)
+	}
+	return c.grant(ctx, c.url, payload)
+}
+
+// Refresh exchanges a refresh token for a new authentication key.
+// The returned grant may carry a rotated refresh token that replaces the old one.
+func (c *Client) Refresh(ctx context.Context, refreshToken string) (*Grant, error) {
+	payload, err := json.Marshal(map[string]string{
+		"refreshToken": refreshToken,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal refresh token: %w", err)
+	}
+	return c.grant(ctx, c.refreshURL, payload)
+}
+
+// grant posts payload to url and decodes the returned Grant.
+func (c *Client) grant(ctx context.Context, url string, payload []byte) (*Grant, error) {
+	var result Grant
+	if err := c.post(ctx, url, payload, &result); err != nil {
+		return nil, err
+	}
+	if result.Key == "" {
+		return nil, errors.New("broker response did not contain a key")
+	}
+	return &result, nil
+}
+
+// defaultRefreshURL derives the refresh endpoint from the login endpoint,
+// replacing its last path segment: /api/logIn becomes /api/refresh.
+func defaultRefreshURL(loginURL string) (string, error) {
+	parsed, err := neturl.Parse(loginURL)
+	if err != nil {
+		return "", fmt.Errorf("invalid broker URL: %w", err)
+	}
+	parsed.Path = path.Join(path.Dir(parsed.Path), "refresh")
+	return parsed.String(), nil
+}
+
+// post sends payload to url, retrying transient failures, and decodes a successful JSON response into result.
+func (c *Client) post(ctx context.Context, url string, payload []byte, result any) error {
+	backoff := c.initialBackoff
+	for attempt := 0; ; attempt++ {
+		retryAfter, err := c.attempt(ctx, url, payload, result)
+		if err == nil {
+			return nil
+		}
+		if attempt >= c.maxRetries || !retryable(err) || ctx.Err() != nil {
+			return err
+		}
+
+		// Waiting less than the broker asked for would only be refused again, and
+		// waiting longer than the cap would leave the user staring at a spinner
+		if retryAfter > c.maxBackoff {
+			return err
+		}
+		delay := retryAfter
+		if delay <= 0 {
+			delay = jitter(backoff)
+			backoff = min(backoff*2, c.maxBackoff)
+		}
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		case <-timer.C:
+		}
+	}
+}
+
+// attempt performs one request. It returns the delay requested by a Retry-After header, if any.
+func (c *Client) attempt(ctx context.Context, url string, payload []byte, result any) (time.Duration, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(payload))
+	if err != nil {
+		return 0, fmt.Errorf("failed to create login request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return 0, &networkError{err: err}
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return parseRetryAfter(resp.Header.Get("Retry-After")), decodeError(resp)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(result); err != nil {
+		return 0, fmt.Errorf("failed to decode response: %w", err)
+	}
+	return 0, nil
+}
+
+// networkError marks transport failures, which are retried.
+type networkError struct {
+	err error
+}
+
+// Error returns the message of the transport failure.
+func (e *networkError) Error() string {
+	return fmt.Sprintf("failed to send login request: %v", e.err)
+}
+
+// Unwrap returns the transport failure.
+func (e *networkError) Unwrap() error {
+	return e.err
+}
+
+// retryable reports whether a failed attempt may succeed when repeated.
+func retryable(err error) bool {
+	var netErr *networkError
+	if errors.As(err, &netErr) {
+		var tlsErr *tls.CertificateVerificationError
+		return !errors.As(err, &tlsErr) && !errors.Is(err, errPinMismatch)
+	}
+	var brokerErr *Error
+	if errors.As(err, &brokerErr) {
+		return brokerErr.StatusCode >= 500 || brokerErr.StatusCode == http.StatusTooManyRequests
+	}
+	return false
+}
+
+// jitter randomizes d by up to ±20% so clients do not retry in lockstep.
+func jitter(d time.Duration) time.Duration {
+	spread := int64(d) / 5
+	if spread <= 0 {
+		return d
+	}
+	return d + time.Duration(rand.Int63n(2*spread)-spread)
+}
+
+// parseRetryAfter parses a Retry-After header given in seconds or as an HTTP date.
+func parseRetryAfter(value string) time.Duration {
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds > 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	if date, err := http.ParseTime(value); err == nil {
+		return time.Until(date)
+	}
+	return 0
+}
+
+// decodeError builds an *Error from a non-200 response, using its JSON body when present.
+func decodeError(resp *http.Response) error {
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorBody))
+	brokerErr := &Error{StatusCode: resp.StatusCode}
+
+	var structured struct {
+		Error   string `json:"error"`
+		Code    string `json:"code"`
+		Message string `json:"message"`
+	}
+	if json.Unmarshal(body, &structured) == nil {
+		brokerErr.Code = structured.Code
+		if brokerErr.Code == "" {
+			brokerErr.Code = structured.Error
+		}
+		brokerErr.Message = structured.Message
+	} else if text := strings.TrimSpace(string(body)); text != "" && len(text) < 200 {
+		brokerErr.Message = text
+	}
+	return brokerErr
+}
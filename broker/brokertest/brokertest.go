@@ -0,0 +1,259 @@
+// Package brokertest provides an in-process key broker implementing the
+// /api/logIn and /api/refresh contracts, for development and integration tests that must not
+// reach the production broker.
+package brokertest
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Paths of the endpoints served by the mock broker
+const (
+	LoginPath   = "/api/logIn"   // Exchanges an account and password for a key and a refresh token
+	RefreshPath = "/api/refresh" // Exchanges a refresh token for a key and a rotated refresh token
+)
+
+// Scenario selects how the mock broker answers a login or refresh request.
+type Scenario int
+
+// Scenarios supported by the mock broker
+const (
+	Normal         Scenario = iota // Check credentials or token and return the key or 401
+	BadCredentials                 // Always reject the credentials with 401
+	RateLimited                    // Answer 429 with a Retry-After header
+	AccountLocked                  // Answer 423 with the account_locked code
+	ServerError                    // Answer 500
+	MalformedJSON                  // Answer 200 with a truncated JSON body
+	Slow                           // Wait for the configured delay, then behave like Normal
+)
+
+// Broker is a scripted key broker. The zero value is not usable; create it with New.
+type Broker struct {
+	mu       sync.Mutex
+	accounts map[string]string // account -> password
+	key      string            // key returned on successful logins
+	scenario Scenario          // default scenario
+	queue    []Scenario        // scenarios consumed by the next requests before the default applies
+	delay    time.Duration     // delay of the Slow scenario
+	wait     time.Duration     // Retry-After of the RateLimited scenario
+	tokens   map[string]string // refresh token -> account
+	issued   int               // number of refresh tokens issued
+	requests int               // number of requests received
+}
+
+// New creates a Broker accepting the given account and password and returning key on success.
+func New(account, password, key string) *Broker {
+	return &Broker{
+		accounts: map[string]string{account: password},
+		tokens:   make(map[string]string),
+		key:      key,
+		delay:    2 * time.Second,
+		wait:     time.Second,
+	}
+}
+
+// AddAccount registers another account accepted by the broker.
+func (b *Broker) AddAccount(account, password string) *Broker {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.accounts[account] = password
+	return b
+}
+
+// SetScenario sets the scenario applied once the queued ones are consumed.
+func (b *Broker) SetScenario(scenario Scenario) *Broker {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.scenario = scenario
+	return b
+}
+
+// Enqueue schedules scenarios for the next requests, one per request, e.g. to make the first attempts fail.
+func (b *Broker) Enqueue(scenarios ...Scenario) *Broker {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.queue = append(b.queue, scenarios...)
+	return b
+}
+
+// SetDelay sets how long the Slow scenario waits before answering.
+func (b *Broker) SetDelay(delay time.Duration) *Broker {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.delay = delay
+	return b
+}
+
+// SetRetryAfter sets the Retry-After header of the RateLimited scenario, in whole seconds.
+func (b *Broker) SetRetryAfter(wait time.Duration) *Broker {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.wait = wait
+	return b
+}
+
+// RevokeTokens invalidates every refresh token issued so far.
+func (b *Broker) RevokeTokens() *Broker {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.tokens = make(map[string]string)
+	return b
+}
+
+// Requests returns the number of login and refresh requests received so far.
+func (b *Broker) Requests() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.requests
+}
+
+// next counts a request and returns the scenario it must be answered with,
+// the delay of the Slow scenario and the Retry-After of the RateLimited one.
+func (b *Broker) next() (Scenario, time.Duration, time.Duration) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.requests++
+	if len(b.queue) > 0 {
+		scenario := b.queue[0]
+		b.queue = b.queue[1:]
+		return scenario, b.delay, b.wait
+	}
+	return b.scenario, b.delay, b.wait
+}
+
+// checkCredentials reports whether account and password match a registered account.
+func (b *Broker) checkCredentials(account, password string) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	expected, found := b.accounts[account]
+	return found && expected == password
+}
+
+// issueToken returns a new refresh token for account.
+func (b *Broker) issueToken(account string) string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.issued++
+	token := fmt.Sprintf("refresh-%d", b.issued)
+	b.tokens[token] = account
+	return token
+}
+
+// rotateToken revokes token and returns a new one for the same account.
+// It reports false if token is not valid.
+func (b *Broker) rotateToken(token string) (string, bool) {
+	b.mu.Lock()
+	account, found := b.tokens[token]
+	delete(b.tokens, token)
+	b.mu.Unlock()
+	if !found {
+		return "", false
+	}
+	return b.issueToken(account), true
+}
+
+// ServeHTTP implements the /api/logIn and /api/refresh contracts.
+func (b *Broker) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != LoginPath && r.URL.Path != RefreshPath {
+		http.NotFound(w, r)
+		return
+	}
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, "method_not_allowed", "use POST")
+		return
+	}
+
+	var credentials struct {
+		Account      string `json:"account"`
+		Password     string `json:"password"`
+		RefreshToken string `json:"refreshToken"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&credentials); err != nil {
+		writeError(w, http.StatusBadRequest, "bad_request", "request body must be JSON")
+		return
+	}
+
+	scenario, delay, wait := b.next()
+	if scenario == Slow {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-time.After(delay):
+		}
+		scenario = Normal
+	}
+
+	switch scenario {
+	case BadCredentials:
+		writeError(w, http.StatusUnauthorized, "invalid_credentials", "invalid account or password")
+	case RateLimited:
+		w.Header().Set("Retry-After", strconv.Itoa(int(wait/time.Second)))
+		writeError(w, http.StatusTooManyRequests, "rate_limited", "too many login attempts")
+	case AccountLocked:
+		writeError(w, http.StatusLocked, "account_locked", "account is locked")
+	case ServerError:
+		writeError(w, http.StatusInternalServerError, "internal", "internal server error")
+	case MalformedJSON:
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"key": "tskey-`)
+	case Normal:
+		var token string
+		if r.URL.Path == RefreshPath {
+			var valid bool
+			if token, valid = b.rotateToken(credentials.RefreshToken); !valid {
+				writeError(w, http.StatusUnauthorized, "invalid_credentials", "refresh token is invalid or expired")
+				return
+			}
+		} else {
+			if !b.checkCredentials(credentials.Account, credentials.Password) {
+				writeError(w, http.StatusUnauthorized, "invalid_credentials", "invalid account or password")
+				return
+			}
+			token = b.issueToken(credentials.Account)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]string{"key": b.key, "refreshToken": token})
+	}
+}
+
+// writeError sends a structured error body as understood by broker.Client.
+func writeError(w http.ResponseWriter, status int, code, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]string{"error": code, "message": message})
+}
+
+// Server is a Broker listening on a local httptest server.
+type Server struct {
+	*Broker
+	*httptest.Server
+}
+
+// NewServer starts a mock broker on a random local port. Close it when done.
+func NewServer(account, password, key string) *Server {
+	b := New(account, password, key)
+	return &Server{Broker: b, Server: httptest.NewServer(b)}
+}
+
+// NewTLSServer is like NewServer but serves HTTPS with a self-signed certificate.
+// Use the Client or Certificate of the embedded httptest.Server to trust it.
+func NewTLSServer(account, password, key string) *Server {
+	b := New(account, password, key)
+	return &Server{Broker: b, Server: httptest.NewTLSServer(b)}
+}
+
+// LoginURL returns the login endpoint of the server, suitable for a broker profile URL.
+func (s *Server) LoginURL() string {
+	return s.URL + LoginPath
+}
+
+// RefreshURL returns the refresh endpoint of the server.
+func (s *Server) RefreshURL() string {
+	return s.URL + RefreshPath
+}
@@ -0,0 +1,76 @@
+package broker
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// Error codes sent by the broker in the "error" or "code" field of error responses
+const (
+	CodeInvalidCredentials = "invalid_credentials" // Unknown account or wrong password
+	CodeInvalidPassword    = "invalid_password"    // Wrong password for an existing account
+	CodeAccountLocked      = "account_locked"      // Account disabled after too many failures
+	CodeRateLimited        = "rate_limited"        // Too many requests from this client
+)
+
+// Error is a failure reported by the broker.
+type Error struct {
+	StatusCode int    // HTTP status of the response
+	Code       string // Machine-readable error code, may be empty
+	Message    string // Human-readable message from the broker, may be empty
+}
+
+// Error returns the broker message, falling back to a description of the status code.
+func (e *Error) Error() string {
+	switch {
+	case e.Message != "":
+		return "login failed: " + e.Message
+	case e.IsInvalidCredentials():
+		return "login failed: invalid account or password"
+	case e.IsAccountLocked():
+		return "login failed: account is locked"
+	case e.IsRateLimited():
+		return "login failed: too many attempts, try again later"
+	}
+	return fmt.Sprintf("login failed with status: %d", e.StatusCode)
+}
+
+// IsInvalidCredentials reports whether the account or password was rejected.
+func (e *Error) IsInvalidCredentials() bool {
+	switch e.Code {
+	case CodeInvalidCredentials, CodeInvalidPassword:
+		return true
+	case "":
+		return e.StatusCode == http.StatusUnauthorized
+	}
+	return false
+}
+
+// IsAccountLocked reports whether the account is locked.
+func (e *Error) IsAccountLocked() bool {
+	return e.Code == CodeAccountLocked || e.Code == "" && e.StatusCode == http.StatusLocked
+}
+
+// IsRateLimited reports whether the client sent too many requests.
+func (e *Error) IsRateLimited() bool {
+	return e.Code == CodeRateLimited || e.Code == "" && e.StatusCode == http.StatusTooManyRequests
+}
+
+// IsInvalidCredentials reports whether err is a broker rejection of the account or password.
+func IsInvalidCredentials(err error) bool {
+	var brokerErr *Error
+	return errors.As(err, &brokerErr) && brokerErr.IsInvalidCredentials()
+}
+
+// IsAccountLocked reports whether err is a broker report of a locked account.
+func IsAccountLocked(err error) bool {
+	var brokerErr *Error
+	return errors.As(err, &brokerErr) && brokerErr.IsAccountLocked()
+}
+
+// IsRateLimited reports whether err is a broker report of too many requests.
+func IsRateLimited(err error) bool {
+	var brokerErr *Error
+	return errors.As(err, &brokerErr) && brokerErr.IsRateLimited()
+}
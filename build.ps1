@@ -5,6 +5,12 @@ param(
 Write-Host "🚀 Starting build process..." -ForegroundColor Green
 Write-Host "UPX Compression: $(if ($UseUpx) { 'Enabled' } else { 'Disabled' })" -ForegroundColor Yellow
 
+# Embed the release version, used by self-update to compare with the release feed
+$version = git describe --tags --always 2>$null
+if (-not $version) { $version = "dev" }
+$ldflags = "-s -w -X tailscale/selfupdate.Version=$version"
+Write-Host "Version: $version" -ForegroundColor Yellow
+
 # Check if go-winres is installed
 $goWinres = Get-Command go-winres -ErrorAction SilentlyContinue
 if (-not $goWinres) {
@@ -14,18 +20,18 @@ if (-not $goWinres) {
 
 # Add icon to Windows executable
 Write-Host "Adding icon to Windows executable..." -ForegroundColor Cyan
-go build -ldflags "-s -w" -o sky-tailscale.exe main.go
+go build -ldflags $ldflags -o sky-tailscale.exe main.go
 go-winres simply --icon ./img/sky-tailscale-icon.png
 
 # Build Windows version
 Write-Host "Building Windows executable..." -ForegroundColor Cyan
-go build -ldflags "-s -w" -o sky-tailscale.exe
+go build -ldflags $ldflags -o sky-tailscale.exe
 
 # Build Linux version
 Write-Host "Building Linux executable..." -ForegroundColor Cyan
 $env:GOOS = "linux"
 $env:GOARCH = "amd64"
-go build -ldflags "-s -w" -o sky-tailscale-linux main.go
+go build -ldflags $ldflags -o sky-tailscale-linux main.go
 
 # Reset GOOS and GOARCH
 $env:GOOS = "windows"
@@ -46,10 +52,17 @@ if ($UseUpx) {
     Write-Host "Skipping UPX compression (not requested)." -ForegroundColor Yellow
 }
 
+# Publish checksums.txt with the release so self-update can verify the binaries
+Write-Host "Writing checksums.txt..." -ForegroundColor Cyan
+Get-FileHash -Algorithm SHA256 sky-tailscale.exe, sky-tailscale-linux | ForEach-Object {
+    "$($_.Hash.ToLower())  $(Split-Path $_.Path -Leaf)"
+} | Set-Content -Encoding ascii checksums.txt
+
 Write-Host "✅ Build process completed!" -ForegroundColor Green
 Write-Host "Generated files:" -ForegroundColor Cyan
 Write-Host "- sky-tailscale.exe (Windows)" -ForegroundColor White
 Write-Host "- sky-tailscale-linux (Linux)" -ForegroundColor White
+Write-Host "- checksums.txt (SHA-256 of both, attach it to the release)" -ForegroundColor White
 
 # Clean up resource files
 Remove-Item -Path "rsrc_windows_amd64.syso" -ErrorAction SilentlyContinue
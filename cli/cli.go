@@ -0,0 +1,222 @@
+// Package cli provides the non-interactive command-line mode.
+// Every subcommand mirrors an item of the terminal menu and runs the same
+// utils operations without initializing the drawer, so the tool can be
+// scripted from CI jobs or login scripts.
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"sort"
+	"tailscale/utils"
+)
+
+// Exit codes returned by Run
+const (
+	ExitOK               = 0 // The command succeeded
+	ExitError            = 1 // The command failed for an unclassified reason
+	ExitUsage            = 2 // The command line was invalid
+	ExitNotLoggedIn      = 3 // The machine is not logged in to Tailscale
+	ExitDaemonNotRunning = 4 // tailscaled is not running
+	ExitPermissionDenied = 5 // The user lacks the privileges required by the command
+	ExitUnknownAccount   = 6 // The requested account does not exist
+)
+
+// command describes a single CLI subcommand.
+type command struct {
+	usage string                       // Argument synopsis shown in help
+	help  string                       // One-line description shown in help
+	run   func(env *environment) error // Implementation of the command
+}
+
+// commands maps subcommand names to their implementation.
+var commands map[string]command
+
+func init() {
+	commands = map[string]command{
+		"connect":     {"[--account NAME] [--password-stdin | --authkey KEY]", "Log in to Tailscale through the key broker", runConnect},
+		"switch":      {"<account>", "Switch to another logged-in account", runSwitch},
+		"signout":     {"", "Sign out of the current account", runSignOut},
+		"info":        {"[--sort COLUMN]", "Show Tailscale IPs and peer status", runInfo},
+		"rdp":         {"[host]", "Open Remote Desktop, optionally connecting to host", runRdp},
+		"accounts":    {"", "List logged-in accounts", runAccounts},
+		"profiles":    {"", "List key broker profiles", runProfiles},
+		"forget":      {"[--all]", "Forget saved broker credentials", runForget},
+		"install":     {"[--dry-run] [--channel NAME] [--version X.Y.Z] [--offline | --from FILE]", "Install Tailscale with the distribution's package manager (Linux)", runInstall},
+		"cache":       {"[list | fetch | clear] [--platform LIST] [--version X.Y.Z]", "Manage the installer cache used for offline installs", runCache},
+		"versions":    {"[--channel NAME]", "List the Tailscale versions available for install", runVersions},
+		"self-update": {"[--check]", "Update sky-tailscale to the latest release", runSelfUpdate},
+		"health":      {"[--start]", "Show the state of tailscaled, optionally starting its service", runHealth},
+		"help":        {"", "Show this help", runHelp},
+	}
+}
+
+// environment carries the parsed state of one CLI invocation.
+type environment struct {
+	ctx    context.Context // Cancelled on interrupt
+	args   []string        // Arguments following the subcommand name
+	flags  *flag.FlagSet   // Flags of the running subcommand
+	json   bool            // Whether output is JSON
+	stdin  io.Reader       // Input, used for --password-stdin
+	stdout io.Writer       // Destination of command output
+	stderr io.Writer       // Destination of errors and usage in text mode
+}
+
+// usageError marks errors caused by an invalid command line.
+type usageError struct {
+	msg string
+}
+
+// Error returns the usage error message.
+func (e *usageError) Error() string {
+	return e.msg
+}
+
+// newUsageError creates a usageError with a formatted message.
+func newUsageError(format string, args ...any) error {
+	return &usageError{msg: fmt.Sprintf(format, args...)}
+}
+
+// Run executes the subcommand named by args[0] with the remaining arguments
+// and returns the process exit code. Output goes to stdout and stderr.
+func Run(args []string) int {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	return run(ctx, args, os.Stdin, os.Stdout, os.Stderr)
+}
+
+// run executes a subcommand with an explicit context and explicit streams.
+func run(ctx context.Context, args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	if len(args) == 0 || args[0] == "-h" || args[0] == "--help" {
+		args = []string{"help"}
+	}
+
+	cmd, found := commands[args[0]]
+	if !found {
+		fmt.Fprintf(stderr, "unknown command: %s\n", args[0])
+		printUsage(stderr)
+		return ExitUsage
+	}
+
+	env := &environment{
+		ctx:    ctx,
+		args:   args[1:],
+		flags:  flag.NewFlagSet(args[0], flag.ContinueOnError),
+		stdin:  stdin,
+		stdout: stdout,
+		stderr: stderr,
+	}
+	env.flags.SetOutput(io.Discard)
+	env.flags.BoolVar(&env.json, "json", false, "print machine-readable JSON output")
+	env.flags.Usage = func() {}
+	err := cmd.run(env)
+	return env.finish(err)
+}
+
+// parse parses the subcommand arguments, accepting flags before and after positional arguments.
+// Subcommands register their own flags on env.flags before calling it.
+func (env *environment) parse() ([]string, error) {
+	args := env.args
+	var positional []string
+	for {
+		if err := env.flags.Parse(args); err != nil {
+			return nil, newUsageError("%s: %v", env.flags.Name(), err)
+		}
+		args = env.flags.Args()
+		if len(args) == 0 {
+			return positional, nil
+		}
+		positional = append(positional, args[0])
+		args = args[1:]
+	}
+}
+
+// finish prints err, if any, in the selected output format and maps it to an exit code.
+func (env *environment) finish(err error) int {
+	if err == nil {
+		return ExitOK
+	}
+
+	code := exitCode(err)
+	hint := utils.Hint(err)
+	if env.json {
+		env.printJSON(struct {
+			Error    string `json:"error"`
+			Hint     string `json:"hint,omitempty"`
+			ExitCode int    `json:"exitCode"`
+		}{err.Error(), hint, code})
+		return code
+	}
+
+	fmt.Fprintf(env.stderr, "Error: %v\n", err)
+	if hint != "" {
+		fmt.Fprintln(env.stderr, hint)
+	}
+	if code == ExitUsage {
+		printUsage(env.stderr)
+	}
+	return code
+}
+
+// exitCode maps an error to the exit code documented for its class.
+func exitCode(err error) int {
+	var usageErr *usageError
+	switch {
+	case errors.As(err, &usageErr):
+		return ExitUsage
+	case utils.IsDaemonNotRunning(err):
+		return ExitDaemonNotRunning
+	case utils.IsPermissionDenied(err):
+		return ExitPermissionDenied
+	case utils.IsUnknownAccount(err):
+		return ExitUnknownAccount
+	case utils.IsNotLoggedIn(err):
+		return ExitNotLoggedIn
+	}
+	return ExitError
+}
+
+// log returns where progress of long-running commands goes: stdout, or stderr
+// in JSON mode so stdout only holds the result.
+func (env *environment) log() io.Writer {
+	if env.json {
+		return env.stderr
+	}
+	return env.stdout
+}
+
+// printJSON writes value as indented JSON to stdout.
+func (env *environment) printJSON(value any) {
+	encoder := json.NewEncoder(env.stdout)
+	encoder.SetIndent("", "  ")
+	encoder.Encode(value)
+}
+
+// printUsage writes the list of subcommands to w.
+func printUsage(w io.Writer) {
+	fmt.Fprintln(w, "Usage: sky-tailscale [--config FILE] [--profile NAME] [command] [--json] [arguments]")
+	fmt.Fprintln(w, "Without a command the interactive menu is started.")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Commands:")
+
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	nameWidth, usageWidth := 0, 0
+	for _, name := range names {
+		nameWidth = max(nameWidth, len(name))
+		usageWidth = max(usageWidth, len(commands[name].usage))
+	}
+	for _, name := range names {
+		cmd := commands[name]
+		fmt.Fprintf(w, "  %-*s %-*s %s\n", nameWidth, name, usageWidth, cmd.usage, cmd.help)
+	}
+}
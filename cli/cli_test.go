@@ -0,0 +1,297 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"reflect"
+	"runtime"
+	"sort"
+	"strings"
+	"testing"
+
+	"tailscale/utils"
+	"tailscale/utils/fakerunner"
+)
+
+// useRunner makes the utils package run fake instead of tailscale until the test ends.
+func useRunner(t *testing.T, fake *fakerunner.Fake) *fakerunner.Fake {
+	t.Helper()
+	previous := utils.SetRunner(fake)
+	t.Cleanup(func() { utils.SetRunner(previous) })
+	return fake
+}
+
+// runCLI runs the command line args with stdin and returns the exit code and both outputs.
+func runCLI(stdin string, args ...string) (int, string, string) {
+	var stdout, stderr bytes.Buffer
+	code := run(context.Background(), args, strings.NewReader(stdin), &stdout, &stderr)
+	return code, stdout.String(), stderr.String()
+}
+
+func TestRunExitCodes(t *testing.T) {
+	tests := []struct {
+		name       string
+		fake       *fakerunner.Fake
+		args       []string
+		want       int
+		wantStdout string
+		wantStderr string
+	}{
+		{name: "help", args: []string{"help"}, want: ExitOK, wantStdout: "Commands:"},
+		{name: "no command", want: ExitOK, wantStdout: "Commands:"},
+		{name: "unknown command", args: []string{"bogus"}, want: ExitUsage, wantStderr: "unknown command: bogus"},
+		{name: "unknown flag", args: []string{"signout", "--bogus"}, want: ExitUsage, wantStderr: "flag provided but not defined: -bogus"},
+		{name: "unexpected argument", args: []string{"signout", "now"}, want: ExitUsage, wantStderr: `signout: unexpected argument "now"`},
+		{name: "missing argument", args: []string{"switch"}, want: ExitUsage, wantStderr: "switch: exactly one account is required"},
+		{name: "invalid sort column", args: []string{"info", "--sort", "size"}, want: ExitUsage, wantStderr: "info:"},
+		{name: "invalid channel", args: []string{"versions", "--channel", "nightly"}, want: ExitUsage, wantStderr: "versions:"},
+		{name: "connect without account", args: []string{"connect", "--account", "alice"}, want: ExitUsage, wantStderr: "--password-stdin or SKY_TAILSCALE_PASSWORD is required"},
+		{
+			name: "signed out",
+			fake: fakerunner.New().On("", "logout"),
+			args: []string{"signout"},
+			want: ExitOK,
+		},
+		{
+			name:       "connected with an auth key",
+			fake:       fakerunner.New().On("Success.\n", "login", "--authkey", "tskey-auth-test"),
+			args:       []string{"connect", "--authkey", "tskey-auth-test"},
+			want:       ExitOK,
+			wantStdout: "Success.",
+		},
+		{
+			name:       "switched",
+			fake:       fakerunner.New().On("Switching to account \"bob@example.com\"\n", "switch", "bob@example.com"),
+			args:       []string{"switch", "bob@example.com"},
+			want:       ExitOK,
+			wantStdout: "bob@example.com",
+		},
+		{
+			name:       "failed",
+			fake:       fakerunner.New().Fail(1, "unexpected failure\n", "logout"),
+			args:       []string{"signout"},
+			want:       ExitError,
+			wantStderr: "Error: ",
+		},
+		{
+			name: "not logged in",
+			fake: fakerunner.New().Fail(1, fakerunner.NotLoggedIn, "ip"),
+			args: []string{"info"},
+			want: ExitNotLoggedIn,
+		},
+		{
+			name: "daemon not running",
+			fake: fakerunner.New().Fail(1, fakerunner.DaemonNotRunning, "logout"),
+			args: []string{"signout"},
+			want: ExitDaemonNotRunning,
+		},
+		{
+			name: "permission denied",
+			fake: fakerunner.New().Fail(1, fakerunner.PermissionDenied, "logout"),
+			args: []string{"signout"},
+			want: ExitPermissionDenied,
+		},
+		{
+			name: "unknown account",
+			fake: fakerunner.New().Fail(1, fakerunner.UnknownAccount, "switch", "nobody@example.com"),
+			args: []string{"switch", "nobody@example.com"},
+			want: ExitUnknownAccount,
+		},
+		{
+			name: "health without daemon",
+			fake: fakerunner.New().Fail(1, fakerunner.DaemonNotRunning, "status", "--json"),
+			args: []string{"health"},
+			want: ExitDaemonNotRunning,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := tt.fake
+			if fake == nil {
+				fake = fakerunner.New()
+			}
+			useRunner(t, fake)
+
+			code, stdout, stderr := runCLI("", tt.args...)
+			if code != tt.want {
+				t.Errorf("exit code = %d, want %d\nstdout: %s\nstderr: %s", code, tt.want, stdout, stderr)
+			}
+			if !strings.Contains(stdout, tt.wantStdout) {
+				t.Errorf("stdout = %q, want it to contain %q", stdout, tt.wantStdout)
+			}
+			if !strings.Contains(stderr, tt.wantStderr) {
+				t.Errorf("stderr = %q, want it to contain %q", stderr, tt.wantStderr)
+			}
+			if tt.want == ExitUsage && !strings.Contains(stderr, "Commands:") {
+				t.Errorf("usage not printed after a usage error:\n%s", stderr)
+			}
+		})
+	}
+}
+
+// keys returns the sorted keys of a JSON object.
+func keys(object map[string]any) []string {
+	var names []string
+	for name := range object {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func TestRunJSON(t *testing.T) {
+	tests := []struct {
+		name     string
+		fake     *fakerunner.Fake
+		args     []string
+		want     int
+		wantKeys []string
+		check    func(t *testing.T, object map[string]any)
+	}{
+		{
+			name:     "result",
+			fake:     fakerunner.New().On("Logged out.\n", "logout"),
+			args:     []string{"signout", "--json"},
+			wantKeys: []string{"ok", "output"},
+			check: func(t *testing.T, object map[string]any) {
+				if object["ok"] != true || object["output"] != "Logged out." {
+					t.Errorf("result = %v", object)
+				}
+			},
+		},
+		{
+			name:     "accounts",
+			fake:     fakerunner.New().On(fakerunner.SwitchList("alice@example.com", "alice@example.com", "bob@example.com"), "switch", "--list"),
+			args:     []string{"accounts", "--json"},
+			wantKeys: []string{"accounts", "current"},
+			check: func(t *testing.T, object map[string]any) {
+				want := []any{"alice@example.com", "bob@example.com"}
+				if object["current"] != "alice@example.com" || !reflect.DeepEqual(object["accounts"], want) {
+					t.Errorf("accounts = %v", object)
+				}
+			},
+		},
+		{
+			name:     "no accounts",
+			fake:     fakerunner.New().On(fakerunner.SwitchListEmpty, "switch", "--list"),
+			args:     []string{"accounts", "--json"},
+			wantKeys: []string{"accounts", "current"},
+			check: func(t *testing.T, object map[string]any) {
+				if accounts, ok := object["accounts"].([]any); !ok || len(accounts) != 0 {
+					t.Errorf("accounts = %v, want an empty list", object["accounts"])
+				}
+			},
+		},
+		{
+			name:     "info",
+			fake:     fakerunner.New().On(fakerunner.IP, "ip").On(fakerunner.Status("laptop"), "status", "--json"),
+			args:     []string{"info", "--json"},
+			wantKeys: []string{"ips", "status"},
+			check: func(t *testing.T, object map[string]any) {
+				if ips, _ := object["ips"].([]any); len(ips) != 2 || ips[0] != "100.101.102.103" {
+					t.Errorf("ips = %v", object["ips"])
+				}
+			},
+		},
+		{
+			name:     "health",
+			fake:     fakerunner.New().On(fakerunner.Status("laptop"), "status", "--json"),
+			args:     []string{"health", "--json"},
+			wantKeys: []string{"backendState", "state"},
+			check: func(t *testing.T, object map[string]any) {
+				if object["state"] != "running" || object["backendState"] != "Running" {
+					t.Errorf("health = %v", object)
+				}
+			},
+		},
+		{
+			name:     "profiles",
+			args:     []string{"profiles", "--json"},
+			wantKeys: []string{"profiles", "selected"},
+		},
+		{
+			name:     "error",
+			fake:     fakerunner.New().Fail(1, fakerunner.DaemonNotRunning, "logout"),
+			args:     []string{"signout", "--json"},
+			want:     ExitDaemonNotRunning,
+			wantKeys: []string{"error", "exitCode", "hint"},
+			check: func(t *testing.T, object map[string]any) {
+				if object["exitCode"] != float64(ExitDaemonNotRunning) || object["hint"] != utils.Hint(&utils.CommandError{Stderr: fakerunner.DaemonNotRunning}) {
+					t.Errorf("error = %v", object)
+				}
+			},
+		},
+		{
+			name:     "usage error",
+			args:     []string{"switch", "--json"},
+			want:     ExitUsage,
+			wantKeys: []string{"error", "exitCode"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := tt.fake
+			if fake == nil {
+				fake = fakerunner.New()
+			}
+			useRunner(t, fake)
+
+			code, stdout, stderr := runCLI("", tt.args...)
+			if code != tt.want {
+				t.Errorf("exit code = %d, want %d", code, tt.want)
+			}
+			// Only the document goes to stdout, so it can be piped to a JSON parser
+			var object map[string]any
+			if err := json.Unmarshal([]byte(stdout), &object); err != nil {
+				t.Fatalf("stdout is not a JSON object: %v\n%s", err, stdout)
+			}
+			if stderr != "" {
+				t.Errorf("stderr = %q, want nothing in JSON mode", stderr)
+			}
+			if got := keys(object); !reflect.DeepEqual(got, tt.wantKeys) {
+				t.Errorf("keys = %v, want %v", got, tt.wantKeys)
+			}
+			if tt.check != nil {
+				tt.check(t, object)
+			}
+		})
+	}
+}
+
+func TestRunPasswordStdin(t *testing.T) {
+	useRunner(t, fakerunner.New())
+	// An empty line is no password
+	code, _, stderr := runCLI("\n", "connect", "--account", "alice", "--password-stdin")
+	if code != ExitUsage || !strings.Contains(stderr, "--password-stdin or SKY_TAILSCALE_PASSWORD is required") {
+		t.Errorf("exit code = %d, stderr = %q", code, stderr)
+	}
+}
+
+func TestRunOnOtherOS(t *testing.T) {
+	t.Run("rdp", func(t *testing.T) {
+		if runtime.GOOS == "windows" {
+			t.Skip("mstsc.exe may be started on Windows")
+		}
+		code, _, stderr := runCLI("", "rdp", "server")
+		if code != ExitError || !strings.Contains(stderr, utils.ErrNotWindows.Error()) {
+			t.Errorf("exit code = %d, stderr = %q", code, stderr)
+		}
+	})
+
+	t.Run("install", func(t *testing.T) {
+		previous := goos
+		goos = "windows"
+		t.Cleanup(func() { goos = previous })
+		code, stdout, stderr := runCLI("", "install", "--dry-run")
+		if code != ExitError || !strings.Contains(stderr, "install is only available on Linux") || stdout != "" {
+			t.Errorf("exit code = %d, stdout = %q, stderr = %q", code, stdout, stderr)
+		}
+	})
+
+	t.Run("rdp arguments", func(t *testing.T) {
+		if code, _, _ := runCLI("", "rdp", "a", "b"); code != ExitUsage {
+			t.Errorf("exit code = %d, want %d", code, ExitUsage)
+		}
+	})
+}
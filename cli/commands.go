@@ -0,0 +1,557 @@
+package cli
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"runtime"
+	"strings"
+	"tailscale/config"
+	"tailscale/download"
+	"tailscale/selfupdate"
+	"tailscale/utils"
+)
+
+// Environment variables read by the connect command
+const (
+	AccountEnv  = "SKY_TAILSCALE_ACCOUNT"  // Broker account used when --account is not given
+	PasswordEnv = "SKY_TAILSCALE_PASSWORD" // Broker password used when --password-stdin is not given
+	AuthKeyEnv  = "TS_AUTHKEY"             // Tailscale auth key used when --authkey is not given
+)
+
+// goos is the operating system commands check they are available on, a variable so tests can change it.
+var goos = runtime.GOOS
+
+// result is the JSON document printed by commands that only report success.
+type result struct {
+	OK     bool   `json:"ok"`
+	Output string `json:"output,omitempty"`
+}
+
+// printResult prints a successful command result in the selected format.
+func (env *environment) printResult(output string) {
+	output = strings.TrimSpace(output)
+	if env.json {
+		env.printJSON(result{OK: true, Output: output})
+		return
+	}
+	if output != "" {
+		fmt.Fprintln(env.stdout, output)
+	}
+}
+
+// runConnect logs in with an auth key obtained from the key broker or passed directly.
+func runConnect(env *environment) error {
+	account := env.flags.String("account", os.Getenv(AccountEnv), "broker account name")
+	passwordStdin := env.flags.Bool("password-stdin", false, "read the broker password from stdin")
+	authKey := env.flags.String("authkey", os.Getenv(AuthKeyEnv), "Tailscale auth key, skipping the broker")
+	if args, err := env.parse(); err != nil {
+		return err
+	} else if len(args) > 0 {
+		return newUsageError("connect: unexpected argument %q", args[0])
+	}
+
+	key := *authKey
+	if key == "" && *account == "" {
+		var err error
+		if key, _, err = utils.RequestKeyWithSavedSession(env.ctx, config.Get().Selected()); err != nil {
+			return err
+		}
+		if key == "" {
+			return newUsageError("connect: --account or %s is required when no credentials are saved", AccountEnv)
+		}
+	}
+	if key == "" {
+		password := os.Getenv(PasswordEnv)
+		if *passwordStdin {
+			line, err := bufio.NewReader(env.stdin).ReadString('\n')
+			if err != nil && line == "" {
+				return fmt.Errorf("failed to read password from stdin: %w", err)
+			}
+			password = strings.TrimRight(line, "\r\n")
+		}
+		if password == "" {
+			return newUsageError("connect: --password-stdin or %s is required", PasswordEnv)
+		}
+
+		var err error
+		if key, err = utils.RequestKey(env.ctx, config.Get().Selected(), *account, password); err != nil {
+			return err
+		}
+	}
+
+	output, err := utils.LoginWithKey(env.ctx, key)
+	if err != nil {
+		return err
+	}
+	env.printResult(output)
+	return nil
+}
+
+// runForget removes the saved broker credentials of the selected profile, or of all profiles with --all.
+func runForget(env *environment) error {
+	all := env.flags.Bool("all", false, "forget the credentials of every profile")
+	if args, err := env.parse(); err != nil {
+		return err
+	} else if len(args) > 0 {
+		return newUsageError("forget: unexpected argument %q", args[0])
+	}
+
+	profile := config.Get().Selected()
+	if *all {
+		profile = nil
+	}
+	if err := utils.ForgetCredentials(profile); err != nil {
+		return err
+	}
+	env.printResult("Saved credentials removed.")
+	return nil
+}
+
+// runSwitch makes another logged-in account the active one.
+func runSwitch(env *environment) error {
+	args, err := env.parse()
+	if err != nil {
+		return err
+	}
+	if len(args) != 1 {
+		return newUsageError("switch: exactly one account is required")
+	}
+
+	output, err := utils.SwitchTo(env.ctx, args[0])
+	if err != nil {
+		return err
+	}
+	env.printResult(output)
+	return nil
+}
+
+// runSignOut logs the current account out.
+func runSignOut(env *environment) error {
+	if args, err := env.parse(); err != nil {
+		return err
+	} else if len(args) > 0 {
+		return newUsageError("signout: unexpected argument %q", args[0])
+	}
+
+	output, err := utils.SignOut(env.ctx)
+	if err != nil {
+		return err
+	}
+	env.printResult(output)
+	return nil
+}
+
+// runInfo prints the Tailscale IPs and the peer table.
+func runInfo(env *environment) error {
+	sortColumn := env.flags.String("sort", utils.SortByName.String(), "column used to sort peers")
+	if args, err := env.parse(); err != nil {
+		return err
+	} else if len(args) > 0 {
+		return newUsageError("info: unexpected argument %q", args[0])
+	}
+	sortKey, err := utils.ParseSortKey(*sortColumn)
+	if err != nil {
+		return newUsageError("info: %v", err)
+	}
+
+	ips, err := utils.GetIPs(env.ctx)
+	if err != nil {
+		return err
+	}
+	status, err := utils.GetStatusContext(env.ctx)
+	if err != nil {
+		return err
+	}
+	utils.SortPeers(status.Peers, sortKey)
+
+	if env.json {
+		env.printJSON(struct {
+			IPs    []string               `json:"ips"`
+			Status *utils.TailscaleStatus `json:"status"`
+		}{ips, status})
+		return nil
+	}
+
+	fmt.Fprintf(env.stdout, "My IP: %s\n", strings.Join(ips, " "))
+	for _, line := range utils.StatusLines(status, sortKey) {
+		fmt.Fprintln(env.stdout, line)
+	}
+	return nil
+}
+
+// runRdp starts the Remote Desktop client.
+func runRdp(env *environment) error {
+	args, err := env.parse()
+	if err != nil {
+		return err
+	}
+	if len(args) > 1 {
+		return newUsageError("rdp: at most one host is allowed")
+	}
+
+	host := ""
+	if len(args) == 1 {
+		host = args[0]
+	}
+	if err := utils.StartMstsc(host); err != nil {
+		return err
+	}
+	env.printResult("")
+	return nil
+}
+
+// runAccounts lists the logged-in accounts, marking the active one.
+func runAccounts(env *environment) error {
+	if args, err := env.parse(); err != nil {
+		return err
+	} else if len(args) > 0 {
+		return newUsageError("accounts: unexpected argument %q", args[0])
+	}
+
+	accounts, err := utils.GetAccounts()
+	if err != nil {
+		return err
+	}
+
+	if env.json {
+		all := accounts.AllAccounts
+		if all == nil {
+			all = []string{}
+		}
+		env.printJSON(struct {
+			Current  string   `json:"current"`
+			Accounts []string `json:"accounts"`
+		}{accounts.CurrentAccount, all})
+		return nil
+	}
+
+	for _, account := range accounts.AllAccounts {
+		marker := " "
+		if account == accounts.CurrentAccount {
+			marker = "*"
+		}
+		fmt.Fprintf(env.stdout, "%s %s\n", marker, account)
+	}
+	return nil
+}
+
+// runProfiles lists the key broker profiles, marking the selected one.
+func runProfiles(env *environment) error {
+	if args, err := env.parse(); err != nil {
+		return err
+	} else if len(args) > 0 {
+		return newUsageError("profiles: unexpected argument %q", args[0])
+	}
+
+	cfg := config.Get()
+	selected := cfg.Selected()
+	if env.json {
+		env.printJSON(struct {
+			Selected string           `json:"selected"`
+			Profiles []config.Profile `json:"profiles"`
+		}{selected.Name, cfg.Profiles})
+		return nil
+	}
+
+	for _, profile := range cfg.Profiles {
+		marker := " "
+		if profile.Name == selected.Name {
+			marker = "*"
+		}
+		fmt.Fprintf(env.stdout, "%s %-12s %-24s %s\n", marker, profile.Name, profile.Title(), profile.URL)
+	}
+	return nil
+}
+
+// runInstall installs Tailscale with the package manager of the Linux distribution,
+// or only prints the plan with --dry-run. --from and --offline install the static
+// binaries from a tarball on disk instead, without network access.
+func runInstall(env *environment) error {
+	dryRun := env.flags.Bool("dry-run", false, "print the installation plan without running it")
+	defaults := utils.InstallTarget()
+	channel := env.flags.String("channel", defaults.Channel, "release channel: stable or unstable")
+	version := env.flags.String("version", defaults.Version, "exact version to install, e.g. 1.76.6")
+	mirror := env.flags.String("mirror", defaults.Mirror, "mirror with the layout of pkgs.tailscale.com")
+	offline := env.flags.Bool("offline", config.Get().Install.Offline, "install from the installer cache only")
+	from := env.flags.String("from", "", "install from this tarball instead of downloading")
+	checksum := env.flags.String("sha256", "", "expected SHA-256 of --from, read from FILE.sha256 when empty")
+	if args, err := env.parse(); err != nil {
+		return err
+	} else if len(args) > 0 {
+		return newUsageError("install: unexpected argument %q", args[0])
+	}
+	target, err := download.ParseTarget(*channel, *version)
+	if err != nil {
+		return newUsageError("install: %v", err)
+	}
+	target.Mirror = *mirror
+	if *checksum != "" && *from == "" {
+		return newUsageError("install: --sha256 requires --from")
+	}
+	if goos != "linux" {
+		return fmt.Errorf("install is only available on Linux, start the menu to install on %s", goos)
+	}
+
+	distro, err := download.DetectDistro()
+	if err != nil {
+		return err
+	}
+	var plan *download.Plan
+	switch {
+	case *from != "":
+		verification, err := download.VerifyLocal(*from, *checksum)
+		if err != nil {
+			return err
+		}
+		plan = download.PlanLinuxArchive(distro, *from, verification.SHA256)
+	case *offline:
+		cache, err := utils.InstallCache()
+		if err != nil {
+			return err
+		}
+		entry, err := cache.Lookup(target, download.LinuxPlatform(runtime.GOARCH))
+		if err != nil {
+			return err
+		}
+		plan = download.PlanLinuxArchive(distro, cache.Path(entry), entry.SHA256)
+	default:
+		plan = download.PlanLinux(distro, target)
+	}
+	// Commands run in the foreground here, so sudo may ask for a password
+	plan.WithSudo("sudo")
+	if *dryRun {
+		if env.json {
+			env.printJSON(struct {
+				Distro string   `json:"distro"`
+				Method string   `json:"method"`
+				Plan   []string `json:"plan"`
+			}{distro.Name, plan.Method, plan.Describe()})
+			return nil
+		}
+		fmt.Fprintln(env.stdout, strings.Join(plan.Describe(), "\n"))
+		return nil
+	}
+
+	if err := plan.Run(env.ctx, env.log()); err != nil {
+		return err
+	}
+	env.printResult("Tailscale installed successfully.")
+	return nil
+}
+
+// defaultCachePlatforms are the platforms prefetched by "cache fetch" without --platform.
+var defaultCachePlatforms = []string{download.PlatformWindows, download.LinuxPlatform("amd64"), download.LinuxPlatform("arm64")}
+
+// runCache manages the installer cache used for offline installs:
+// "list" shows it, "fetch" downloads installers into it and "clear" empties it.
+func runCache(env *environment) error {
+	defaults := utils.InstallTarget()
+	channel := env.flags.String("channel", defaults.Channel, "release channel: stable or unstable")
+	version := env.flags.String("version", defaults.Version, "exact version to fetch, e.g. 1.76.6")
+	mirror := env.flags.String("mirror", defaults.Mirror, "mirror with the layout of pkgs.tailscale.com")
+	platforms := env.flags.String("platform", strings.Join(defaultCachePlatforms, ","), "comma-separated platforms to fetch: windows, linux-<arch>")
+	dir := env.flags.String("dir", "", "cache directory, the configured one when empty")
+	args, err := env.parse()
+	if err != nil {
+		return err
+	}
+	action := "list"
+	if len(args) > 0 {
+		action = args[0]
+	}
+	if len(args) > 1 {
+		return newUsageError("cache: unexpected argument %q", args[1])
+	}
+
+	cache := download.NewCache(*dir)
+	if *dir == "" {
+		if cache, err = utils.InstallCache(); err != nil {
+			return err
+		}
+	}
+
+	switch action {
+	case "list":
+		entries, err := cache.List()
+		if err != nil {
+			return err
+		}
+		if env.json {
+			env.printJSON(struct {
+				Dir     string                `json:"dir"`
+				Entries []download.CacheEntry `json:"entries"`
+			}{cache.Dir(), entries})
+			return nil
+		}
+		fmt.Fprintln(env.stdout, "Cache: "+cache.Dir())
+		for _, entry := range entries {
+			fmt.Fprintln(env.stdout, entry.String())
+		}
+		return nil
+
+	case "fetch":
+		target, err := download.ParseTarget(*channel, *version)
+		if err != nil {
+			return newUsageError("cache: %v", err)
+		}
+		target.Mirror = *mirror
+		var fetched []*download.CacheEntry
+		for _, platform := range strings.Split(*platforms, ",") {
+			entry, err := cache.Fetch(env.ctx, target, strings.TrimSpace(platform), env.log())
+			if err != nil {
+				return fmt.Errorf("failed to fetch %s: %w", platform, err)
+			}
+			fetched = append(fetched, entry)
+		}
+		if env.json {
+			env.printJSON(fetched)
+			return nil
+		}
+		fmt.Fprintf(env.stdout, "Installers cached in %s: %d\n", cache.Dir(), len(fetched))
+		return nil
+
+	case "clear":
+		if err := cache.Clear(); err != nil {
+			return err
+		}
+		env.printResult("Installer cache cleared.")
+		return nil
+	}
+	return newUsageError("cache: unknown action %q (available: list, fetch, clear)", action)
+}
+
+// runVersions lists the Tailscale versions available on a release channel.
+func runVersions(env *environment) error {
+	channel := env.flags.String("channel", utils.InstallTarget().Channel, "release channel: stable or unstable")
+	if args, err := env.parse(); err != nil {
+		return err
+	} else if len(args) > 0 {
+		return newUsageError("versions: unexpected argument %q", args[0])
+	}
+	target, err := download.ParseTarget(*channel, "")
+	if err != nil {
+		return newUsageError("versions: %v", err)
+	}
+	target.Mirror = utils.InstallTarget().Mirror
+
+	versions, err := download.ListVersions(env.ctx, target)
+	if err != nil {
+		return err
+	}
+	latest, err := download.LatestVersion(env.ctx, target)
+	if err != nil {
+		return err
+	}
+
+	if env.json {
+		names := make([]string, len(versions))
+		for i, version := range versions {
+			names[i] = version.String()
+		}
+		env.printJSON(struct {
+			Latest   string   `json:"latest"`
+			Versions []string `json:"versions"`
+		}{latest.String(), names})
+		return nil
+	}
+	for _, version := range versions {
+		marker := " "
+		if version == latest {
+			marker = "*"
+		}
+		fmt.Fprintf(env.stdout, "%s %s\n", marker, version)
+	}
+	return nil
+}
+
+// runHealth reports the state of tailscaled, starting its service first with --start
+// when it is not running. It fails with ExitDaemonNotRunning when tailscaled stays unreachable.
+func runHealth(env *environment) error {
+	start := env.flags.Bool("start", false, "start the Tailscale service when tailscaled is not running")
+	if args, err := env.parse(); err != nil {
+		return err
+	} else if len(args) > 0 {
+		return newUsageError("health: unexpected argument %q", args[0])
+	}
+
+	health := utils.CheckHealth(env.ctx)
+	if health.State == utils.DaemonNotRunning && *start {
+		started, err := utils.StartDaemon(env.ctx)
+		if err != nil {
+			return err
+		}
+		health = started
+	}
+	if health.State == utils.DaemonNotRunning || health.State == utils.DaemonUnknown && health.Err != nil {
+		return health.Err
+	}
+
+	if env.json {
+		env.printJSON(struct {
+			State        string   `json:"state"`
+			BackendState string   `json:"backendState"`
+			Warnings     []string `json:"warnings,omitempty"`
+		}{health.State.String(), health.Backend, health.Warnings})
+		return nil
+	}
+	fmt.Fprintln(env.stdout, health.String())
+	if hint := health.Hint(); hint != "" {
+		fmt.Fprintln(env.stdout, hint)
+	}
+	for _, warning := range health.Warnings {
+		fmt.Fprintln(env.stdout, "Warning: "+warning)
+	}
+	return nil
+}
+
+// runSelfUpdate replaces the sky-tailscale binary with the latest release,
+// or only reports whether one is available with --check.
+func runSelfUpdate(env *environment) error {
+	check := env.flags.Bool("check", false, "only report whether an update is available")
+	if args, err := env.parse(); err != nil {
+		return err
+	} else if len(args) > 0 {
+		return newUsageError("self-update: unexpected argument %q", args[0])
+	}
+
+	update, err := selfupdate.Check(env.ctx, config.Get().Update.Feed())
+	if err != nil {
+		return err
+	}
+	if env.json && (update == nil || *check) {
+		result := struct {
+			Current   string `json:"current"`
+			Latest    string `json:"latest,omitempty"`
+			Available bool   `json:"available"`
+		}{Current: selfupdate.Version, Available: update != nil}
+		if update != nil {
+			result.Latest = update.Latest.String()
+		}
+		env.printJSON(result)
+		return nil
+	}
+	if update == nil {
+		fmt.Fprintf(env.stdout, "sky-tailscale %s is up to date.\n", selfupdate.Version)
+		return nil
+	}
+	if *check {
+		fmt.Fprintln(env.stdout, update.String())
+		return nil
+	}
+
+	if err := selfupdate.Apply(env.ctx, update, download.ToLog(env.log())); err != nil {
+		return err
+	}
+	env.printResult("Updated to sky-tailscale " + update.Latest.String() + ".")
+	return nil
+}
+
+// runHelp prints the usage of every subcommand.
+func runHelp(env *environment) error {
+	if _, err := env.parse(); err != nil {
+		return err
+	}
+	printUsage(env.stdout)
+	return nil
+}
@@ -0,0 +1,46 @@
+// Command mock-broker serves a local key broker for development.
+// Point the client at it with SKY_TAILSCALE_BROKER_URL:
+//
+//	go run ./cmd/mock-broker -listen 127.0.0.1:8080
+//	SKY_TAILSCALE_BROKER_URL=http://127.0.0.1:8080/api/logIn sky-tailscale
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"tailscale/broker/brokertest"
+	"time"
+)
+
+// scenarios maps the -scenario flag values to mock broker scenarios.
+var scenarios = map[string]brokertest.Scenario{
+	"normal":    brokertest.Normal,
+	"reject":    brokertest.BadCredentials,
+	"ratelimit": brokertest.RateLimited,
+	"locked":    brokertest.AccountLocked,
+	"error":     brokertest.ServerError,
+	"malformed": brokertest.MalformedJSON,
+	"slow":      brokertest.Slow,
+}
+
+func main() {
+	listen := flag.String("listen", "127.0.0.1:8080", "address to listen on")
+	account := flag.String("account", "demo", "accepted account")
+	password := flag.String("password", "demo", "accepted password")
+	key := flag.String("key", "tskey-auth-mock", "auth key returned on success")
+	scenario := flag.String("scenario", "normal", "answer every request with: normal, reject, ratelimit, locked, error, malformed or slow")
+	delay := flag.Duration("delay", 5*time.Second, "response delay of the slow scenario")
+	flag.Parse()
+
+	selected, found := scenarios[strings.ToLower(*scenario)]
+	if !found {
+		log.Fatalf("unknown scenario %q", *scenario)
+	}
+
+	b := brokertest.New(*account, *password, *key).SetScenario(selected).SetDelay(*delay)
+	fmt.Printf("Mock broker listening on http://%s%s (scenario %s)\n", *listen, brokertest.LoginPath, *scenario)
+	log.Fatal(http.ListenAndServe(*listen, b))
+}
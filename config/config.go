@@ -0,0 +1,419 @@
+// Package config loads the sky-tailscale configuration file and resolves
+// the key broker profile used to log in. Values come from, in increasing
+// order of precedence: built-in defaults, the config file, environment
+// variables and command-line flags.
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"tailscale/i18n"
+	"time"
+)
+
+const (
+	// DefaultBrokerURL is the login endpoint of the public sky-tailscale key broker
+	DefaultBrokerURL = "https://sky-tailscale.sky1218.com/api/logIn"
+
+	// DefaultProfileName names the built-in profile pointing at DefaultBrokerURL
+	DefaultProfileName = "sky"
+
+	// DefaultBrokerTimeout bounds broker requests of profiles without a timeout
+	DefaultBrokerTimeout = 30 * time.Second
+
+	// MaxBrokerRetries caps the retries of a profile, so a typo cannot make a login hang for hours
+	MaxBrokerRetries = 10
+
+	// FileName is the name of the config file inside the config directory
+	FileName = "config.json"
+
+	// CacheDirName is the name of the installer cache inside the config directory
+	CacheDirName = "installers"
+
+	// DefaultUpdateFeedURL is the release feed sky-tailscale updates itself from
+	DefaultUpdateFeedURL = "https://api.github.com/repos/911218sky/tailscale-cline/releases/latest"
+)
+
+// Environment variables overriding the config file
+const (
+	ConfigEnv    = "SKY_TAILSCALE_CONFIG"     // Path of the config file
+	ProfileEnv   = "SKY_TAILSCALE_PROFILE"    // Name of the selected profile
+	BrokerURLEnv = "SKY_TAILSCALE_BROKER_URL" // Login endpoint replacing the selected profile's URL
+	ChannelEnv   = "SKY_TAILSCALE_CHANNEL"    // Release channel of Tailscale installs
+	VersionEnv   = "SKY_TAILSCALE_VERSION"    // Tailscale version pinned for installs
+	MirrorEnv    = "SKY_TAILSCALE_MIRROR"     // Package mirror replacing pkgs.tailscale.com
+	CacheDirEnv  = "SKY_TAILSCALE_CACHE_DIR"  // Directory of cached installers
+	OfflineEnv   = "SKY_TAILSCALE_OFFLINE"    // Install only from cached installers when "1" or "true"
+	UpdateURLEnv = "SKY_TAILSCALE_UPDATE_URL" // Release feed of sky-tailscale self-updates
+	LanguageEnv  = "SKY_TAILSCALE_LANGUAGE"   // Language of the terminal UI
+	ThemeEnv     = "SKY_TAILSCALE_THEME"      // Color theme of the terminal UI
+)
+
+// versionPattern matches a Tailscale version such as 1.76.6.
+var versionPattern = regexp.MustCompile(`^\d+\.\d+\.\d+$`)
+
+// Duration is a time.Duration read from JSON as a string such as "30s" or as a number of seconds.
+type Duration time.Duration
+
+// UnmarshalJSON parses a duration string or a number of seconds.
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	var value any
+	if err := json.Unmarshal(data, &value); err != nil {
+		return err
+	}
+	switch v := value.(type) {
+	case float64:
+		*d = Duration(v * float64(time.Second))
+	case string:
+		parsed, err := time.ParseDuration(v)
+		if err != nil {
+			return fmt.Errorf("invalid duration %q: %w", v, err)
+		}
+		*d = Duration(parsed)
+	default:
+		return fmt.Errorf("invalid duration: %s", data)
+	}
+	return nil
+}
+
+// MarshalJSON writes the duration as a string such as "30s".
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+// Profile describes one key broker the client can log in through.
+type Profile struct {
+	Name        string   `json:"name"`                 // Identifier used by --profile and SKY_TAILSCALE_PROFILE
+	DisplayName string   `json:"displayName"`          // Human-readable name shown in the UI
+	URL         string   `json:"url"`                  // Login endpoint of the broker
+	RefreshURL  string   `json:"refreshUrl,omitempty"` // Refresh endpoint, derived from URL when empty
+	CABundle    string   `json:"caBundle,omitempty"`   // Optional PEM file of CAs trusted for the broker
+	Timeout     Duration `json:"timeout,omitempty"`    // Timeout of broker requests
+	PinnedKeys  []string `json:"pinnedKeys,omitempty"` // Optional base64 SHA-256 hashes of the broker public key
+	MaxRetries  int      `json:"maxRetries,omitempty"` // Retries on network and server errors, negative to disable
+}
+
+// Title returns the display name of the profile, falling back to its name.
+func (p *Profile) Title() string {
+	if p.DisplayName != "" {
+		return p.DisplayName
+	}
+	return p.Name
+}
+
+// RequestTimeout returns the profile timeout, or DefaultBrokerTimeout when unset.
+func (p *Profile) RequestTimeout() time.Duration {
+	if p.Timeout <= 0 {
+		return DefaultBrokerTimeout
+	}
+	return time.Duration(p.Timeout)
+}
+
+// Install selects the Tailscale build installed when Tailscale is missing.
+type Install struct {
+	Channel  string `json:"channel,omitempty"`  // "stable" or "unstable", stable when empty
+	Version  string `json:"version,omitempty"`  // Pinned version such as "1.76.6", the latest release when empty
+	Mirror   string `json:"mirror,omitempty"`   // Mirror with the layout of pkgs.tailscale.com, the official server when empty
+	CacheDir string `json:"cacheDir,omitempty"` // Directory of cached installers, CacheDirName in the config directory when empty
+	Offline  bool   `json:"offline,omitempty"`  // Install only from cached installers, never from the network
+}
+
+// Cache returns the directory of cached installers.
+func (i *Install) Cache() (string, error) {
+	if i.CacheDir != "" {
+		return i.CacheDir, nil
+	}
+	dir, err := Dir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, CacheDirName), nil
+}
+
+// validate checks the channel, version and mirror.
+func (i *Install) validate() error {
+	switch i.Channel {
+	case "", "stable", "unstable":
+	default:
+		return fmt.Errorf("unknown channel %q (available: stable, unstable)", i.Channel)
+	}
+	if i.Version != "" && !versionPattern.MatchString(i.Version) {
+		return fmt.Errorf("invalid version %q, expected a version such as 1.76.6", i.Version)
+	}
+	if i.Mirror != "" {
+		if err := validateURL(i.Mirror); err != nil {
+			return fmt.Errorf("mirror: %w", err)
+		}
+	}
+	return nil
+}
+
+// Update configures how sky-tailscale updates itself.
+type Update struct {
+	FeedURL string `json:"feedUrl,omitempty"` // Release feed in the format of the GitHub latest release API
+}
+
+// Feed returns the release feed URL, DefaultUpdateFeedURL when unset.
+func (u *Update) Feed() string {
+	if u.FeedURL == "" {
+		return DefaultUpdateFeedURL
+	}
+	return u.FeedURL
+}
+
+// Config is the content of the config file.
+type Config struct {
+	DefaultProfile string    `json:"defaultProfile,omitempty"` // Profile selected when none is requested
+	Profiles       []Profile `json:"profiles"`                 // Available broker profiles
+	Install        Install   `json:"install"`                  // Tailscale build to install
+	Update         Update    `json:"update"`                   // Self-update of sky-tailscale
+	Language       string    `json:"language,omitempty"`       // Language of the terminal UI such as "zh-TW", from the locale when empty
+	Theme          string    `json:"theme,omitempty"`          // Color theme of the terminal UI such as "light", "no-color" with NO_COLOR or "dark" when empty
+
+	path     string // File the config was loaded from
+	selected string // Name of the selected profile
+}
+
+// defaultProfile returns the built-in profile for the public broker.
+func defaultProfile() Profile {
+	return Profile{
+		Name:        DefaultProfileName,
+		DisplayName: "sky-tailscale",
+		URL:         DefaultBrokerURL,
+		Timeout:     Duration(DefaultBrokerTimeout),
+	}
+}
+
+// Default returns the configuration used when no config file exists.
+func Default() *Config {
+	return &Config{
+		DefaultProfile: DefaultProfileName,
+		Profiles:       []Profile{defaultProfile()},
+		selected:       DefaultProfileName,
+	}
+}
+
+// Dir returns the sky-tailscale directory inside the user config directory.
+func Dir() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to locate config directory: %w", err)
+	}
+	return filepath.Join(dir, "sky-tailscale"), nil
+}
+
+// DefaultPath returns the config file location inside the user config directory.
+func DefaultPath() (string, error) {
+	dir, err := Dir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, FileName), nil
+}
+
+// Load reads the config file at path. A missing file yields the default configuration.
+func Load(path string) (*Config, error) {
+	cfg := Default()
+	cfg.path = path
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return cfg, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config: %w", err)
+	}
+
+	cfg.Profiles = nil
+	if err := json.Unmarshal(data, cfg); err != nil {
+		return nil, fmt.Errorf("failed to parse config %s: %w", path, err)
+	}
+	if len(cfg.Profiles) == 0 {
+		cfg.Profiles = []Profile{defaultProfile()}
+	}
+	if cfg.DefaultProfile == "" {
+		cfg.DefaultProfile = cfg.Profiles[0].Name
+	}
+	if err := cfg.validate(); err != nil {
+		return nil, fmt.Errorf("invalid config %s: %w", path, err)
+	}
+	cfg.selected = cfg.DefaultProfile
+	return cfg, nil
+}
+
+// validate checks that profiles are uniquely named, point at valid URLs and retry a bounded number of times.
+func (c *Config) validate() error {
+	seen := make(map[string]bool)
+	for _, profile := range c.Profiles {
+		if profile.Name == "" {
+			return fmt.Errorf("profile without a name")
+		}
+		if seen[profile.Name] {
+			return fmt.Errorf("duplicate profile %q", profile.Name)
+		}
+		seen[profile.Name] = true
+		if err := validateURL(profile.URL); err != nil {
+			return fmt.Errorf("profile %q: %w", profile.Name, err)
+		}
+		if profile.RefreshURL != "" {
+			if err := validateURL(profile.RefreshURL); err != nil {
+				return fmt.Errorf("profile %q: refreshUrl: %w", profile.Name, err)
+			}
+		}
+		if profile.MaxRetries > MaxBrokerRetries {
+			return fmt.Errorf("profile %q: maxRetries %d is above %d", profile.Name, profile.MaxRetries, MaxBrokerRetries)
+		}
+	}
+	if !seen[c.DefaultProfile] {
+		return fmt.Errorf("default profile %q is not defined", c.DefaultProfile)
+	}
+	if err := c.Install.validate(); err != nil {
+		return fmt.Errorf("install: %w", err)
+	}
+	if c.Update.FeedURL != "" {
+		if err := validateURL(c.Update.FeedURL); err != nil {
+			return fmt.Errorf("update: feedUrl: %w", err)
+		}
+	}
+	if c.Language != "" {
+		if _, err := i18n.Match(c.Language); err != nil {
+			return fmt.Errorf("language: %w", err)
+		}
+	}
+	return nil
+}
+
+// validateURL checks that raw is an absolute http(s) URL.
+func validateURL(raw string) error {
+	parsed, err := url.Parse(raw)
+	if err != nil {
+		return fmt.Errorf("invalid URL %q: %w", raw, err)
+	}
+	if parsed.Scheme != "https" && parsed.Scheme != "http" || parsed.Host == "" {
+		return fmt.Errorf("invalid URL %q: must be an absolute http(s) URL", raw)
+	}
+	return nil
+}
+
+// Path returns the file the config was loaded from.
+func (c *Config) Path() string {
+	return c.path
+}
+
+// Profile returns the profile with the given name, or nil if it does not exist.
+func (c *Config) Profile(name string) *Profile {
+	for i := range c.Profiles {
+		if c.Profiles[i].Name == name {
+			return &c.Profiles[i]
+		}
+	}
+	return nil
+}
+
+// Selected returns the currently selected profile.
+func (c *Config) Selected() *Profile {
+	if profile := c.Profile(c.selected); profile != nil {
+		return profile
+	}
+	return &c.Profiles[0]
+}
+
+// Select makes the profile with the given name the selected one.
+func (c *Config) Select(name string) error {
+	if c.Profile(name) == nil {
+		names := make([]string, len(c.Profiles))
+		for i, profile := range c.Profiles {
+			names[i] = profile.Name
+		}
+		return fmt.Errorf("unknown profile %q (available: %s)", name, strings.Join(names, ", "))
+	}
+	c.selected = name
+	return nil
+}
+
+// current is the configuration used by the application.
+var current = Default()
+
+// Init loads the configuration and applies environment and flag overrides.
+// Empty path and profile arguments fall back to SKY_TAILSCALE_CONFIG,
+// SKY_TAILSCALE_PROFILE and the defaults, in that order.
+func Init(path, profile string) error {
+	if path == "" {
+		path = os.Getenv(ConfigEnv)
+	}
+	if path == "" {
+		var err error
+		if path, err = DefaultPath(); err != nil {
+			return err
+		}
+	}
+
+	cfg, err := Load(path)
+	if err != nil {
+		return err
+	}
+
+	if profile == "" {
+		profile = os.Getenv(ProfileEnv)
+	}
+	if profile != "" {
+		if err := cfg.Select(profile); err != nil {
+			return err
+		}
+	}
+
+	if brokerURL := os.Getenv(BrokerURLEnv); brokerURL != "" {
+		if err := validateURL(brokerURL); err != nil {
+			return fmt.Errorf("%s: %w", BrokerURLEnv, err)
+		}
+		cfg.Selected().URL = brokerURL
+	}
+
+	if channel := os.Getenv(ChannelEnv); channel != "" {
+		cfg.Install.Channel = strings.ToLower(channel)
+	}
+	if version := os.Getenv(VersionEnv); version != "" {
+		cfg.Install.Version = strings.TrimPrefix(version, "v")
+	}
+	if mirror := os.Getenv(MirrorEnv); mirror != "" {
+		cfg.Install.Mirror = mirror
+	}
+	if cacheDir := os.Getenv(CacheDirEnv); cacheDir != "" {
+		cfg.Install.CacheDir = cacheDir
+	}
+	if offline := os.Getenv(OfflineEnv); offline != "" {
+		cfg.Install.Offline = offline == "1" || strings.EqualFold(offline, "true")
+	}
+	if err := cfg.Install.validate(); err != nil {
+		return fmt.Errorf("install: %w", err)
+	}
+	if feedURL := os.Getenv(UpdateURLEnv); feedURL != "" {
+		if err := validateURL(feedURL); err != nil {
+			return fmt.Errorf("%s: %w", UpdateURLEnv, err)
+		}
+		cfg.Update.FeedURL = feedURL
+	}
+	if language := os.Getenv(LanguageEnv); language != "" {
+		if _, err := i18n.Match(language); err != nil {
+			return fmt.Errorf("%s: %w", LanguageEnv, err)
+		}
+		cfg.Language = language
+	}
+	if theme := os.Getenv(ThemeEnv); theme != "" {
+		cfg.Theme = theme
+	}
+
+	current = cfg
+	return nil
+}
+
+// Get returns the configuration used by the application.
+func Get() *Config {
+	return current
+}
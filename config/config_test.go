@@ -0,0 +1,241 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// overrides are the environment variables Init reads, cleared by useEnv so the
+// environment of the machine running the tests does not leak in.
+var overrides = []string{
+	ConfigEnv, ProfileEnv, BrokerURLEnv, ChannelEnv, VersionEnv, MirrorEnv,
+	CacheDirEnv, OfflineEnv, UpdateURLEnv, LanguageEnv, ThemeEnv,
+}
+
+// useEnv sets the environment variables Init reads to env, clearing the others,
+// and restores them and the current configuration when the test ends.
+func useEnv(t *testing.T, env map[string]string) {
+	t.Helper()
+	for _, name := range overrides {
+		t.Setenv(name, env[name])
+	}
+	previous := current
+	t.Cleanup(func() { current = previous })
+}
+
+// writeConfig writes content to a config file in a temporary directory and returns its path.
+func writeConfig(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), FileName)
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// twoProfiles is a config file with a default profile and a second one.
+const twoProfiles = `{
+  "defaultProfile": "office",
+  "profiles": [
+    {"name": "office", "url": "https://broker.example.com/api/logIn", "timeout": "10s", "maxRetries": 2},
+    {"name": "lab", "displayName": "Lab", "url": "http://10.0.0.5:8080/api/logIn", "timeout": 5}
+  ],
+  "install": {"channel": "unstable", "version": "1.76.6"}
+}`
+
+func TestLoad(t *testing.T) {
+	path := writeConfig(t, twoProfiles)
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Path() != path || cfg.Selected().Name != "office" || len(cfg.Profiles) != 2 {
+		t.Errorf("Load() = %+v", cfg)
+	}
+	lab := cfg.Profile("lab")
+	if lab == nil || lab.Title() != "Lab" || lab.RequestTimeout() != 5*time.Second {
+		t.Errorf("lab profile = %+v", lab)
+	}
+	if office := cfg.Profile("office"); office.Title() != "office" || office.RequestTimeout() != 10*time.Second || office.MaxRetries != 2 {
+		t.Errorf("office profile = %+v", office)
+	}
+	if cfg.Install.Channel != "unstable" || cfg.Install.Version != "1.76.6" {
+		t.Errorf("install = %+v", cfg.Install)
+	}
+}
+
+func TestLoadDefaults(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+	}{
+		{name: "missing file"},
+		{name: "empty object", content: "{}"},
+		{name: "install only", content: `{"install": {"offline": true}}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), FileName)
+			if tt.content != "" {
+				path = writeConfig(t, tt.content)
+			}
+			cfg, err := Load(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			selected := cfg.Selected()
+			if selected.Name != DefaultProfileName || selected.URL != DefaultBrokerURL || selected.RequestTimeout() != DefaultBrokerTimeout {
+				t.Errorf("selected profile = %+v, want the built-in one", selected)
+			}
+			if cfg.Update.Feed() != DefaultUpdateFeedURL {
+				t.Errorf("update feed = %q, want %q", cfg.Update.Feed(), DefaultUpdateFeedURL)
+			}
+		})
+	}
+}
+
+func TestLoadRejects(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		wantErr string
+	}{
+		{name: "malformed JSON", content: `{"profiles": [`, wantErr: "failed to parse config"},
+		{name: "wrong type", content: `{"profiles": [{"name": "a", "url": "https://a.example.com", "maxRetries": "3"}]}`, wantErr: "failed to parse config"},
+		{name: "invalid timeout", content: `{"profiles": [{"name": "a", "url": "https://a.example.com", "timeout": "soon"}]}`, wantErr: `invalid duration "soon"`},
+		{name: "unnamed profile", content: `{"profiles": [{"url": "https://a.example.com"}]}`, wantErr: "profile without a name"},
+		{name: "duplicate profile", content: `{"profiles": [{"name": "a", "url": "https://a.example.com"}, {"name": "a", "url": "https://b.example.com"}]}`, wantErr: `duplicate profile "a"`},
+		{name: "unknown default", content: `{"defaultProfile": "b", "profiles": [{"name": "a", "url": "https://a.example.com"}]}`, wantErr: `default profile "b" is not defined`},
+		{name: "relative URL", content: `{"profiles": [{"name": "a", "url": "/api/logIn"}]}`, wantErr: `profile "a": invalid URL "/api/logIn"`},
+		{name: "other scheme", content: `{"profiles": [{"name": "a", "url": "ftp://a.example.com/logIn"}]}`, wantErr: "must be an absolute http(s) URL"},
+		{name: "invalid refresh URL", content: `{"profiles": [{"name": "a", "url": "https://a.example.com", "refreshUrl": "refresh"}]}`, wantErr: `profile "a": refreshUrl: invalid URL`},
+		{name: "too many retries", content: `{"profiles": [{"name": "a", "url": "https://a.example.com", "maxRetries": 1000}]}`, wantErr: `profile "a": maxRetries 1000 is above 10`},
+		{name: "unknown channel", content: `{"install": {"channel": "nightly"}}`, wantErr: `install: unknown channel "nightly"`},
+		{name: "invalid version", content: `{"install": {"version": "latest"}}`, wantErr: `install: invalid version "latest"`},
+		{name: "invalid mirror", content: `{"install": {"mirror": "mirror.example.com"}}`, wantErr: "install: mirror: invalid URL"},
+		{name: "invalid feed", content: `{"update": {"feedUrl": "file:///releases.json"}}`, wantErr: "update: feedUrl: invalid URL"},
+		{name: "unknown language", content: `{"language": "fr"}`, wantErr: "language: "},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Load(writeConfig(t, tt.content))
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Load() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestInit(t *testing.T) {
+	path := writeConfig(t, twoProfiles)
+	tests := []struct {
+		name    string
+		path    string
+		profile string
+		env     map[string]string
+		check   func(t *testing.T, cfg *Config)
+		wantErr string
+	}{
+		{
+			name:  "file values",
+			path:  path,
+			check: func(t *testing.T, cfg *Config) { expect(t, "selected profile", cfg.Selected().Name, "office") },
+		},
+		{
+			name: "config from the environment",
+			env:  map[string]string{ConfigEnv: path, ProfileEnv: "lab"},
+			check: func(t *testing.T, cfg *Config) {
+				expect(t, "path", cfg.Path(), path)
+				expect(t, "selected profile", cfg.Selected().Name, "lab")
+			},
+		},
+		{
+			name:    "flag before the environment",
+			path:    path,
+			profile: "office",
+			env:     map[string]string{ProfileEnv: "lab"},
+			check:   func(t *testing.T, cfg *Config) { expect(t, "selected profile", cfg.Selected().Name, "office") },
+		},
+		{
+			name:    "unknown profile",
+			path:    path,
+			profile: "home",
+			wantErr: `unknown profile "home" (available: office, lab)`,
+		},
+		{
+			name: "environment overrides",
+			path: path,
+			env: map[string]string{
+				BrokerURLEnv: "https://other.example.com/api/logIn",
+				ChannelEnv:   "STABLE",
+				VersionEnv:   "v1.78.1",
+				MirrorEnv:    "https://mirror.example.com",
+				CacheDirEnv:  "/var/cache/sky-tailscale",
+				OfflineEnv:   "TRUE",
+				UpdateURLEnv: "https://updates.example.com/latest",
+				LanguageEnv:  "zh-TW",
+				ThemeEnv:     "light",
+			},
+			check: func(t *testing.T, cfg *Config) {
+				expect(t, "broker URL", cfg.Selected().URL, "https://other.example.com/api/logIn")
+				expect(t, "channel", cfg.Install.Channel, "stable")
+				expect(t, "version", cfg.Install.Version, "1.78.1")
+				expect(t, "mirror", cfg.Install.Mirror, "https://mirror.example.com")
+				expect(t, "cache directory", cfg.Install.CacheDir, "/var/cache/sky-tailscale")
+				expect(t, "update feed", cfg.Update.Feed(), "https://updates.example.com/latest")
+				expect(t, "language", cfg.Language, "zh-TW")
+				expect(t, "theme", cfg.Theme, "light")
+				if !cfg.Install.Offline {
+					t.Error("offline not set by the environment")
+				}
+				// Only the selected profile is redirected
+				expect(t, "other broker URL", cfg.Profile("lab").URL, "http://10.0.0.5:8080/api/logIn")
+			},
+		},
+		{
+			name:  "offline turned off",
+			path:  writeConfig(t, `{"install": {"offline": true}}`),
+			env:   map[string]string{OfflineEnv: "0"},
+			check: func(t *testing.T, cfg *Config) { expect(t, "offline", cfg.Install.Offline, false) },
+		},
+		{name: "invalid broker URL", path: path, env: map[string]string{BrokerURLEnv: "broker.example.com"}, wantErr: BrokerURLEnv + ": invalid URL"},
+		{name: "invalid channel", path: path, env: map[string]string{ChannelEnv: "nightly"}, wantErr: `install: unknown channel "nightly"`},
+		{name: "invalid version", path: path, env: map[string]string{VersionEnv: "1.78"}, wantErr: `install: invalid version "1.78"`},
+		{name: "invalid feed", path: path, env: map[string]string{UpdateURLEnv: "releases"}, wantErr: UpdateURLEnv + ": invalid URL"},
+		{name: "unknown language", path: path, env: map[string]string{LanguageEnv: "fr"}, wantErr: LanguageEnv + ": "},
+		{name: "malformed file", path: writeConfig(t, "{"), wantErr: "failed to parse config"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			useEnv(t, tt.env)
+			before := Get()
+
+			err := Init(tt.path, tt.profile)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("Init() error = %v, want %q", err, tt.wantErr)
+				}
+				// A rejected configuration is not used
+				if Get() != before {
+					t.Error("Init() replaced the configuration despite the error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			tt.check(t, Get())
+		})
+	}
+}
+
+// expect reports a mismatch between the value of a setting and the wanted one.
+func expect[T comparable](t *testing.T, setting string, got, want T) {
+	t.Helper()
+	if got != want {
+		t.Errorf("%s = %v, want %v", setting, got, want)
+	}
+}
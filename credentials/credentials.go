@@ -0,0 +1,289 @@
+// Package credentials stores broker refresh tokens in an encrypted local file,
+// so users can obtain new auth keys without retyping their password.
+// Passwords are never stored.
+//
+// The file is encrypted with AES-256-GCM using a random key kept in a
+// separate, owner-only key file next to it. On Windows the key itself is
+// encrypted with DPAPI, tying it to the Windows user account, so copying both
+// files to another account or machine does not reveal the tokens. Elsewhere
+// the key is protected only by its file permissions: this keeps tokens
+// unreadable when the credential file alone is copied or backed up, but not
+// from the root user or from malware running as the same user, who can also
+// read the key. A refresh token only yields auth keys for its own broker
+// account and is revoked by the broker, so that risk is accepted.
+//
+// A credential file that cannot be decrypted is removed, so a lost or damaged
+// key costs a password prompt instead of breaking every later save.
+package credentials
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"tailscale/config"
+	"time"
+)
+
+const (
+	// FileName is the name of the encrypted credential file inside the config directory
+	FileName = "credentials.enc"
+
+	// KeyFileName is the name of the file holding the encryption key
+	KeyFileName = "credentials.key"
+
+	// LockoutFileName is the name of the file recording failed logins
+	LockoutFileName = "lockout.json"
+
+	// keySize is the AES-256 key size in bytes
+	keySize = 32
+
+	// fileVersion identifies the layout of the credential file
+	fileVersion = 1
+)
+
+// ErrCorrupted is wrapped by the error of Load when the saved credentials could
+// not be decrypted and were reset.
+var ErrCorrupted = errors.New("saved credentials are corrupted and were reset")
+
+// Entry is the saved session of one broker profile.
+type Entry struct {
+	Account      string    `json:"account"`      // Broker account the token belongs to
+	RefreshToken string    `json:"refreshToken"` // Token exchanged for new auth keys
+	SavedAt      time.Time `json:"savedAt"`      // When the token was last saved
+}
+
+// envelope is the on-disk layout of the credential file.
+type envelope struct {
+	Version int    `json:"version"`
+	Nonce   []byte `json:"nonce"`
+	Data    []byte `json:"data"`
+}
+
+// Store reads and writes the credential file.
+type Store struct {
+	path        string // Encrypted credential file
+	keyPath     string // File holding the encryption key
+	lockoutPath string // File recording failed logins
+}
+
+// NewStore creates a Store keeping its files in dir.
+func NewStore(dir string) *Store {
+	return &Store{
+		path:        filepath.Join(dir, FileName),
+		keyPath:     filepath.Join(dir, KeyFileName),
+		lockoutPath: filepath.Join(dir, LockoutFileName),
+	}
+}
+
+// DefaultStore creates a Store in the sky-tailscale config directory.
+func DefaultStore() (*Store, error) {
+	dir, err := config.Dir()
+	if err != nil {
+		return nil, err
+	}
+	return NewStore(dir), nil
+}
+
+// Load returns the saved entry of profile, or nil if there is none.
+// Corrupted credentials are reset and reported with an error wrapping ErrCorrupted.
+func (s *Store) Load(profile string) (*Entry, error) {
+	entries, err := s.readOrReset()
+	if err != nil {
+		return nil, err
+	}
+	entry, found := entries[profile]
+	if !found {
+		return nil, nil
+	}
+	return &entry, nil
+}
+
+// Save stores entry for profile, replacing any previous one.
+func (s *Store) Save(profile string, entry Entry) error {
+	entries, err := s.readOrReset()
+	if err != nil && !errors.Is(err, ErrCorrupted) {
+		return err
+	}
+	if entry.SavedAt.IsZero() {
+		entry.SavedAt = time.Now()
+	}
+	entries[profile] = entry
+	return s.writeAll(entries)
+}
+
+// Forget removes the saved entry of profile.
+func (s *Store) Forget(profile string) error {
+	entries, err := s.readOrReset()
+	if errors.Is(err, ErrCorrupted) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if _, found := entries[profile]; !found {
+		return nil
+	}
+	delete(entries, profile)
+	return s.writeAll(entries)
+}
+
+// ForgetAll removes the credential file and its key. Failed logins are kept,
+// so forgetting credentials does not lift a login lockout.
+func (s *Store) ForgetAll() error {
+	for _, path := range []string{s.path, s.keyPath} {
+		if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("failed to remove %s: %w", path, err)
+		}
+	}
+	return nil
+}
+
+// readOrReset is readAll, except that corrupted credentials are removed and
+// yield no entries along with the error wrapping ErrCorrupted.
+func (s *Store) readOrReset() (map[string]Entry, error) {
+	entries, err := s.readAll()
+	if !errors.Is(err, ErrCorrupted) {
+		return entries, err
+	}
+	if resetErr := s.ForgetAll(); resetErr != nil {
+		return nil, resetErr
+	}
+	return make(map[string]Entry), err
+}
+
+// readAll decrypts the credential file. A missing file yields no entries,
+// one that cannot be decrypted an error wrapping ErrCorrupted.
+func (s *Store) readAll() (map[string]Entry, error) {
+	entries := make(map[string]Entry)
+
+	data, err := os.ReadFile(s.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return entries, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read credentials: %w", err)
+	}
+
+	var env envelope
+	if err := json.Unmarshal(data, &env); err != nil || env.Version != fileVersion {
+		return nil, fmt.Errorf("%w: %s is not a credential file", ErrCorrupted, s.path)
+	}
+
+	gcm, err := s.cipher(false)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("%w: credential key %s is missing", ErrCorrupted, s.keyPath)
+	}
+	if err != nil {
+		return nil, err
+	}
+	plain, err := gcm.Open(nil, env.Nonce, env.Data, nil)
+	if err != nil {
+		return nil, fmt.Errorf("%w: failed to decrypt %s", ErrCorrupted, s.path)
+	}
+	if err := json.Unmarshal(plain, &entries); err != nil {
+		return nil, fmt.Errorf("%w: failed to decode %s", ErrCorrupted, s.path)
+	}
+	return entries, nil
+}
+
+// writeAll encrypts entries into the credential file, replacing it atomically.
+func (s *Store) writeAll(entries map[string]Entry) error {
+	if len(entries) == 0 {
+		return s.ForgetAll()
+	}
+
+	plain, err := json.Marshal(entries)
+	if err != nil {
+		return fmt.Errorf("failed to encode credentials: %w", err)
+	}
+	gcm, err := s.cipher(true)
+	if err != nil {
+		return err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return fmt.Errorf("failed to generate nonce: %w", err)
+	}
+	data, err := json.Marshal(envelope{
+		Version: fileVersion,
+		Nonce:   nonce,
+		Data:    gcm.Seal(nil, nonce, plain, nil),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to encode credentials: %w", err)
+	}
+	return writeFileAtomic(s.path, data)
+}
+
+// cipher returns the AES-GCM cipher of the store, creating the key file if create is true.
+// A key that cannot be used yields an error wrapping ErrCorrupted.
+func (s *Store) cipher(create bool) (cipher.AEAD, error) {
+	key, err := s.readKey()
+	if errors.Is(err, os.ErrNotExist) && create {
+		key = make([]byte, keySize)
+		if _, err := rand.Read(key); err != nil {
+			return nil, fmt.Errorf("failed to generate key: %w", err)
+		}
+		protected, err := protectKey(key)
+		if err != nil {
+			return nil, err
+		}
+		if err := writeFileAtomic(s.keyPath, protected); err != nil {
+			return nil, err
+		}
+	} else if err != nil {
+		return nil, err
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// readKey reads the key file and removes its platform protection.
+func (s *Store) readKey() ([]byte, error) {
+	data, err := os.ReadFile(s.keyPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read credential key: %w", err)
+	}
+	key, err := unprotectKey(data)
+	if err != nil || len(key) != keySize {
+		return nil, fmt.Errorf("%w: credential key %s cannot be used", ErrCorrupted, s.keyPath)
+	}
+	return key, nil
+}
+
+// writeFileAtomic writes data to an owner-only temporary file and renames it over path.
+func writeFileAtomic(path string, data []byte) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return fmt.Errorf("failed to create directory: %w", err)
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to create temp file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if err := tmp.Chmod(0o600); err != nil && !errors.Is(err, errors.ErrUnsupported) {
+		tmp.Close()
+		return fmt.Errorf("failed to protect %s: %w", path, err)
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("failed to replace %s: %w", path, err)
+	}
+	return nil
+}
@@ -0,0 +1,136 @@
+package credentials
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// newSaved returns a store in a temporary directory holding a session of profile "sky".
+func newSaved(t *testing.T) (*Store, string) {
+	t.Helper()
+	dir := t.TempDir()
+	store := NewStore(dir)
+	if err := store.Save("sky", Entry{Account: "alice", RefreshToken: "token-1"}); err != nil {
+		t.Fatal(err)
+	}
+	return store, dir
+}
+
+func TestSaveLoad(t *testing.T) {
+	store, dir := newSaved(t)
+	entry, err := store.Load("sky")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if entry == nil || entry.Account != "alice" || entry.RefreshToken != "token-1" || entry.SavedAt.IsZero() {
+		t.Errorf("Load = %+v", entry)
+	}
+	if entry, err := store.Load("other"); entry != nil || err != nil {
+		t.Errorf("Load of an unknown profile = %+v, %v", entry, err)
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, FileName))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Contains(data, []byte("token-1")) {
+		t.Errorf("credential file holds the token in clear:\n%s", data)
+	}
+}
+
+func TestCorruptedStoreIsReset(t *testing.T) {
+	tests := []struct {
+		name    string
+		corrupt func(dir string) error
+	}{
+		{"garbage file", func(dir string) error {
+			return os.WriteFile(filepath.Join(dir, FileName), []byte("not json"), 0o600)
+		}},
+		{"missing key", func(dir string) error {
+			return os.Remove(filepath.Join(dir, KeyFileName))
+		}},
+		{"truncated key", func(dir string) error {
+			return os.WriteFile(filepath.Join(dir, KeyFileName), []byte("short"), 0o600)
+		}},
+		{"other key", func(dir string) error {
+			other := NewStore(filepath.Join(dir, "other"))
+			if err := other.Save("sky", Entry{Account: "bob", RefreshToken: "token-2"}); err != nil {
+				return err
+			}
+			return os.Rename(filepath.Join(dir, "other", KeyFileName), filepath.Join(dir, KeyFileName))
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store, dir := newSaved(t)
+			if err := tt.corrupt(dir); err != nil {
+				t.Fatal(err)
+			}
+
+			if _, err := store.Load("sky"); !errors.Is(err, ErrCorrupted) {
+				t.Fatalf("Load error = %v, want ErrCorrupted", err)
+			}
+			// The reset is reported once, then the store is empty and usable again
+			if entry, err := store.Load("sky"); entry != nil || err != nil {
+				t.Fatalf("Load after reset = %+v, %v", entry, err)
+			}
+			if err := store.Save("sky", Entry{Account: "alice", RefreshToken: "token-3"}); err != nil {
+				t.Fatal(err)
+			}
+			if entry, err := store.Load("sky"); err != nil || entry == nil || entry.RefreshToken != "token-3" {
+				t.Errorf("Load after save = %+v, %v", entry, err)
+			}
+		})
+	}
+}
+
+func TestSaveAndForgetResetCorruptedStore(t *testing.T) {
+	store, dir := newSaved(t)
+	os.Remove(filepath.Join(dir, KeyFileName))
+	if err := store.Save("sky", Entry{Account: "alice", RefreshToken: "token-2"}); err != nil {
+		t.Fatalf("Save over corrupted credentials = %v", err)
+	}
+	if entry, err := store.Load("sky"); err != nil || entry == nil || entry.RefreshToken != "token-2" {
+		t.Errorf("Load = %+v, %v", entry, err)
+	}
+
+	os.Remove(filepath.Join(dir, KeyFileName))
+	if err := store.Forget("sky"); err != nil {
+		t.Fatalf("Forget of corrupted credentials = %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, FileName)); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("corrupted credential file kept: %v", err)
+	}
+}
+
+func TestLockout(t *testing.T) {
+	dir := t.TempDir()
+	store := NewStore(dir)
+	if lockout, err := store.Lockout(); err != nil || lockout.Failures != 0 || !lockout.LockedUntil.IsZero() {
+		t.Fatalf("Lockout of a new store = %+v, %v", lockout, err)
+	}
+
+	lockedUntil := time.Now().Add(5 * time.Minute).Round(0)
+	if err := store.SaveLockout(Lockout{Failures: 2, LockedUntil: lockedUntil}); err != nil {
+		t.Fatal(err)
+	}
+	// Forgetting credentials must not lift the lockout
+	if err := store.ForgetAll(); err != nil {
+		t.Fatal(err)
+	}
+	lockout, err := NewStore(dir).Lockout()
+	if err != nil || lockout.Failures != 2 || !lockout.LockedUntil.Equal(lockedUntil) {
+		t.Errorf("Lockout after restart = %+v, %v", lockout, err)
+	}
+
+	if err := store.SaveLockout(Lockout{}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, LockoutFileName)); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("lockout file kept without failures: %v", err)
+	}
+}
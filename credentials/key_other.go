@@ -0,0 +1,14 @@
+//go:build !windows
+
+package credentials
+
+// protectKey returns key unchanged: outside Windows the key file is only
+// protected by its owner-only permissions.
+func protectKey(key []byte) ([]byte, error) {
+	return key, nil
+}
+
+// unprotectKey returns data unchanged, see protectKey.
+func unprotectKey(data []byte) ([]byte, error) {
+	return data, nil
+}
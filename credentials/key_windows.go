@@ -0,0 +1,62 @@
+//go:build windows
+
+package credentials
+
+import (
+	"fmt"
+	"syscall"
+	"unsafe"
+)
+
+// cryptProtectUIForbidden makes DPAPI fail rather than show a prompt
+const cryptProtectUIForbidden = 0x1
+
+var (
+	crypt32           = syscall.NewLazyDLL("crypt32.dll")
+	procProtectData   = crypt32.NewProc("CryptProtectData")
+	procUnprotectData = crypt32.NewProc("CryptUnprotectData")
+)
+
+// dataBlob is the DATA_BLOB structure DPAPI exchanges buffers with.
+type dataBlob struct {
+	size uint32
+	data *byte
+}
+
+// newBlob returns a DATA_BLOB pointing at b.
+func newBlob(b []byte) *dataBlob {
+	if len(b) == 0 {
+		return &dataBlob{}
+	}
+	return &dataBlob{size: uint32(len(b)), data: &b[0]}
+}
+
+// bytes copies the blob into Go memory and frees the buffer DPAPI allocated.
+func (b *dataBlob) bytes() []byte {
+	defer syscall.LocalFree(syscall.Handle(unsafe.Pointer(b.data)))
+	return append([]byte(nil), unsafe.Slice(b.data, b.size)...)
+}
+
+// protectKey encrypts key with DPAPI, so only the current Windows user can decrypt it.
+func protectKey(key []byte) ([]byte, error) {
+	var out dataBlob
+	r, _, err := procProtectData.Call(
+		uintptr(unsafe.Pointer(newBlob(key))), 0, 0, 0, 0,
+		cryptProtectUIForbidden, uintptr(unsafe.Pointer(&out)))
+	if r == 0 {
+		return nil, fmt.Errorf("failed to protect credential key: %w", err)
+	}
+	return out.bytes(), nil
+}
+
+// unprotectKey decrypts a key encrypted by protectKey.
+func unprotectKey(data []byte) ([]byte, error) {
+	var out dataBlob
+	r, _, err := procUnprotectData.Call(
+		uintptr(unsafe.Pointer(newBlob(data))), 0, 0, 0, 0,
+		cryptProtectUIForbidden, uintptr(unsafe.Pointer(&out)))
+	if r == 0 {
+		return nil, fmt.Errorf("failed to unprotect credential key: %w", err)
+	}
+	return out.bytes(), nil
+}
@@ -0,0 +1,47 @@
+package credentials
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+)
+
+// Lockout records consecutive failed logins, so a login lockout survives restarts.
+type Lockout struct {
+	Failures    int       `json:"failures"`    // Consecutive failed logins
+	LockedUntil time.Time `json:"lockedUntil"` // End of the current lockout, zero when not locked
+}
+
+// Lockout returns the recorded failed logins. A missing file means none,
+// and an unreadable one is treated the same, since it holds nothing secret.
+func (s *Store) Lockout() (Lockout, error) {
+	var lockout Lockout
+	data, err := os.ReadFile(s.lockoutPath)
+	if errors.Is(err, os.ErrNotExist) {
+		return lockout, nil
+	}
+	if err != nil {
+		return lockout, fmt.Errorf("failed to read login lockout: %w", err)
+	}
+	if err := json.Unmarshal(data, &lockout); err != nil {
+		return Lockout{}, nil
+	}
+	return lockout, nil
+}
+
+// SaveLockout records lockout, removing the file once there are no failures left.
+func (s *Store) SaveLockout(lockout Lockout) error {
+	if lockout.Failures == 0 && lockout.LockedUntil.IsZero() {
+		if err := os.Remove(s.lockoutPath); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("failed to remove %s: %w", s.lockoutPath, err)
+		}
+		return nil
+	}
+	data, err := json.Marshal(lockout)
+	if err != nil {
+		return fmt.Errorf("failed to encode login lockout: %w", err)
+	}
+	return writeFileAtomic(s.lockoutPath, data)
+}
@@ -0,0 +1,240 @@
+package download
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"tailscale/i18n"
+	"tailscale/utils/drawer"
+)
+
+// PlatformWindows names the Windows installer in the cache. Linux tarballs are named by LinuxPlatform.
+const PlatformWindows = "windows"
+
+// MetadataSuffix is appended to the name of a cached installer to get its integrity metadata
+const MetadataSuffix = ".meta.json"
+
+// ErrNotCached is returned when the cache holds no valid installer for a target.
+var ErrNotCached = errors.New("no cached installer found")
+
+// LinuxPlatform returns the cache platform of the Linux tarball for arch, such as "linux-amd64".
+func LinuxPlatform(arch string) string {
+	return "linux-" + arch
+}
+
+// PackageURL returns the URL of the installer of the pinned target for platform.
+func (t Target) PackageURL(platform string) (string, error) {
+	if platform == PlatformWindows {
+		return t.WindowsURL(), nil
+	}
+	if arch, found := strings.CutPrefix(platform, "linux-"); found && arch != "" {
+		return t.TarballURL(arch), nil
+	}
+	return "", fmt.Errorf("unknown platform %q (expected %s or linux-<arch>)", platform, PlatformWindows)
+}
+
+// CacheEntry is the integrity metadata stored next to a cached installer.
+type CacheEntry struct {
+	Name             string    `json:"name"`             // File name inside the cache directory
+	Platform         string    `json:"platform"`         // PlatformWindows or a LinuxPlatform
+	Channel          string    `json:"channel"`          // Release channel the installer was published on
+	Version          string    `json:"version"`          // Tailscale version of the installer
+	URL              string    `json:"url"`              // Where the installer was downloaded from
+	SHA256           string    `json:"sha256"`           // Verified hex SHA-256 of the file
+	Size             int64     `json:"size"`             // Size of the file in bytes
+	SignatureChecked bool      `json:"signatureChecked"` // Whether a signature was verified when downloading
+	FetchedAt        time.Time `json:"fetchedAt"`        // When the installer was downloaded
+}
+
+// Verification returns the verification the entry was stored with.
+func (e *CacheEntry) Verification() *Verification {
+	return &Verification{SHA256: e.SHA256, SignatureChecked: e.SignatureChecked}
+}
+
+// String describes the entry for listings.
+func (e *CacheEntry) String() string {
+	return fmt.Sprintf("%-14s %-8s %-9s %9s  %s", e.Platform, e.Version, e.Channel, formatBytes(e.Size), e.Name)
+}
+
+// Cache is a directory of verified installers, so Tailscale can be installed without network access.
+type Cache struct {
+	dir string // Directory holding the installers and their metadata
+}
+
+// NewCache returns the cache stored in dir. The directory is created when an installer is added.
+func NewCache(dir string) *Cache {
+	return &Cache{dir: dir}
+}
+
+// Dir returns the directory of the cache.
+func (c *Cache) Dir() string {
+	return c.dir
+}
+
+// Path returns the location of the installer of entry.
+func (c *Cache) Path(entry *CacheEntry) string {
+	return filepath.Join(c.dir, entry.Name)
+}
+
+// Download adds the installer of target for platform to the cache, drawing its progress
+// on the terminal, and returns its entry. A valid cached installer is reused.
+func (c *Cache) Download(ctx context.Context, d *drawer.Drawer, target Target, platform string) (*CacheEntry, error) {
+	return c.add(ctx, target, platform, ToDrawer(d))
+}
+
+// Fetch is like Download but reports progress as text lines written to log.
+func (c *Cache) Fetch(ctx context.Context, target Target, platform string, log io.Writer) (*CacheEntry, error) {
+	return c.add(ctx, target, platform, ToLog(log))
+}
+
+// add downloads and verifies the installer of target for platform unless a valid copy is cached.
+func (c *Cache) add(ctx context.Context, target Target, platform string, out Output) (*CacheEntry, error) {
+	// Cached files are versioned, so "latest" is resolved first
+	target, err := target.Resolve(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if entry, err := c.Lookup(target, platform); err == nil {
+		out.println(i18n.T(i18n.InstallUsingCached, entry.Name))
+		return entry, nil
+	}
+
+	url, err := target.PackageURL(platform)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(c.dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create cache directory: %w", err)
+	}
+	entry := &CacheEntry{
+		Name:     path.Base(url),
+		Platform: platform,
+		Channel:  target.channel(),
+		Version:  target.Version,
+		URL:      url,
+	}
+	verification, err := fetch(ctx, url, c.Path(entry), out)
+	if err != nil {
+		return nil, err
+	}
+	info, err := os.Stat(c.Path(entry))
+	if err != nil {
+		return nil, err
+	}
+	entry.SHA256 = verification.SHA256
+	entry.SignatureChecked = verification.SignatureChecked
+	entry.Size = info.Size()
+	entry.FetchedAt = time.Now().UTC()
+	if err := c.writeEntry(entry); err != nil {
+		return nil, err
+	}
+	return entry, nil
+}
+
+// writeEntry stores the metadata of entry next to its installer.
+func (c *Cache) writeEntry(entry *CacheEntry) error {
+	data, err := json.MarshalIndent(entry, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(c.Path(entry)+MetadataSuffix, data, 0o644); err != nil {
+		return fmt.Errorf("failed to write cache metadata: %w", err)
+	}
+	return nil
+}
+
+// Lookup returns the newest cached installer for platform matching the channel of target
+// and, when it is pinned, its version. Installers whose content no longer matches their
+// metadata are removed. It returns ErrNotCached when no valid installer is found.
+func (c *Cache) Lookup(target Target, platform string) (*CacheEntry, error) {
+	entries, err := c.List()
+	if err != nil {
+		return nil, err
+	}
+	for i := range entries {
+		entry := &entries[i]
+		if entry.Platform != platform || entry.Channel != target.channel() {
+			continue
+		}
+		if target.Pinned() && entry.Version != target.Version {
+			continue
+		}
+		if err := VerifyFile(c.Path(entry), entry.SHA256); err != nil {
+			c.remove(entry)
+			continue
+		}
+		return entry, nil
+	}
+	return nil, fmt.Errorf("%w for %s %s in %s", ErrNotCached, platform, target, c.dir)
+}
+
+// List returns the cached installers sorted by platform, newest version first.
+// Metadata files that cannot be read are skipped.
+func (c *Cache) List() ([]CacheEntry, error) {
+	files, err := filepath.Glob(filepath.Join(c.dir, "*"+MetadataSuffix))
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []CacheEntry
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			continue
+		}
+		var entry CacheEntry
+		if json.Unmarshal(data, &entry) != nil || entry.Name != strings.TrimSuffix(filepath.Base(file), MetadataSuffix) {
+			continue
+		}
+		entries = append(entries, entry)
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].Platform != entries[j].Platform {
+			return entries[i].Platform < entries[j].Platform
+		}
+		a, _ := ParseVersion(entries[i].Version)
+		b, _ := ParseVersion(entries[j].Version)
+		return b.Less(a)
+	})
+	return entries, nil
+}
+
+// Clear removes every cached installer and its metadata, along with interrupted downloads.
+func (c *Cache) Clear() error {
+	entries, err := c.List()
+	if err != nil {
+		return err
+	}
+	for i := range entries {
+		if err := c.remove(&entries[i]); err != nil {
+			return err
+		}
+	}
+	parts, _ := filepath.Glob(filepath.Join(c.dir, "*"+PartSuffix))
+	for _, part := range parts {
+		if err := os.Remove(part); err != nil {
+			return fmt.Errorf("failed to remove %s: %w", part, err)
+		}
+	}
+	return nil
+}
+
+// remove deletes the installer of entry and its metadata.
+func (c *Cache) remove(entry *CacheEntry) error {
+	if err := os.Remove(c.Path(entry)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to remove %s: %w", entry.Name, err)
+	}
+	if err := os.Remove(c.Path(entry) + MetadataSuffix); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to remove metadata of %s: %w", entry.Name, err)
+	}
+	return nil
+}
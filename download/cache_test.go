@@ -0,0 +1,200 @@
+package download_test
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"tailscale/download"
+	"tailscale/download/downloadtest"
+)
+
+// writeEntry stores entry in cache with content as its installer, as a download would.
+func writeEntry(t *testing.T, cache *download.Cache, entry download.CacheEntry, content []byte) {
+	t.Helper()
+	if err := os.WriteFile(cache.Path(&entry), content, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	data, err := json.Marshal(entry)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(cache.Path(&entry)+download.MetadataSuffix, data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// files returns the names of the files in dir.
+func files(t *testing.T, dir string) []string {
+	t.Helper()
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, entry := range entries {
+		names = append(names, entry.Name())
+	}
+	return names
+}
+
+// cachedTarget is the release served by downloadtest, so cached installers are named after PackagePath.
+func cachedTarget(server *downloadtest.Server) download.Target {
+	return download.Target{Version: "1.0.0", Mirror: server.URL}
+}
+
+func TestCacheFetch(t *testing.T) {
+	data := payload(50_000)
+	server := downloadtest.NewServer(data)
+	defer server.Close()
+	cache := download.NewCache(filepath.Join(t.TempDir(), "installers"))
+
+	var log bytes.Buffer
+	entry, err := cache.Fetch(context.Background(), cachedTarget(server), download.PlatformWindows, &log)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if entry.Name != "tailscale-setup-1.0.0.exe" || entry.Version != "1.0.0" || entry.Channel != download.ChannelStable || entry.Size != int64(len(data)) {
+		t.Errorf("entry = %+v", entry)
+	}
+	if got, err := os.ReadFile(cache.Path(entry)); err != nil || !bytes.Equal(got, data) {
+		t.Fatalf("cached installer differs from the payload: %v", err)
+	}
+
+	// The second fetch is served from the cache without contacting the server
+	log.Reset()
+	again, err := cache.Fetch(context.Background(), cachedTarget(server), download.PlatformWindows, &log)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again.SHA256 != entry.SHA256 || !strings.Contains(log.String(), "Using cached tailscale-setup-1.0.0.exe") {
+		t.Errorf("second fetch = %+v, log %q", again, log.String())
+	}
+	if requests := server.Ranges(); len(requests) != 1 {
+		t.Errorf("installer requested %d times, want once", len(requests))
+	}
+}
+
+func TestCacheLookup(t *testing.T) {
+	content := []byte("installer")
+	digest := sha256.Sum256(content)
+	valid := download.CacheEntry{
+		Name:     "tailscale-setup-1.76.6.exe",
+		Platform: download.PlatformWindows,
+		Channel:  download.ChannelStable,
+		Version:  "1.76.6",
+		SHA256:   hex.EncodeToString(digest[:]),
+	}
+
+	tests := []struct {
+		name     string
+		target   download.Target
+		platform string
+		corrupt  bool
+		wantErr  bool
+	}{
+		{name: "pinned", target: download.Target{Version: "1.76.6"}, platform: download.PlatformWindows},
+		{name: "latest", target: download.Target{}, platform: download.PlatformWindows},
+		{name: "other version", target: download.Target{Version: "1.78.1"}, platform: download.PlatformWindows, wantErr: true},
+		{name: "other channel", target: download.Target{Channel: download.ChannelUnstable}, platform: download.PlatformWindows, wantErr: true},
+		{name: "other platform", target: download.Target{}, platform: download.LinuxPlatform("amd64"), wantErr: true},
+		{name: "corrupted", target: download.Target{Version: "1.76.6"}, platform: download.PlatformWindows, corrupt: true, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cache := download.NewCache(t.TempDir())
+			stored := content
+			if tt.corrupt {
+				stored = []byte("tampered")
+			}
+			writeEntry(t, cache, valid, stored)
+
+			entry, err := cache.Lookup(tt.target, tt.platform)
+			if tt.wantErr {
+				if !errors.Is(err, download.ErrNotCached) {
+					t.Fatalf("Lookup error = %v, want ErrNotCached", err)
+				}
+			} else if err != nil || entry.Name != valid.Name {
+				t.Fatalf("Lookup = %+v, %v", entry, err)
+			}
+
+			// A corrupted installer is removed with its metadata, anything else is kept
+			left := files(t, cache.Dir())
+			if tt.corrupt && len(left) != 0 {
+				t.Errorf("corrupted installer kept: %q", left)
+			}
+			if !tt.corrupt && len(left) != 2 {
+				t.Errorf("cache holds %q, want the installer and its metadata", left)
+			}
+		})
+	}
+}
+
+func TestCacheList(t *testing.T) {
+	cache := download.NewCache(t.TempDir())
+	for _, entry := range []download.CacheEntry{
+		{Name: "tailscale-setup-1.9.0.exe", Platform: download.PlatformWindows, Version: "1.9.0"},
+		{Name: "tailscale_1.76.6_amd64.tgz", Platform: download.LinuxPlatform("amd64"), Version: "1.76.6"},
+		{Name: "tailscale-setup-1.76.6.exe", Platform: download.PlatformWindows, Version: "1.76.6"},
+		{Name: "tailscale-setup-1.10.2.exe", Platform: download.PlatformWindows, Version: "1.10.2"},
+	} {
+		writeEntry(t, cache, entry, nil)
+	}
+	// Metadata naming another file, and metadata that is not JSON, are skipped
+	misnamed, _ := json.Marshal(download.CacheEntry{Name: "tailscale-setup-1.80.0.exe", Platform: download.PlatformWindows, Version: "1.80.0"})
+	if err := os.WriteFile(filepath.Join(cache.Dir(), "renamed.exe"+download.MetadataSuffix), misnamed, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(cache.Dir(), "broken.exe"+download.MetadataSuffix), []byte("{"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	entries, err := cache.List()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, entry := range entries {
+		got = append(got, entry.Name)
+	}
+	want := []string{"tailscale_1.76.6_amd64.tgz", "tailscale-setup-1.76.6.exe", "tailscale-setup-1.10.2.exe", "tailscale-setup-1.9.0.exe"}
+	if strings.Join(got, " ") != strings.Join(want, " ") {
+		t.Errorf("List = %q, want %q", got, want)
+	}
+}
+
+func TestCacheClear(t *testing.T) {
+	cache := download.NewCache(t.TempDir())
+	writeEntry(t, cache, download.CacheEntry{Name: "tailscale-setup-1.76.6.exe", Platform: download.PlatformWindows, Version: "1.76.6"}, []byte("installer"))
+	part := filepath.Join(cache.Dir(), "tailscale-setup-1.78.1.exe"+download.PartSuffix)
+	if err := os.WriteFile(part, []byte("inst"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	other := filepath.Join(cache.Dir(), "notes.txt")
+	if err := os.WriteFile(other, nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := cache.Clear(); err != nil {
+		t.Fatal(err)
+	}
+	// Only files of the cache are removed
+	if left := files(t, cache.Dir()); len(left) != 1 || left[0] != "notes.txt" {
+		t.Errorf("cache holds %q after Clear, want only notes.txt", left)
+	}
+}
+
+func TestCacheLookupEmpty(t *testing.T) {
+	// A missing directory is an empty cache, the state of an offline machine nothing was copied to
+	cache := download.NewCache(filepath.Join(t.TempDir(), "missing"))
+	if _, err := cache.Lookup(download.Target{Version: "1.76.6"}, download.PlatformWindows); !errors.Is(err, download.ErrNotCached) {
+		t.Fatalf("Lookup error = %v, want ErrNotCached", err)
+	}
+}
@@ -0,0 +1,188 @@
+package download
+
+import (
+	"context"
+	"crypto/ed25519"
+	"embed"
+	"encoding/binary"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path"
+
+	"golang.org/x/crypto/blake2s"
+)
+
+// Package signatures follow the distsign scheme of pkgs.tailscale.com: root keys
+// embedded in the client sign a bundle of signing keys published at the root of
+// the package server, and a signing key signs each package.
+const (
+	// SigningKeysFile is the bundle of signing keys at the root of the package server
+	SigningKeysFile = "distsign.pub"
+
+	// RootKeyPEM is the PEM block type of a root public key
+	RootKeyPEM = "ROOT PUBLIC KEY"
+
+	// SigningKeyPEM is the PEM block type of a signing public key
+	SigningKeyPEM = "SIGNING PUBLIC KEY"
+)
+
+// roots holds the root keys of pkgs.tailscale.com, copied from
+// tailscale.com/clientupdate/distsign/roots.
+//
+//go:embed roots/*.pem
+var roots embed.FS
+
+// rootKeys are the keys trusted to sign the bundle of signing keys.
+var rootKeys = mustParseRoots()
+
+// mustParseRoots parses the embedded root keys.
+func mustParseRoots() []ed25519.PublicKey {
+	files, err := roots.ReadDir("roots")
+	if err != nil {
+		panic(err)
+	}
+	var keys []ed25519.PublicKey
+	for _, file := range files {
+		data, err := roots.ReadFile("roots/" + file.Name())
+		if err != nil {
+			panic(err)
+		}
+		parsed, err := ParsePublicKeys(data, RootKeyPEM)
+		if err != nil {
+			panic(fmt.Sprintf("embedded root key %s: %v", file.Name(), err))
+		}
+		keys = append(keys, parsed...)
+	}
+	return keys
+}
+
+// SetRootKeys replaces the trusted root keys and returns the previous ones,
+// for tests and private mirrors signing packages with their own keys.
+func SetRootKeys(keys ...ed25519.PublicKey) []ed25519.PublicKey {
+	previous := rootKeys
+	rootKeys = keys
+	return previous
+}
+
+// ParsePublicKeys parses the concatenated PEM blocks of type pemType in data,
+// each holding a raw ed25519 public key.
+func ParsePublicKeys(data []byte, pemType string) ([]ed25519.PublicKey, error) {
+	var keys []ed25519.PublicKey
+	for {
+		var block *pem.Block
+		block, data = pem.Decode(data)
+		if block == nil {
+			break
+		}
+		if block.Type != pemType {
+			return nil, fmt.Errorf("unexpected PEM block %q, want %q", block.Type, pemType)
+		}
+		if len(block.Bytes) != ed25519.PublicKeySize {
+			return nil, fmt.Errorf("%s has %d bytes, want %d", pemType, len(block.Bytes), ed25519.PublicKeySize)
+		}
+		keys = append(keys, ed25519.PublicKey(block.Bytes))
+	}
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("no %s found", pemType)
+	}
+	return keys, nil
+}
+
+// PackageMessage returns what a signing key signs for a package: the BLAKE2s-256
+// hash of its content followed by its length as a little-endian uint64.
+func PackageMessage(content io.Reader) ([]byte, error) {
+	hash, err := blake2s.New256(nil)
+	if err != nil {
+		return nil, err
+	}
+	size, err := io.Copy(hash, content)
+	if err != nil {
+		return nil, err
+	}
+	return binary.LittleEndian.AppendUint64(hash.Sum(nil), uint64(size)), nil
+}
+
+// signingKeysURL returns the URL of the signing key bundle for the package at
+// packageURL, published in the parent of its channel directory.
+func signingKeysURL(packageURL string) (string, error) {
+	u, err := url.Parse(packageURL)
+	if err != nil {
+		return "", fmt.Errorf("invalid package URL: %w", err)
+	}
+	u.Path = path.Join(path.Dir(path.Dir(u.Path)), SigningKeysFile)
+	u.RawQuery = ""
+	return u.String(), nil
+}
+
+// fetchSigningKeys downloads the signing key bundle at url and checks that a root key signed it.
+func fetchSigningKeys(ctx context.Context, url string) ([]ed25519.PublicKey, error) {
+	bundle, err := fetchMetadata(ctx, url)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch signing keys: %w", err)
+	}
+	sig, err := fetchMetadata(ctx, url+SignatureSuffix)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch signing keys signature: %w", err)
+	}
+	if bundle == nil || sig == nil {
+		return nil, fmt.Errorf("signing keys %s are not published", url)
+	}
+	if !verifyAny(rootKeys, bundle, sig) {
+		return nil, &SignatureError{URL: url + SignatureSuffix}
+	}
+	keys, err := ParsePublicKeys(bundle, SigningKeyPEM)
+	if err != nil {
+		return nil, fmt.Errorf("invalid signing keys %s: %w", url, err)
+	}
+	return keys, nil
+}
+
+// verifySignature checks the detached signature of the package at url against fileName.
+// It reports false without error when no signature is published. A published signature
+// must be valid for a signing key endorsed by a root key.
+func verifySignature(ctx context.Context, url, fileName string) (bool, error) {
+	sig, err := fetchMetadata(ctx, url+SignatureSuffix)
+	if err != nil {
+		return false, fmt.Errorf("failed to fetch signature: %w", err)
+	}
+	if sig == nil {
+		return false, nil
+	}
+
+	keysURL, err := signingKeysURL(url)
+	if err != nil {
+		return false, err
+	}
+	keys, err := fetchSigningKeys(ctx, keysURL)
+	if err != nil {
+		return false, err
+	}
+
+	file, err := os.Open(fileName)
+	if err != nil {
+		return false, fmt.Errorf("failed to read %s: %w", fileName, err)
+	}
+	defer file.Close()
+	message, err := PackageMessage(file)
+	if err != nil {
+		return false, fmt.Errorf("failed to hash %s: %w", fileName, err)
+	}
+
+	if !verifyAny(keys, message, sig) {
+		return false, &SignatureError{URL: url + SignatureSuffix}
+	}
+	return true, nil
+}
+
+// verifyAny reports whether any of keys signed message with sig.
+func verifyAny(keys []ed25519.PublicKey, message, sig []byte) bool {
+	for _, key := range keys {
+		if ed25519.Verify(key, message, sig) {
+			return true
+		}
+	}
+	return false
+}
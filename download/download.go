@@ -1,106 +1,398 @@
 package download
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"os"
 	"os/exec"
+	"path"
+	"strconv"
+	"strings"
+	"time"
 
+	"tailscale/i18n"
 	"tailscale/utils/drawer"
 )
 
 const (
-	// TailscaleWindowsURL is the URL to download the latest stable version of Tailscale for Windows.
-	// Exported as it might be useful for other packages to know the download URL.
-	TailscaleWindowsURL = "https://pkgs.tailscale.com/stable/tailscale-setup-latest.exe"
-	bufferSize          = 1024
+	// PartSuffix is appended to the file name while a download is incomplete
+	PartSuffix = ".part"
+
+	// MaxRetries is the number of times a transfer failing without progress is retried
+	MaxRetries = 5
+
+	// initialBackoff is the delay before the first retry, doubled after each failure
+	initialBackoff = time.Second
+
+	// maxBackoff caps the delay between two retries
+	maxBackoff = 30 * time.Second
+
+	// bufferSize is the size of the chunks copied from the network
+	bufferSize = 64 << 10
 )
 
-// DownloadTailscaleWindows downloads the Tailscale installer for Windows and saves it to the specified file.
-// It displays a progress bar during download.
-func DownloadTailscaleWindows(fileName string) error {
-	drawer.Print("Downloading Tailscale...", drawer.DefaultOption)
+// stallTimeout is how long an attempt may receive no data before it is abandoned and retried
+var stallTimeout = 30 * time.Second
+
+// errStalled is the cause of an attempt abandoned after stallTimeout without data
+var errStalled = errors.New("download stalled")
+
+// SetStallTimeout sets how long a transfer may receive no data before it is
+// abandoned and resumed, and returns the previous timeout.
+func SetStallTimeout(timeout time.Duration) time.Duration {
+	previous := stallTimeout
+	stallTimeout = timeout
+	return previous
+}
+
+// DownloadTailscaleWindows downloads the Tailscale installer of target for Windows and saves it to the specified file.
+// It displays a progress bar with d during download and verifies the installer before returning.
+func DownloadTailscaleWindows(ctx context.Context, d *drawer.Drawer, fileName string, target Target) (*Verification, error) {
+	return DownloadInstaller(ctx, d, target.WindowsURL(), fileName)
+}
+
+// DownloadInstaller downloads the package at url to fileName, then checks it against the
+// SHA-256 published next to it and, when one is published, its signature.
+// Progress is drawn with d. A file failing verification is removed. Cancelling ctx
+// stops the download, keeping the partial file for a later resume.
+func DownloadInstaller(ctx context.Context, d *drawer.Drawer, url, fileName string) (*Verification, error) {
+	return fetch(ctx, url, fileName, ToDrawer(d))
+}
+
+// Fetch is like DownloadInstaller but reports progress as text lines written to log
+// instead of drawing on the terminal, for use outside the interactive UI.
+func Fetch(ctx context.Context, url, fileName string, log io.Writer) (*Verification, error) {
+	return fetch(ctx, url, fileName, ToLog(log))
+}
+
+// DownloadUnverified downloads url to fileName with the same resume and retries, for
+// callers that check the file against a checksum published elsewhere. Progress is
+// reported to out.
+func DownloadUnverified(ctx context.Context, url, fileName string, out Output) error {
+	out.println(i18n.T(i18n.DownloadStarted, path.Base(url)))
+	if _, err := downloadFile(ctx, url, fileName, out); err != nil {
+		return err
+	}
+	out.println(i18n.T(i18n.DownloadCompleted))
+	return nil
+}
+
+// fetch downloads and verifies the package at url, reporting to out.
+func fetch(ctx context.Context, url, fileName string, out Output) (*Verification, error) {
+	out.println(i18n.T(i18n.DownloadStarted, path.Base(url)))
 
-	resp, err := http.Get(TailscaleWindowsURL)
+	// Checksums are published next to the versioned file the "latest" URL redirects to
+	finalURL, err := downloadFile(ctx, url, fileName, out)
 	if err != nil {
-		return fmt.Errorf("failed to download Tailscale: %w", err)
+		return nil, err
 	}
-	defer resp.Body.Close()
+	out.println(i18n.T(i18n.DownloadCompleted))
 
-	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("download failed with status: %s", resp.Status)
+	out.println(i18n.T(i18n.DownloadVerifying))
+	verification, err := verifyDownload(ctx, finalURL, fileName)
+	if err != nil {
+		os.Remove(fileName)
+		out.println(i18n.T(i18n.DownloadVerifyFailed))
+		return nil, err
+	}
+	out.println(i18n.T(i18n.DownloadVerified, verification))
+	return verification, nil
+}
+
+// Output is where a download reports progress: drawn on the terminal, or written
+// as text lines to a log outside the interactive UI.
+type Output struct {
+	d   *drawer.Drawer // Drawer the progress is drawn with when there is no log
+	log io.Writer      // Log receiving progress lines
+}
+
+// ToDrawer reports progress by drawing it with d.
+func ToDrawer(d *drawer.Drawer) Output {
+	return Output{d: d}
+}
+
+// ToLog reports progress as text lines written to log.
+func ToLog(log io.Writer) Output {
+	return Output{log: log}
+}
+
+// println reports a line of text.
+func (o Output) println(message string) {
+	if o.log != nil {
+		fmt.Fprintln(o.log, message)
+		return
 	}
+	o.d.Print(message, drawer.DefaultOption)
+}
 
-	contentLength := resp.ContentLength
-	if contentLength <= 0 {
-		return fmt.Errorf("invalid content length received")
+// nextLine moves the terminal cursor below a progress bar.
+func (o Output) nextLine() {
+	if o.log == nil {
+		o.d.NextLine()
 	}
+}
 
-	file, err := os.Create(fileName)
+// verifyDownload checks fileName, downloaded from url, against the published checksum and signature.
+func verifyDownload(ctx context.Context, url, fileName string) (*Verification, error) {
+	expected, err := FetchChecksum(ctx, url)
 	if err != nil {
-		return fmt.Errorf("failed to create file: %w", err)
+		return nil, err
+	}
+	actual, err := FileSHA256(fileName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to hash %s: %w", fileName, err)
+	}
+	if actual != expected {
+		return nil, &ChecksumError{File: fileName, Expected: expected, Actual: actual}
 	}
-	defer file.Close()
 
-	if err := downloadWithProgress(resp.Body, file, contentLength); err != nil {
-		return fmt.Errorf("failed during download: %w", err)
+	signed, err := verifySignature(ctx, url, fileName)
+	if err != nil {
+		return nil, err
 	}
+	return &Verification{SHA256: actual, SignatureChecked: signed}, nil
+}
 
-	drawer.NextLine()
-	drawer.Print("Download completed", drawer.DefaultOption)
-	return nil
+// retryableError marks a transfer failure that may succeed when resumed,
+// such as a dropped connection or a server error.
+type retryableError struct {
+	err error
 }
 
-// downloadWithProgress copies data from src to dst while updating a progress bar.
-func downloadWithProgress(src io.Reader, dst io.Writer, total int64) error {
-	y := drawer.GetY()
-	buffer := make([]byte, bufferSize)
-	var downloaded int64
+// Error returns the message of the underlying error.
+func (e *retryableError) Error() string {
+	return e.err.Error()
+}
 
-	for {
-		n, err := src.Read(buffer)
-		if err != nil && err != io.EOF {
-			return err
-		}
-		if n == 0 {
+// Unwrap returns the underlying error.
+func (e *retryableError) Unwrap() error {
+	return e.err
+}
+
+// transfer is a download into a .part file that can be resumed with HTTP Range requests.
+type transfer struct {
+	url       string    // URL to request, replaced by the redirect target after the first response
+	part      string    // File receiving the data
+	validator string    // ETag or Last-Modified of the resource, sent as If-Range when resuming
+	progress  *progress // Progress bar of the transfer
+}
+
+// downloadFile downloads url into fileName through a .part file, resuming it after
+// failures and stalls with exponential backoff. It returns the URL the file was served from.
+func downloadFile(ctx context.Context, url, fileName string, out Output) (string, error) {
+	t := &transfer{url: url, part: fileName + PartSuffix, progress: newProgress(out)}
+	defer t.progress.close()
+
+	backoff := initialBackoff
+	for retries := 0; ; {
+		before := t.progress.downloaded
+		err := t.resume(ctx)
+		if err == nil {
 			break
 		}
 
-		if _, err := dst.Write(buffer[:n]); err != nil {
-			return err
+		// Only failures without progress count towards the retry limit
+		if t.progress.downloaded > before {
+			retries, backoff = 0, initialBackoff
 		}
+		var retryable *retryableError
+		if !errors.As(err, &retryable) || retries >= MaxRetries {
+			out.nextLine()
+			return "", fmt.Errorf("failed during download: %w", err)
+		}
+		retries++
 
-		downloaded += int64(n)
-		percent := int(float64(downloaded) / float64(total) * 100)
-		drawer.DrawProgressBar(y, percent, drawer.DefaultOption)
-
-		if err == io.EOF {
-			break
+		t.progress.draw(i18n.T(i18n.DownloadRetrying, err, backoff, retries, MaxRetries))
+		if err := sleep(ctx, backoff); err != nil {
+			out.nextLine()
+			return "", fmt.Errorf("failed during download: %w", err)
 		}
+		backoff = min(backoff*2, maxBackoff)
 	}
-	return nil
+	out.nextLine()
+
+	if err := os.Rename(t.part, fileName); err != nil {
+		return "", fmt.Errorf("failed to move download into place: %w", err)
+	}
+	return t.url, nil
 }
 
-// DownloadTailscaleLinux downloads and installs Tailscale for Linux using the official install script.
-func DownloadTailscaleLinux() error {
-	drawer.Print("Downloading and installing Tailscale for Linux...", drawer.DefaultOption)
+// sleep waits for delay, returning early with the error of ctx when it is done.
+func sleep(ctx context.Context, delay time.Duration) error {
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
 
-	cmd := exec.Command("sh", "-c", "curl -fsSL https://tailscale.com/install.sh | sh")
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
+// interrupted returns why the attempt running with ctx was cancelled instead of
+// err, if it was: a stall is retried, a cancellation by the caller is not.
+func interrupted(ctx context.Context, err error) error {
+	cause := context.Cause(ctx)
+	switch {
+	case cause == nil:
+		return err
+	case errors.Is(cause, errStalled):
+		return &retryableError{fmt.Errorf("%w: no data received for %s", errStalled, stallTimeout)}
+	}
+	return cause
+}
 
-	if err := cmd.Run(); err != nil {
-		return fmt.Errorf("failed to install Tailscale: %w", err)
+// resume requests the rest of the file after what the .part file already holds and appends it.
+// The attempt is abandoned once no data arrived for stallTimeout.
+func (t *transfer) resume(ctx context.Context) error {
+	var offset int64
+	if info, err := os.Stat(t.part); err == nil {
+		offset = info.Size()
+	}
+
+	ctx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+	stall := time.AfterFunc(stallTimeout, func() { cancel(errStalled) })
+	defer stall.Stop()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, t.url, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	if offset > 0 {
+		req.Header.Set("Range", fmt.Sprintf("bytes=%d-", offset))
+		if t.validator != "" {
+			req.Header.Set("If-Range", t.validator)
+		}
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return interrupted(ctx, &retryableError{fmt.Errorf("failed to download Tailscale: %w", err)})
+	}
+	defer resp.Body.Close()
+
+	// Later attempts go straight to the redirect target, so a release published
+	// meanwhile cannot be mixed into the partial file
+	t.url = resp.Request.URL.String()
+
+	flags := os.O_CREATE | os.O_WRONLY
+	total := resp.ContentLength
+	switch {
+	case resp.StatusCode == http.StatusPartialContent:
+		start, size, err := parseContentRange(resp.Header.Get("Content-Range"))
+		if err != nil || start != offset {
+			os.Remove(t.part)
+			return &retryableError{fmt.Errorf("server returned an unexpected range %q", resp.Header.Get("Content-Range"))}
+		}
+		flags |= os.O_APPEND
+		total = size
+	case resp.StatusCode == http.StatusOK:
+		// The server sent the whole file, either because nothing was downloaded yet,
+		// it ignores ranges or the file changed
+		offset = 0
+		flags |= os.O_TRUNC
+	case resp.StatusCode == http.StatusRequestedRangeNotSatisfiable && offset > 0:
+		if _, size, err := parseContentRange(resp.Header.Get("Content-Range")); err == nil && size == offset {
+			return nil
+		}
+		os.Remove(t.part)
+		return &retryableError{fmt.Errorf("partial download is stale")}
+	case resp.StatusCode >= http.StatusInternalServerError || resp.StatusCode == http.StatusTooManyRequests:
+		return &retryableError{fmt.Errorf("download failed with status: %s", resp.Status)}
+	default:
+		return fmt.Errorf("download failed with status: %s", resp.Status)
+	}
+
+	if t.validator == "" {
+		t.validator = resp.Header.Get("ETag")
+		if t.validator == "" {
+			t.validator = resp.Header.Get("Last-Modified")
+		}
+	}
+
+	file, err := os.OpenFile(t.part, flags, 0o644)
+	if err != nil {
+		return fmt.Errorf("failed to create file: %w", err)
 	}
 
-	drawer.Print("Tailscale downloaded and installed successfully.", drawer.DefaultOption)
+	t.progress.start(offset, total)
+	err = copyWithProgress(file, resp.Body, t.progress, func() { stall.Reset(stallTimeout) })
+	if closeErr := file.Close(); err == nil && closeErr != nil {
+		err = fmt.Errorf("failed to write file: %w", closeErr)
+	}
+	if err != nil {
+		return interrupted(ctx, err)
+	}
+	t.progress.draw("")
+
+	if total >= 0 && t.progress.downloaded < total {
+		return &retryableError{io.ErrUnexpectedEOF}
+	}
 	return nil
 }
 
-// Install runs the Tailscale installer executable on Windows.
-func Install(downloadFileName string) error {
-	drawer.Print("Installing Tailscale...", drawer.DefaultOption)
+// parseContentRange parses a Content-Range header such as "bytes 100-199/200" or "bytes */200".
+// The size is -1 when the server does not know it.
+func parseContentRange(header string) (start, size int64, err error) {
+	rangeSpec, sizeSpec, found := strings.Cut(strings.TrimPrefix(header, "bytes "), "/")
+	if !found {
+		return 0, 0, fmt.Errorf("invalid Content-Range %q", header)
+	}
+
+	size = -1
+	if sizeSpec != "*" {
+		if size, err = strconv.ParseInt(sizeSpec, 10, 64); err != nil {
+			return 0, 0, fmt.Errorf("invalid Content-Range %q", header)
+		}
+	}
+	if rangeSpec == "*" {
+		return 0, size, nil
+	}
+	startSpec, _, _ := strings.Cut(rangeSpec, "-")
+	if start, err = strconv.ParseInt(startSpec, 10, 64); err != nil {
+		return 0, 0, fmt.Errorf("invalid Content-Range %q", header)
+	}
+	return start, size, nil
+}
+
+// copyWithProgress copies src to dst in large chunks while updating the progress bar,
+// calling received whenever data arrives. Read failures are retryable, write failures are not.
+func copyWithProgress(dst io.Writer, src io.Reader, p *progress, received func()) error {
+	buffer := make([]byte, bufferSize)
+	for {
+		n, err := src.Read(buffer)
+		if n > 0 {
+			received()
+			if _, writeErr := dst.Write(buffer[:n]); writeErr != nil {
+				return fmt.Errorf("failed to write file: %w", writeErr)
+			}
+			p.add(n)
+		}
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return &retryableError{err}
+		}
+	}
+}
+
+// Install runs the Tailscale installer executable on Windows, reporting it with d.
+// The file is hashed again and must still match verification, so a file
+// swapped or damaged after download is never executed.
+func Install(d *drawer.Drawer, downloadFileName string, verification *Verification) error {
+	if verification == nil {
+		return ErrNotVerified
+	}
+	if err := VerifyFile(downloadFileName, verification.SHA256); err != nil {
+		return fmt.Errorf("refusing to run installer: %w", err)
+	}
+
+	d.Print(i18n.T(i18n.InstallRunning), drawer.DefaultOption)
 
 	cmd := exec.Command(downloadFileName, "--install")
 	cmd.Stdout = os.Stdout
@@ -110,6 +402,6 @@ func Install(downloadFileName string) error {
 		return fmt.Errorf("failed to run installer: %w", err)
 	}
 
-	drawer.Print("Tailscale installed successfully.", drawer.DefaultOption)
+	d.Print(i18n.T(i18n.InstallSucceeded), drawer.SuccessOption)
 	return nil
 }
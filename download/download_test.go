@@ -0,0 +1,106 @@
+package download_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"tailscale/download"
+	"tailscale/download/downloadtest"
+)
+
+// payload returns size bytes of installer content that differ at every offset.
+func payload(size int) []byte {
+	data := make([]byte, size)
+	for i := range data {
+		data[i] = byte(i * 7 / 3)
+	}
+	return data
+}
+
+// useStallTimeout sets the stall timeout of downloads until the test ends.
+func useStallTimeout(t *testing.T, timeout time.Duration) {
+	t.Helper()
+	previous := download.SetStallTimeout(timeout)
+	t.Cleanup(func() { download.SetStallTimeout(previous) })
+}
+
+func TestFetchResumes(t *testing.T) {
+	tests := []struct {
+		name  string
+		setup func(s *downloadtest.Server)
+	}{
+		{"interrupted", func(s *downloadtest.Server) { s.Interrupt(100_000) }},
+		{"stalled", func(s *downloadtest.Server) { s.Stall(100_000) }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			useStallTimeout(t, 200*time.Millisecond)
+			data := payload(300_000)
+			server := downloadtest.NewServer(data)
+			defer server.Close()
+			tt.setup(server)
+
+			fileName := filepath.Join(t.TempDir(), "tailscale-setup.exe")
+			if _, err := download.Fetch(context.Background(), server.PackageURL(), fileName, io.Discard); err != nil {
+				t.Fatal(err)
+			}
+			if got, err := os.ReadFile(fileName); err != nil || !bytes.Equal(got, data) {
+				t.Fatalf("downloaded file differs from the payload: %v", err)
+			}
+			if _, err := os.Stat(fileName + download.PartSuffix); !errors.Is(err, os.ErrNotExist) {
+				t.Errorf("partial file kept: %v", err)
+			}
+
+			// The second request asks for the rest of the file only
+			ranges := server.Ranges()
+			if len(ranges) != 2 || ranges[0] != "" || !strings.HasPrefix(ranges[1], "bytes=") || ranges[1] == "bytes=0-" {
+				t.Errorf("requests with Range %q, want a whole then a partial request", ranges)
+			}
+		})
+	}
+}
+
+func TestFetchCancel(t *testing.T) {
+	tests := []struct {
+		name  string
+		setup func(s *downloadtest.Server)
+	}{
+		{"while stalled", func(s *downloadtest.Server) { s.Stall(100_000) }},
+		{"while waiting to retry", func(s *downloadtest.Server) { s.Interrupt(100_000) }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			useStallTimeout(t, time.Minute)
+			server := downloadtest.NewServer(payload(300_000))
+			defer server.Close()
+			tt.setup(server)
+
+			ctx, cancel := context.WithCancel(context.Background())
+			time.AfterFunc(200*time.Millisecond, cancel)
+			fileName := filepath.Join(t.TempDir(), "tailscale-setup.exe")
+			start := time.Now()
+			_, err := download.Fetch(ctx, server.PackageURL(), fileName, io.Discard)
+			if !errors.Is(err, context.Canceled) {
+				t.Fatalf("Fetch error = %v, want context.Canceled", err)
+			}
+			// Both the stall timeout and the first backoff are far longer
+			if elapsed := time.Since(start); elapsed > 900*time.Millisecond {
+				t.Errorf("Fetch returned %s after being cancelled", elapsed)
+			}
+			// The partial file is kept for a later resume
+			if info, err := os.Stat(fileName + download.PartSuffix); err != nil || info.Size() == 0 {
+				t.Errorf("partial file not kept: %v", err)
+			}
+			if len(server.Ranges()) != 1 {
+				t.Errorf("cancelled download was retried: %q", server.Ranges())
+			}
+		})
+	}
+}
@@ -0,0 +1,198 @@
+// Package downloadtest provides a local package server mimicking
+// pkgs.tailscale.com, serving installers with their published checksum and
+// distsign signature, for development and integration tests of the downloader.
+// Installers are served with Range support, and responses can be cut short to
+// exercise resumed downloads.
+package downloadtest
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"tailscale/download"
+	"time"
+)
+
+const (
+	// PackagePath is the path of the installer served by the package server
+	PackagePath = "/stable/tailscale-setup-1.0.0.exe"
+
+	// KeysPath is the path of the signing key bundle, at the root of the server
+	KeysPath = "/" + download.SigningKeysFile
+)
+
+// Server serves one installer with its checksum and, if signed, its signature.
+type Server struct {
+	*httptest.Server
+
+	mu        sync.Mutex
+	payload   []byte   // bytes published as the installer
+	served    []byte   // bytes actually served, differs from payload once tampered
+	checksum  []byte   // bytes the published checksum is computed over
+	signature []byte   // detached signature of the package, nil if unsigned
+	keys      []byte   // signing key bundle, nil if unsigned
+	keysSig   []byte   // root signature of the signing key bundle
+	cuts      []cut    // cuts applied to the next responses of the installer
+	ranges    []string // Range header of each request for the installer, empty when absent
+}
+
+// cut ends a response of the installer after some bytes.
+type cut struct {
+	after int  // bytes sent before the cut
+	stall bool // whether the connection stays open without data instead of being dropped
+}
+
+// NewServer starts a package server publishing payload. Close it when done.
+func NewServer(payload []byte) *Server {
+	s := &Server{payload: payload, served: payload, checksum: payload}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serveHTTP))
+	return s
+}
+
+// Sign publishes the payload signed with signing, and a bundle of the signing
+// key signed with root, as pkgs.tailscale.com does. The downloader accepts it
+// once the public key of root is set with download.SetRootKeys.
+func (s *Server) Sign(root, signing ed25519.PrivateKey) *Server {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.keys = pem.EncodeToMemory(&pem.Block{Type: download.SigningKeyPEM, Bytes: signing.Public().(ed25519.PublicKey)})
+	s.keysSig = ed25519.Sign(root, s.keys)
+	message, err := download.PackageMessage(bytes.NewReader(s.payload))
+	if err != nil {
+		panic(err)
+	}
+	s.signature = ed25519.Sign(signing, message)
+	return s
+}
+
+// Tamper serves data instead of the published payload while keeping its checksum and signature.
+func (s *Server) Tamper(data []byte) *Server {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.served = data
+	return s
+}
+
+// Replace serves data with a matching checksum but keeps the signature of the
+// published payload, as a compromised mirror would.
+func (s *Server) Replace(data []byte) *Server {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.served, s.checksum = data, data
+	return s
+}
+
+// Interrupt makes the next response of the installer drop the connection after
+// sending after bytes. Calls add up, one response per call.
+func (s *Server) Interrupt(after int) *Server {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.cuts = append(s.cuts, cut{after: after})
+	return s
+}
+
+// Stall makes the next response of the installer stop sending data after
+// after bytes, keeping the connection open until the client gives up.
+func (s *Server) Stall(after int) *Server {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.cuts = append(s.cuts, cut{after: after, stall: true})
+	return s
+}
+
+// Ranges returns the Range header of each request for the installer so far,
+// an empty string for requests of the whole file.
+func (s *Server) Ranges() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.ranges...)
+}
+
+// PackageURL returns the URL of the installer.
+func (s *Server) PackageURL() string {
+	return s.URL + PackagePath
+}
+
+// serveHTTP answers requests for the installer, its checksum and its signature.
+func (s *Server) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path == PackagePath {
+		s.servePackage(w, r)
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	switch r.URL.Path {
+	case PackagePath + download.ChecksumSuffix:
+		digest := sha256.Sum256(s.checksum)
+		w.Write([]byte(hex.EncodeToString(digest[:]) + "  tailscale-setup-1.0.0.exe\n"))
+	case PackagePath + download.SignatureSuffix:
+		serveOptional(w, r, s.signature)
+	case KeysPath:
+		serveOptional(w, r, s.keys)
+	case KeysPath + download.SignatureSuffix:
+		serveOptional(w, r, s.keysSig)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// servePackage serves the installer with Range and If-Range support, cut short
+// when a cut is pending. The lock is not held while sending, so a stalled
+// response does not block the next request.
+func (s *Server) servePackage(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	served := s.served
+	s.ranges = append(s.ranges, r.Header.Get("Range"))
+	var next *cut
+	if len(s.cuts) > 0 {
+		next = &s.cuts[0]
+		s.cuts = s.cuts[1:]
+	}
+	s.mu.Unlock()
+
+	digest := sha256.Sum256(served)
+	w.Header().Set("ETag", `"`+hex.EncodeToString(digest[:8])+`"`)
+	if next != nil {
+		w = &cutWriter{ResponseWriter: w, request: r, remaining: next.after, stall: next.stall}
+	}
+	http.ServeContent(w, r, PackagePath, time.Time{}, bytes.NewReader(served))
+}
+
+// cutWriter passes the first bytes of a response, then drops the connection or
+// stalls until the client gives up.
+type cutWriter struct {
+	http.ResponseWriter
+	request   *http.Request // request answered, whose context ends when the client gives up
+	remaining int           // bytes still sent before the cut
+	stall     bool          // whether to stall instead of dropping the connection
+}
+
+// Write sends p up to the cut, then flushes what was sent and aborts the handler.
+func (w *cutWriter) Write(p []byte) (int, error) {
+	if len(p) <= w.remaining {
+		w.remaining -= len(p)
+		return w.ResponseWriter.Write(p)
+	}
+	w.ResponseWriter.Write(p[:w.remaining])
+	w.ResponseWriter.(http.Flusher).Flush()
+	if w.stall {
+		<-w.request.Context().Done()
+	}
+	panic(http.ErrAbortHandler)
+}
+
+// serveOptional writes data, or answers 404 when it is nil.
+func serveOptional(w http.ResponseWriter, r *http.Request, data []byte) {
+	if data == nil {
+		http.NotFound(w, r)
+		return
+	}
+	w.Write(data)
+}
@@ -0,0 +1,430 @@
+package download
+
+import (
+	"bufio"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"tailscale/i18n"
+)
+
+const (
+	// osReleasePath is where the distribution is described
+	osReleasePath = "/etc/os-release"
+
+	// osReleaseFallbackPath is used when osReleasePath does not exist
+	osReleaseFallbackPath = "/usr/lib/os-release"
+)
+
+// Installation methods chosen by PlanLinux
+const (
+	MethodApt     = "apt"     // Official apt repository
+	MethodDnf     = "dnf"     // Official dnf repository
+	MethodYum     = "yum"     // Official yum repository, for older RHEL derivatives
+	MethodPacman  = "pacman"  // Arch Linux package
+	MethodZypper  = "zypper"  // Official zypper repository
+	MethodTarball = "tarball" // Static binaries, for every other distribution
+)
+
+// Distro describes a Linux distribution as read from os-release.
+type Distro struct {
+	ID              string   // Lower-case identifier, e.g. "ubuntu"
+	IDLike          []string // Identifiers of related distributions, e.g. "debian"
+	Name            string   // Human-readable name
+	VersionID       string   // Version, e.g. "22.04"
+	VersionCodename string   // Release codename, e.g. "jammy"
+	UbuntuCodename  string   // Codename of the Ubuntu release an Ubuntu derivative is based on
+}
+
+// ParseOSRelease parses the os-release format of freedesktop.org.
+func ParseOSRelease(r io.Reader) (*Distro, error) {
+	values := make(map[string]string)
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		key, value, found := strings.Cut(line, "=")
+		if !found {
+			continue
+		}
+		values[key] = strings.Trim(value, `"'`)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read os-release: %w", err)
+	}
+
+	distro := &Distro{
+		ID:              strings.ToLower(values["ID"]),
+		IDLike:          strings.Fields(strings.ToLower(values["ID_LIKE"])),
+		Name:            values["PRETTY_NAME"],
+		VersionID:       values["VERSION_ID"],
+		VersionCodename: values["VERSION_CODENAME"],
+		UbuntuCodename:  values["UBUNTU_CODENAME"],
+	}
+	if distro.Name == "" {
+		distro.Name = values["NAME"]
+	}
+	if distro.ID == "" {
+		distro.ID = "linux"
+	}
+	return distro, nil
+}
+
+// DetectDistro reads the distribution of this machine from os-release.
+func DetectDistro() (*Distro, error) {
+	file, err := os.Open(osReleasePath)
+	if os.IsNotExist(err) {
+		file, err = os.Open(osReleaseFallbackPath)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to detect distribution: %w", err)
+	}
+	defer file.Close()
+	return ParseOSRelease(file)
+}
+
+// Is reports whether the distribution is, or is derived from, one of ids.
+func (d *Distro) Is(ids ...string) bool {
+	for _, id := range ids {
+		if d.ID == id {
+			return true
+		}
+		for _, like := range d.IDLike {
+			if like == id {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// majorVersion returns the part of VersionID before the first dot.
+func (d *Distro) majorVersion() string {
+	major, _, _ := strings.Cut(d.VersionID, ".")
+	return major
+}
+
+// Step is one action of an installation plan.
+type Step struct {
+	Description string   // What the step does
+	Command     []string // Command run by the step, nil for steps done in Go
+	Privileged  bool     // Whether the command needs root
+	Detail      string   // What a step done in Go does, for dry runs
+
+	run func(ctx context.Context, log io.Writer) error // Implementation of steps done in Go
+}
+
+// String describes the step as shown in dry runs, with sudo prefixed to privileged commands.
+func (s Step) String(sudo []string) string {
+	if s.Command == nil {
+		return s.Description + ": " + s.Detail
+	}
+	command := s.Command
+	if s.Privileged {
+		command = append(append([]string(nil), sudo...), command...)
+	}
+	return s.Description + ": " + strings.Join(command, " ")
+}
+
+// Plan is the list of steps installing Tailscale on a Linux distribution.
+type Plan struct {
+	Distro  *Distro  // Distribution the plan was made for
+	Method  string   // Installation method, one of the Method constants
+	Target  Target   // Build being installed
+	Steps   []Step   // Steps in execution order
+	Notes   []string // Remarks shown after the steps
+	sudo    []string // Prefix of privileged commands, empty when running as root
+	staging string   // Private directory receiving downloads, created by Run
+	archive string   // Tarball on disk the plan installs from, if any
+}
+
+// newPlan creates an empty plan using a private staging directory under the temp directory.
+func newPlan(distro *Distro, target Target, method string) *Plan {
+	suffix := make([]byte, 8)
+	rand.Read(suffix)
+
+	plan := &Plan{
+		Distro:  distro,
+		Method:  method,
+		Target:  target,
+		staging: filepath.Join(os.TempDir(), "sky-tailscale-install-"+hex.EncodeToString(suffix)),
+	}
+	if os.Geteuid() != 0 {
+		// Privileged commands run in the background and must not prompt for a password
+		plan.sudo = []string{"sudo", "-n"}
+	}
+	return plan
+}
+
+// WithSudo sets the prefix of privileged commands, e.g. "sudo" to allow a password prompt.
+// It has no effect when running as root.
+func (p *Plan) WithSudo(sudo ...string) *Plan {
+	if os.Geteuid() != 0 {
+		p.sudo = sudo
+	}
+	return p
+}
+
+// command appends a step running args.
+func (p *Plan) command(description string, privileged bool, args ...string) {
+	p.Steps = append(p.Steps, Step{Description: description, Command: args, Privileged: privileged})
+}
+
+// fetch appends steps downloading url and installing it as dest with the given mode.
+func (p *Plan) fetch(description, url, dest, mode string) {
+	staged := filepath.Join(p.staging, filepath.Base(dest))
+	p.Steps = append(p.Steps, Step{
+		Description: description,
+		Detail:      i18n.T(i18n.PlanDownload, url),
+		run: func(ctx context.Context, log io.Writer) error {
+			return fetchFile(ctx, url, staged, log)
+		},
+	})
+	p.command(i18n.T(i18n.PlanInstallFile, dest), true, "install", "-D", "-m", mode, staged, dest)
+}
+
+// PlanLinux returns the plan installing target on distro using its official Tailscale
+// repository, or the static tarball when the distribution is not supported or the
+// target uses a mirror.
+func PlanLinux(distro *Distro, target Target) *Plan {
+	var plan *Plan
+	switch {
+	case target.Mirror != "":
+		// Repository definitions point at the official server, so mirrors serve the tarball
+	case distro.Is("ubuntu", "debian", "raspbian"):
+		plan = planApt(distro, target)
+	case distro.Is("fedora", "rhel", "centos", "amzn"):
+		plan = planRPM(distro, target)
+	case distro.Is("arch") && !target.Pinned() && target.channel() == ChannelStable:
+		// Arch only packages the latest stable release
+		plan = newPlan(distro, target, MethodPacman)
+		plan.command(i18n.T(i18n.PlanInstallPackage), true, "pacman", "-Sy", "--noconfirm", "--needed", "tailscale")
+	case distro.Is("opensuse-tumbleweed", "opensuse-leap", "opensuse", "suse"):
+		plan = planZypper(distro, target)
+	}
+	if plan == nil {
+		plan = planTarball(distro, target, runtime.GOARCH)
+	}
+	return withService(plan)
+}
+
+// PlanLinuxArchive returns the plan installing the static binaries from a tarball on disk,
+// such as a cached or hand-copied one, after checking it has the expected hex SHA-256.
+func PlanLinuxArchive(distro *Distro, archive, expected string) *Plan {
+	// The version is only known from the name of official tarballs
+	var target Target
+	if match := indexVersionRef.FindStringSubmatch(filepath.Base(archive)); match != nil {
+		target.Version = match[1]
+	}
+	plan := newPlan(distro, target, MethodTarball)
+	plan.archive = archive
+	plan.Steps = append(plan.Steps, Step{
+		Description: i18n.T(i18n.PlanExtractArchive),
+		Detail:      archive,
+		run: func(ctx context.Context, log io.Writer) error {
+			if err := VerifyFile(archive, expected); err != nil {
+				return err
+			}
+			fmt.Fprintln(log, i18n.T(i18n.PlanChecksumVerified, expected))
+			return extractTarball(archive, plan.staging)
+		},
+	})
+	plan.installTarballFiles()
+	return withService(plan)
+}
+
+// withService appends the step enabling tailscaled, or a note when systemd is missing.
+func withService(plan *Plan) *Plan {
+	if _, err := exec.LookPath("systemctl"); err == nil {
+		plan.command(i18n.T(i18n.PlanEnableService), true, "systemctl", "enable", "--now", "tailscaled")
+	} else {
+		plan.Notes = append(plan.Notes, i18n.T(i18n.PlanNoSystemd))
+	}
+	return plan
+}
+
+// planApt configures the official apt repository. It returns nil when the release codename is unknown.
+func planApt(distro *Distro, target Target) *Plan {
+	repo, codename := "debian", distro.VersionCodename
+	switch {
+	case distro.ID == "raspbian":
+		repo = "raspbian"
+	case distro.ID == "ubuntu" || distro.UbuntuCodename != "":
+		repo = "ubuntu"
+		if distro.UbuntuCodename != "" {
+			codename = distro.UbuntuCodename
+		}
+	}
+	if codename == "" {
+		return nil
+	}
+
+	base := fmt.Sprintf("%s/%s/%s", target.ChannelURL(), repo, codename)
+	plan := newPlan(distro, target, MethodApt)
+	plan.fetch(i18n.T(i18n.PlanAddKey), base+".noarmor.gpg", "/usr/share/keyrings/tailscale-archive-keyring.gpg", "0644")
+	plan.fetch(i18n.T(i18n.PlanAddRepository), base+".tailscale-keyring.list", "/etc/apt/sources.list.d/tailscale.list", "0644")
+	plan.command(i18n.T(i18n.PlanUpdateIndex), true, "apt-get", "update")
+	if target.Pinned() {
+		plan.command(i18n.T(i18n.PlanInstallVersion, target.Version), true, "apt-get", "install", "-y", "--allow-downgrades", "tailscale="+target.Version)
+	} else {
+		plan.command(i18n.T(i18n.PlanInstallPackage), true, "apt-get", "install", "-y", "tailscale")
+	}
+	return plan
+}
+
+// planRPM configures the official dnf or yum repository. It returns nil for unsupported releases.
+func planRPM(distro *Distro, target Target) *Plan {
+	major := distro.majorVersion()
+	var repo string
+	switch {
+	case distro.ID == "fedora":
+		repo = "fedora"
+	case distro.ID == "amzn" && major == "2":
+		repo = "amazon-linux/2"
+	case distro.ID == "centos" && major != "":
+		repo = "centos/" + major
+	case distro.Is("rhel", "centos") && major != "":
+		repo = "rhel/" + major
+	default:
+		return nil
+	}
+
+	method := MethodDnf
+	if _, err := exec.LookPath("dnf"); err != nil {
+		method = MethodYum
+	}
+	plan := newPlan(distro, target, method)
+	plan.fetch(i18n.T(i18n.PlanAddRepository), fmt.Sprintf("%s/%s/tailscale.repo", target.ChannelURL(), repo), "/etc/yum.repos.d/tailscale.repo", "0644")
+	if target.Pinned() {
+		// install does not downgrade, but it is a no-op when the version is already installed
+		plan.command(i18n.T(i18n.PlanInstallVersion, target.Version), true, method, "install", "-y", "tailscale-"+target.Version)
+		plan.command(i18n.T(i18n.PlanDowngrade, target.Version), true, "sh", "-c", method+" downgrade -y tailscale-"+target.Version+" || true")
+	} else {
+		plan.command(i18n.T(i18n.PlanInstallPackage), true, method, "install", "-y", "tailscale")
+	}
+	return plan
+}
+
+// planZypper configures the official zypper repository. It returns nil for unsupported releases.
+func planZypper(distro *Distro, target Target) *Plan {
+	var repo string
+	switch {
+	case distro.ID == "opensuse-tumbleweed":
+		repo = "opensuse/tumbleweed"
+	case distro.ID == "opensuse-leap" && distro.VersionID != "":
+		repo = "opensuse/leap/" + distro.VersionID
+	default:
+		return nil
+	}
+
+	plan := newPlan(distro, target, MethodZypper)
+	plan.command(i18n.T(i18n.PlanAddRepository), true, "zypper", "--non-interactive", "addrepo", "--gpgcheck", "--refresh", fmt.Sprintf("%s/%s/tailscale.repo", target.ChannelURL(), repo))
+	plan.command(i18n.T(i18n.PlanRefreshRepositories), true, "zypper", "--non-interactive", "--gpg-auto-import-keys", "refresh")
+	if target.Pinned() {
+		plan.command(i18n.T(i18n.PlanInstallVersion, target.Version), true, "zypper", "--non-interactive", "install", "--oldpackage", "tailscale="+target.Version)
+	} else {
+		plan.command(i18n.T(i18n.PlanInstallPackage), true, "zypper", "--non-interactive", "install", "tailscale")
+	}
+	return plan
+}
+
+// Describe returns the plan as numbered lines, as shown by dry runs.
+func (p *Plan) Describe() []string {
+	source := p.Target.String()
+	if p.archive != "" {
+		source = i18n.T(i18n.PlanFromArchive, filepath.Base(p.archive))
+	}
+	lines := []string{i18n.T(i18n.PlanHeader, source, p.Distro.Name, p.Method)}
+	for i, step := range p.Steps {
+		lines = append(lines, fmt.Sprintf("%d. %s", i+1, step.String(p.sudo)))
+	}
+	for _, note := range p.Notes {
+		lines = append(lines, i18n.T(i18n.PlanNote, note))
+	}
+	return lines
+}
+
+// Run executes the steps in order, writing their output to log. It stops at the first failing step.
+func (p *Plan) Run(ctx context.Context, log io.Writer) error {
+	if err := os.Mkdir(p.staging, 0o700); err != nil {
+		return fmt.Errorf("failed to create staging directory: %w", err)
+	}
+	defer os.RemoveAll(p.staging)
+
+	for i, step := range p.Steps {
+		fmt.Fprintf(log, "==> [%d/%d] %s\n", i+1, len(p.Steps), step.Description)
+
+		var err error
+		if step.run != nil {
+			err = step.run(ctx, log)
+		} else {
+			err = p.runCommand(ctx, step, log)
+		}
+		if err != nil {
+			return fmt.Errorf("step %q failed: %w", step.Description, err)
+		}
+	}
+	for _, note := range p.Notes {
+		fmt.Fprintln(log, i18n.T(i18n.PlanNote, note))
+	}
+	return nil
+}
+
+// runCommand runs the command of step, prefixed with sudo when it is privileged.
+func (p *Plan) runCommand(ctx context.Context, step Step, log io.Writer) error {
+	args := step.Command
+	if step.Privileged {
+		args = append(append([]string(nil), p.sudo...), args...)
+	}
+	fmt.Fprintln(log, "$ "+strings.Join(args, " "))
+
+	cmd := exec.CommandContext(ctx, args[0], args[1:]...)
+	cmd.Stdout = log
+	cmd.Stderr = log
+	if err := cmd.Run(); err != nil {
+		if step.Privileged && len(p.sudo) > 0 && p.sudo[len(p.sudo)-1] == "-n" {
+			return fmt.Errorf("%w (run as root, or run 'sudo -v' first so sudo does not need a password)", err)
+		}
+		return err
+	}
+	return nil
+}
+
+// fetchFile downloads url into path.
+func fetchFile(ctx context.Context, url, path string, log io.Writer) error {
+	fmt.Fprintln(log, i18n.T(i18n.DownloadStarted, url))
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to download %s: %w", url, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("failed to download %s: %s", url, resp.Status)
+	}
+
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o600)
+	if err != nil {
+		return fmt.Errorf("failed to create file: %w", err)
+	}
+	if _, err := io.Copy(file, resp.Body); err != nil {
+		file.Close()
+		return fmt.Errorf("failed to download %s: %w", url, err)
+	}
+	return file.Close()
+}
@@ -0,0 +1,129 @@
+package download
+
+import (
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"tailscale/utils/drawer"
+)
+
+// Intervals between two progress updates
+const (
+	drawInterval = 100 * time.Millisecond // Redraws of the progress bar
+	logInterval  = 2 * time.Second        // Progress lines written to a log
+)
+
+// progress tracks a transfer and draws its progress bar, speed and ETA,
+// or writes them as text lines when its output has a log.
+type progress struct {
+	out        Output      // Where progress is reported
+	y          int         // Line the progress bar is drawn on
+	total      int64       // Expected size in bytes, or -1 if unknown
+	downloaded int64       // Bytes written to the file so far
+	resumedAt  int64       // Bytes already present when the current attempt started
+	started    time.Time   // When the current attempt started
+	lastDraw   time.Time   // When the bar was last drawn
+	frame      int         // Animation frame of the indeterminate bar
+	resized    atomic.Bool // Set when the terminal was resized, so the bar is redrawn at once
+	stopResize func()      // Removes the resize listener, nil when progress goes to a log
+}
+
+// newProgress creates a progress reported to out, drawn on the current line of the terminal.
+func newProgress(out Output) *progress {
+	p := &progress{out: out, total: -1}
+	if out.log == nil {
+		p.y = out.d.GetY()
+		p.stopResize = out.d.OnResize(func(width, height int) {
+			p.resized.Store(true)
+		})
+	}
+	return p
+}
+
+// close stops following terminal resizes.
+func (p *progress) close() {
+	if p.stopResize != nil {
+		p.stopResize()
+	}
+}
+
+// start begins a new attempt resuming at offset of a file of total bytes (-1 if unknown).
+func (p *progress) start(offset, total int64) {
+	p.downloaded = offset
+	p.resumedAt = offset
+	p.total = total
+	p.started = time.Now()
+	p.draw("")
+}
+
+// add records n more bytes and redraws the bar at most every drawInterval,
+// or right away after a resize so it is laid out for the new width.
+func (p *progress) add(n int) {
+	p.downloaded += int64(n)
+	interval := drawInterval
+	if p.out.log != nil {
+		interval = logInterval
+	}
+	if p.resized.Swap(false) || time.Since(p.lastDraw) >= interval {
+		p.draw("")
+	}
+}
+
+// draw renders the bar with the transfer details, or with status instead when it is not empty.
+func (p *progress) draw(status string) {
+	p.lastDraw = time.Now()
+
+	info := status
+	if info == "" {
+		info = p.details()
+	}
+	if p.out.log != nil {
+		if p.total > 0 {
+			info = fmt.Sprintf("%3d%%  %s", p.downloaded*100/p.total, info)
+		}
+		fmt.Fprintln(p.out.log, "  "+info)
+		return
+	}
+	if p.total > 0 {
+		percent := int(float64(p.downloaded) / float64(p.total) * 100)
+		p.out.d.DrawProgressBarWithInfo(p.y, min(percent, 100), info, drawer.DefaultOption)
+		return
+	}
+	p.frame++
+	p.out.d.DrawIndeterminateBar(p.y, p.frame, info, drawer.DefaultOption)
+}
+
+// details formats the downloaded size, transfer speed and remaining time.
+func (p *progress) details() string {
+	text := formatBytes(p.downloaded)
+	if p.total > 0 {
+		text += " / " + formatBytes(p.total)
+	}
+
+	elapsed := time.Since(p.started).Seconds()
+	if elapsed <= 0 || p.downloaded <= p.resumedAt {
+		return text
+	}
+	speed := float64(p.downloaded-p.resumedAt) / elapsed
+	text += "  " + formatBytes(int64(speed)) + "/s"
+	if p.total > 0 && speed > 0 {
+		eta := time.Duration(float64(p.total-p.downloaded) / speed * float64(time.Second))
+		text += "  ETA " + eta.Round(time.Second).String()
+	}
+	return text
+}
+
+// formatBytes formats n as a human-readable size using binary units.
+func formatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
@@ -0,0 +1,36 @@
+package download
+
+import (
+	"strings"
+	"testing"
+
+	"tailscale/utils/drawer"
+
+	"github.com/nsf/termbox-go"
+)
+
+func TestProgressRedrawsOnResize(t *testing.T) {
+	screen := drawer.NewMemoryScreen(60, 4)
+	d := drawer.New(screen)
+	p := newProgress(ToDrawer(d))
+	defer p.close()
+	p.start(0, 100)
+	// Within the draw interval the bar is not drawn again
+	p.add(50)
+	if line := screen.Line(0); !strings.Contains(line, " 0/100") {
+		t.Fatalf("bar before the resize = %q, want 0/100", line)
+	}
+
+	screen.Resize(30, 4)
+	if event := d.PollEvent(); event.Type != termbox.EventResize {
+		t.Fatalf("event = %+v, want EventResize", event)
+	}
+	p.add(0)
+	line := screen.Line(0)
+	if !strings.HasPrefix(line, "|") || !strings.Contains(line, " 50/100") {
+		t.Errorf("bar after the resize = %q, want it redrawn at 50/100", line)
+	}
+	if width := drawer.TextWidth(line); width > 30 {
+		t.Errorf("bar after the resize is %d columns wide, want at most 30", width)
+	}
+}
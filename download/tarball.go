@@ -0,0 +1,156 @@
+package download
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"tailscale/i18n"
+)
+
+// tarballFiles maps the files installed from the static tarball to their destination and mode.
+var tarballFiles = []struct {
+	name string // Path inside the tarball, below its top-level directory
+	dest string // Installed location
+	mode string // Installed permissions
+}{
+	{"tailscale", "/usr/bin/tailscale", "0755"},
+	{"tailscaled", "/usr/sbin/tailscaled", "0755"},
+	{"systemd/tailscaled.service", "/etc/systemd/system/tailscaled.service", "0644"},
+	{"systemd/tailscaled.defaults", "/etc/default/tailscaled", "0644"},
+}
+
+// packageIndex is the part of the ?mode=json package index listing the static tarballs.
+type packageIndex struct {
+	TarballsVersion string            `json:"TarballsVersion"` // Version of the tarballs
+	Tarballs        map[string]string `json:"Tarballs"`        // File name per architecture
+}
+
+// planTarball installs the static binaries of target for arch.
+func planTarball(distro *Distro, target Target, arch string) *Plan {
+	plan := newPlan(distro, target, MethodTarball)
+	detail := i18n.T(i18n.PlanLatestTarball, arch, target.ChannelURL())
+	if target.Pinned() {
+		detail = i18n.T(i18n.PlanDownload, target.TarballURL(arch))
+	}
+	plan.Steps = append(plan.Steps, Step{
+		Description: i18n.T(i18n.PlanDownloadTarball),
+		Detail:      detail,
+		run: func(ctx context.Context, log io.Writer) error {
+			return fetchTarball(ctx, target, arch, plan.staging, log)
+		},
+	})
+	plan.installTarballFiles()
+	if distro.ID != "linux" && target.Mirror == "" {
+		plan.Notes = append(plan.Notes, i18n.T(i18n.PlanNoRepository, distro.Name))
+	}
+	return plan
+}
+
+// installTarballFiles appends the steps installing the files extracted into the staging directory.
+func (p *Plan) installTarballFiles() {
+	_, lookErr := exec.LookPath("systemctl")
+	for _, file := range tarballFiles {
+		// The service files are only useful with systemd
+		if strings.HasPrefix(file.name, "systemd/") && lookErr != nil {
+			continue
+		}
+		p.command(i18n.T(i18n.PlanInstallFile, file.dest), true, "install", "-D", "-m", file.mode, filepath.Join(p.staging, path.Base(file.name)), file.dest)
+	}
+}
+
+// fetchTarball downloads the tarball of target for arch into dir, checks its
+// published checksum and extracts the files listed in tarballFiles.
+func fetchTarball(ctx context.Context, target Target, arch, dir string, log io.Writer) error {
+	url := target.TarballURL(arch)
+	if !target.Pinned() {
+		index, err := fetchPackageIndex(ctx, target.ChannelURL())
+		if err != nil {
+			return err
+		}
+		name, found := index.Tarballs[arch]
+		if !found {
+			return fmt.Errorf("no Tailscale tarball is published for %s", arch)
+		}
+		fmt.Fprintln(log, i18n.T(i18n.PlanLatestVersion, index.TarballsVersion))
+		url = target.ChannelURL() + "/" + name
+	}
+
+	archive := filepath.Join(dir, path.Base(url))
+	if err := fetchFile(ctx, url, archive, log); err != nil {
+		return err
+	}
+	expected, err := FetchChecksum(ctx, url)
+	if err != nil {
+		return err
+	}
+	if err := VerifyFile(archive, expected); err != nil {
+		return err
+	}
+	fmt.Fprintln(log, i18n.T(i18n.PlanChecksumVerified, expected))
+
+	return extractTarball(archive, dir)
+}
+
+// extractTarball extracts the files listed in tarballFiles from archive into dir, flattened.
+func extractTarball(archive, dir string) error {
+	file, err := os.Open(archive)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	gz, err := gzip.NewReader(file)
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", archive, err)
+	}
+	reader := tar.NewReader(gz)
+
+	wanted := make(map[string]bool)
+	for _, f := range tarballFiles {
+		wanted[f.name] = true
+	}
+	for {
+		header, err := reader.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read %s: %w", archive, err)
+		}
+
+		// Entries are below a versioned directory such as tailscale_1.2.3_amd64/
+		_, name, found := strings.Cut(path.Clean(header.Name), "/")
+		if !found || !wanted[name] || header.Typeflag != tar.TypeReg {
+			continue
+		}
+		if err := extractFile(reader, filepath.Join(dir, path.Base(name))); err != nil {
+			return err
+		}
+		delete(wanted, name)
+	}
+	for name := range wanted {
+		return fmt.Errorf("%s is missing from %s", name, archive)
+	}
+	return nil
+}
+
+// extractFile writes the current tar entry to dest.
+func extractFile(src io.Reader, dest string) error {
+	file, err := os.OpenFile(dest, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o600)
+	if err != nil {
+		return fmt.Errorf("failed to create %s: %w", dest, err)
+	}
+	if _, err := io.Copy(file, src); err != nil {
+		file.Close()
+		return fmt.Errorf("failed to extract %s: %w", dest, err)
+	}
+	return file.Close()
+}
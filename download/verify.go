@@ -0,0 +1,160 @@
+package download
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+
+	"tailscale/i18n"
+)
+
+const (
+	// ChecksumSuffix is appended to a package URL to get its published SHA-256 checksum
+	ChecksumSuffix = ".sha256"
+
+	// SignatureSuffix is appended to a package or key bundle URL to get its detached ed25519 signature
+	SignatureSuffix = ".sig"
+
+	// maxMetadataSize limits the size of checksum and signature files
+	maxMetadataSize = 4 << 10
+)
+
+// ErrNotVerified is returned when asked to install a file that was not verified.
+var ErrNotVerified = errors.New("installer has not been verified")
+
+// ChecksumError reports a file whose SHA-256 does not match the published one.
+type ChecksumError struct {
+	File     string // Path of the rejected file
+	Expected string // Published checksum
+	Actual   string // Checksum of the file on disk
+}
+
+// Error describes the mismatch.
+func (e *ChecksumError) Error() string {
+	return fmt.Sprintf("checksum mismatch for %s: expected sha256 %s, got %s", e.File, e.Expected, e.Actual)
+}
+
+// SignatureError reports a signature that none of the trusted keys accepts.
+type SignatureError struct {
+	URL string // Signature that was rejected
+}
+
+// Error describes the rejected signature.
+func (e *SignatureError) Error() string {
+	return fmt.Sprintf("signature %s does not match any trusted signing key", e.URL)
+}
+
+// Verification is the result of checking a downloaded installer.
+type Verification struct {
+	SHA256           string // Verified hex SHA-256 of the file
+	SignatureChecked bool   // Whether a signature was published and verified
+}
+
+// String summarizes the verification for display.
+func (v *Verification) String() string {
+	if v.SignatureChecked {
+		return i18n.T(i18n.DownloadSignatureOK, v.SHA256[:16])
+	}
+	return i18n.T(i18n.DownloadChecksumOK, v.SHA256[:16])
+}
+
+// FetchChecksum downloads the published SHA-256 of the package at url.
+// The file holds the hex digest, optionally followed by the file name.
+func FetchChecksum(ctx context.Context, url string) (string, error) {
+	data, err := fetchMetadata(ctx, url+ChecksumSuffix)
+	if err != nil {
+		return "", fmt.Errorf("failed to fetch checksum: %w", err)
+	}
+	if data == nil {
+		return "", fmt.Errorf("failed to fetch checksum: %s not found", url+ChecksumSuffix)
+	}
+	return parseChecksum(data, url+ChecksumSuffix)
+}
+
+// parseChecksum reads the hex digest at the start of the checksum file named source.
+func parseChecksum(data []byte, source string) (string, error) {
+	fields := strings.Fields(string(data))
+	if len(fields) == 0 {
+		return "", fmt.Errorf("checksum file %s is empty", source)
+	}
+	checksum := strings.ToLower(fields[0])
+	if decoded, err := hex.DecodeString(checksum); err != nil || len(decoded) != sha256.Size {
+		return "", fmt.Errorf("checksum file %s does not contain a SHA-256 digest", source)
+	}
+	return checksum, nil
+}
+
+// fetchMetadata downloads a small file, returning nil data if it does not exist.
+func fetchMetadata(ctx context.Context, url string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s: unexpected status: %s", url, resp.Status)
+	}
+	return io.ReadAll(io.LimitReader(resp.Body, maxMetadataSize))
+}
+
+// FileSHA256 returns the hex SHA-256 of the file at path.
+func FileSHA256(path string) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	hash := sha256.New()
+	if _, err := io.Copy(hash, file); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// VerifyFile checks that the file at path has the expected hex SHA-256.
+func VerifyFile(path, expected string) error {
+	actual, err := FileSHA256(path)
+	if err != nil {
+		return fmt.Errorf("failed to hash %s: %w", path, err)
+	}
+	if !strings.EqualFold(actual, expected) {
+		return &ChecksumError{File: path, Expected: expected, Actual: actual}
+	}
+	return nil
+}
+
+// VerifyLocal checks an installer copied to path by hand, for air-gapped installs.
+// The expected hex SHA-256 is read from the .sha256 file next to it when empty.
+func VerifyLocal(path, expected string) (*Verification, error) {
+	if expected == "" {
+		data, err := os.ReadFile(path + ChecksumSuffix)
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, fmt.Errorf("no checksum for %s: copy its %s file next to it or give the expected SHA-256", path, ChecksumSuffix)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read checksum: %w", err)
+		}
+		if expected, err = parseChecksum(data, path+ChecksumSuffix); err != nil {
+			return nil, err
+		}
+	}
+	if err := VerifyFile(path, expected); err != nil {
+		return nil, err
+	}
+	return &Verification{SHA256: strings.ToLower(expected)}, nil
+}
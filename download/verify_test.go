@@ -0,0 +1,141 @@
+package download_test
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"tailscale/download"
+	"tailscale/download/downloadtest"
+)
+
+// newKey returns a new ed25519 key pair, failing the test on error.
+func newKey(t *testing.T) (ed25519.PublicKey, ed25519.PrivateKey) {
+	t.Helper()
+	public, private, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return public, private
+}
+
+// trustRoot makes the downloader trust root until the test ends.
+func trustRoot(t *testing.T, root ed25519.PublicKey) {
+	t.Helper()
+	previous := download.SetRootKeys(root)
+	t.Cleanup(func() { download.SetRootKeys(previous...) })
+}
+
+func TestFetchVerifies(t *testing.T) {
+	payload := bytes.Repeat([]byte("tailscale installer "), 1000)
+	rootPublic, root := newKey(t)
+	_, signing := newKey(t)
+	_, otherRoot := newKey(t)
+
+	tests := []struct {
+		name       string
+		setup      func(s *downloadtest.Server)
+		wantSigned bool
+		wantErr    func(error) bool
+	}{
+		{
+			name:  "unsigned",
+			setup: func(s *downloadtest.Server) {},
+		},
+		{
+			name:       "signed",
+			setup:      func(s *downloadtest.Server) { s.Sign(root, signing) },
+			wantSigned: true,
+		},
+		{
+			name:    "tampered",
+			setup:   func(s *downloadtest.Server) { s.Sign(root, signing).Tamper([]byte("malware")) },
+			wantErr: isChecksumError,
+		},
+		{
+			name:    "replaced with its checksum",
+			setup:   func(s *downloadtest.Server) { s.Sign(root, signing).Replace([]byte("malware")) },
+			wantErr: isSignatureError,
+		},
+		{
+			name:    "signing keys of an untrusted root",
+			setup:   func(s *downloadtest.Server) { s.Sign(otherRoot, signing) },
+			wantErr: isSignatureError,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			trustRoot(t, rootPublic)
+			server := downloadtest.NewServer(payload)
+			defer server.Close()
+			tt.setup(server)
+
+			fileName := filepath.Join(t.TempDir(), "tailscale-setup.exe")
+			verification, err := download.Fetch(context.Background(), server.PackageURL(), fileName, io.Discard)
+
+			if tt.wantErr != nil {
+				if !tt.wantErr(err) {
+					t.Fatalf("Fetch error = %v", err)
+				}
+				if _, err := os.Stat(fileName); !errors.Is(err, os.ErrNotExist) {
+					t.Errorf("rejected installer was kept: %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			digest := sha256.Sum256(payload)
+			if verification.SHA256 != hex.EncodeToString(digest[:]) || verification.SignatureChecked != tt.wantSigned {
+				t.Errorf("verification = %+v, want signed %v", verification, tt.wantSigned)
+			}
+			if data, err := os.ReadFile(fileName); err != nil || !bytes.Equal(data, payload) {
+				t.Errorf("downloaded file differs from the payload: %v", err)
+			}
+		})
+	}
+}
+
+func TestEmbeddedRootKeys(t *testing.T) {
+	roots := download.SetRootKeys()
+	download.SetRootKeys(roots...)
+	if len(roots) == 0 {
+		t.Fatal("no root key embedded")
+	}
+}
+
+func TestParsePublicKeys(t *testing.T) {
+	public, _ := newKey(t)
+	block := "-----BEGIN SIGNING PUBLIC KEY-----\n" + base64.StdEncoding.EncodeToString(public) + "\n-----END SIGNING PUBLIC KEY-----\n"
+
+	keys, err := download.ParsePublicKeys([]byte(block+block), download.SigningKeyPEM)
+	if err != nil || len(keys) != 2 || !keys[0].Equal(public) {
+		t.Errorf("ParsePublicKeys = %v, %v", keys, err)
+	}
+	if _, err := download.ParsePublicKeys([]byte(block), download.RootKeyPEM); err == nil {
+		t.Error("ParsePublicKeys accepted a signing key as a root key")
+	}
+	if _, err := download.ParsePublicKeys([]byte("no keys"), download.SigningKeyPEM); err == nil {
+		t.Error("ParsePublicKeys accepted data without keys")
+	}
+}
+
+// isChecksumError reports whether err is a *download.ChecksumError.
+func isChecksumError(err error) bool {
+	var checksumErr *download.ChecksumError
+	return errors.As(err, &checksumErr)
+}
+
+// isSignatureError reports whether err is a *download.SignatureError.
+func isSignatureError(err error) bool {
+	var signatureErr *download.SignatureError
+	return errors.As(err, &signatureErr)
+}
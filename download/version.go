@@ -0,0 +1,69 @@
+package download
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Version is a Tailscale release number such as 1.76.6.
+type Version struct {
+	Major int
+	Minor int
+	Patch int
+}
+
+// ParseVersion parses a version such as "1.76.6", also accepting a leading "v"
+// and the build suffix of long versions such as "1.76.6-t2cd4a5e5c-g1c9d1bd1b".
+func ParseVersion(s string) (Version, error) {
+	s = strings.TrimPrefix(strings.TrimSpace(s), "v")
+	short, _, _ := strings.Cut(s, "-")
+	parts := strings.Split(short, ".")
+	if len(parts) != 3 {
+		return Version{}, fmt.Errorf("invalid version %q", s)
+	}
+
+	var numbers [3]int
+	for i, part := range parts {
+		n, err := strconv.Atoi(part)
+		if err != nil || n < 0 {
+			return Version{}, fmt.Errorf("invalid version %q", s)
+		}
+		numbers[i] = n
+	}
+	return Version{Major: numbers[0], Minor: numbers[1], Patch: numbers[2]}, nil
+}
+
+// String formats the version as "major.minor.patch".
+func (v Version) String() string {
+	return fmt.Sprintf("%d.%d.%d", v.Major, v.Minor, v.Patch)
+}
+
+// IsZero reports whether the version is unset.
+func (v Version) IsZero() bool {
+	return v == Version{}
+}
+
+// Compare returns -1, 0 or 1 when v is older than, equal to or newer than other.
+func (v Version) Compare(other Version) int {
+	for _, d := range []int{v.Major - other.Major, v.Minor - other.Minor, v.Patch - other.Patch} {
+		if d < 0 {
+			return -1
+		}
+		if d > 0 {
+			return 1
+		}
+	}
+	return 0
+}
+
+// Less reports whether v is older than other.
+func (v Version) Less(other Version) bool {
+	return v.Compare(other) < 0
+}
+
+// IsUnstable reports whether the version belongs to the unstable channel,
+// whose releases have an odd minor number.
+func (v Version) IsUnstable() bool {
+	return v.Minor%2 == 1
+}
@@ -0,0 +1,177 @@
+package download
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Release channels of the package repositories
+const (
+	ChannelStable   = "stable"   // Releases recommended for production
+	ChannelUnstable = "unstable" // Development builds
+)
+
+// packagesRoot is the host serving every channel.
+const packagesRoot = "https://pkgs.tailscale.com"
+
+// maxIndexSize limits the size of a package index page.
+const maxIndexSize = 8 << 20
+
+// Patterns matching a version and the versions referenced by package file names and version links
+var (
+	versionPattern  = regexp.MustCompile(`^\d+\.\d+\.\d+$`)
+	indexVersionRef = regexp.MustCompile(`(?:tailscale-setup-|tailscale_|[?&]v=)(\d+\.\d+\.\d+)`)
+)
+
+// Target selects which Tailscale build to install. The zero value is the latest stable release.
+type Target struct {
+	Channel string // Release channel, ChannelStable when empty
+	Version string // Exact version such as "1.76.6", the latest release of the channel when empty
+	Mirror  string // Root of a mirror with the layout of pkgs.tailscale.com, the official server when empty
+}
+
+// ParseTarget validates a channel and version, either of which may be empty.
+func ParseTarget(channel, version string) (Target, error) {
+	channel = strings.ToLower(strings.TrimSpace(channel))
+	version = strings.TrimPrefix(strings.TrimSpace(version), "v")
+	switch channel {
+	case "", ChannelStable, ChannelUnstable:
+	default:
+		return Target{}, fmt.Errorf("unknown channel %q (available: %s, %s)", channel, ChannelStable, ChannelUnstable)
+	}
+	if version != "" && !versionPattern.MatchString(version) {
+		return Target{}, fmt.Errorf("invalid version %q, expected a version such as 1.76.6", version)
+	}
+	return Target{Channel: channel, Version: version}, nil
+}
+
+// channel returns the channel of the target, defaulting to stable.
+func (t Target) channel() string {
+	if t.Channel == "" {
+		return ChannelStable
+	}
+	return t.Channel
+}
+
+// Pinned reports whether the target asks for an exact version.
+func (t Target) Pinned() bool {
+	return t.Version != ""
+}
+
+// String describes the target, e.g. "1.76.6 (stable)" or "latest unstable".
+func (t Target) String() string {
+	if t.Pinned() {
+		return fmt.Sprintf("%s (%s)", t.Version, t.channel())
+	}
+	return "latest " + t.channel()
+}
+
+// ChannelURL returns the root of the package repository of the target channel.
+func (t Target) ChannelURL() string {
+	if t.Mirror != "" {
+		return strings.TrimSuffix(t.Mirror, "/") + "/" + t.channel()
+	}
+	return packagesRoot + "/" + t.channel()
+}
+
+// Resolve returns the target pinned to the latest release of its channel when it is not pinned already.
+func (t Target) Resolve(ctx context.Context) (Target, error) {
+	if t.Pinned() {
+		return t, nil
+	}
+	latest, err := LatestVersion(ctx, t)
+	if err != nil {
+		return t, err
+	}
+	t.Version = latest.String()
+	return t, nil
+}
+
+// WindowsURL returns the URL of the Windows installer of the target.
+func (t Target) WindowsURL() string {
+	if !t.Pinned() {
+		return t.ChannelURL() + "/tailscale-setup-latest.exe"
+	}
+	return fmt.Sprintf("%s/tailscale-setup-%s.exe", t.ChannelURL(), t.Version)
+}
+
+// TarballURL returns the URL of the static Linux binaries of the pinned target for arch.
+func (t Target) TarballURL(arch string) string {
+	return fmt.Sprintf("%s/tailscale_%s_%s.tgz", t.ChannelURL(), t.Version, arch)
+}
+
+// LatestVersion returns the version currently published as the latest release of the target channel.
+func LatestVersion(ctx context.Context, target Target) (Version, error) {
+	index, err := fetchPackageIndex(ctx, target.ChannelURL())
+	if err != nil {
+		return Version{}, err
+	}
+	version, err := ParseVersion(index.TarballsVersion)
+	if err != nil {
+		return Version{}, fmt.Errorf("package index of %s does not name a valid version: %w", target.channel(), err)
+	}
+	return version, nil
+}
+
+// ListVersions returns the versions available on the target channel, newest first. They are
+// read from the links of the HTML package index, which also lists older releases.
+func ListVersions(ctx context.Context, target Target) ([]Version, error) {
+	url := target.ChannelURL() + "/"
+	page, err := fetchPage(ctx, url)
+	if err != nil {
+		return nil, err
+	}
+
+	seen := make(map[Version]bool)
+	var versions []Version
+	for _, match := range indexVersionRef.FindAllStringSubmatch(string(page), -1) {
+		version, err := ParseVersion(match[1])
+		if err == nil && !seen[version] {
+			seen[version] = true
+			versions = append(versions, version)
+		}
+	}
+	if len(versions) == 0 {
+		return nil, fmt.Errorf("no versions found at %s", url)
+	}
+	sort.Slice(versions, func(i, j int) bool {
+		return versions[j].Less(versions[i])
+	})
+	return versions, nil
+}
+
+// fetchPackageIndex downloads the JSON package index of the channel at channelURL.
+func fetchPackageIndex(ctx context.Context, channelURL string) (*packageIndex, error) {
+	data, err := fetchPage(ctx, channelURL+"/?mode=json")
+	if err != nil {
+		return nil, err
+	}
+	var index packageIndex
+	if err := json.Unmarshal(data, &index); err != nil {
+		return nil, fmt.Errorf("failed to parse package index: %w", err)
+	}
+	return &index, nil
+}
+
+// fetchPage downloads a package index page.
+func fetchPage(ctx context.Context, url string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch %s: %w", url, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to fetch %s: %s", url, resp.Status)
+	}
+	return io.ReadAll(io.LimitReader(resp.Body, maxIndexSize))
+}
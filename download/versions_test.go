@@ -0,0 +1,222 @@
+package download_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"tailscale/download"
+)
+
+// indexPage is an excerpt of the HTML package index of a channel, listing the
+// current installers and, through version links, older releases.
+const indexPage = `<html><body>
+<a href="tailscale-setup-1.76.6.exe">tailscale-setup-1.76.6.exe</a>
+<a href="tailscale-setup-1.76.6-amd64.msi">tailscale-setup-1.76.6-amd64.msi</a>
+<a href="tailscale_1.76.6_amd64.tgz">tailscale_1.76.6_amd64.tgz</a>
+<a href="tailscale_1.76.6_arm64.tgz">tailscale_1.76.6_arm64.tgz</a>
+<a href="?v=1.74.1">1.74.1</a>
+<a href="?mode=json&v=1.9.12">1.9.12</a>
+<a href="?v=1.76">1.76</a>
+<a href="tailscale-setup-latest.exe">tailscale-setup-latest.exe</a>
+</body></html>`
+
+// newIndexServer serves the package index of each channel from pages, answering
+// requests with ?mode=json with the JSON index naming latest. The returned
+// function lists the requested paths.
+func newIndexServer(t *testing.T, pages map[string]string, latest string) (*httptest.Server, func() []string) {
+	t.Helper()
+	var mu sync.Mutex
+	var requested []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		requested = append(requested, r.URL.RequestURI())
+		mu.Unlock()
+		page, found := pages[strings.Trim(r.URL.Path, "/")]
+		if !found {
+			http.NotFound(w, r)
+			return
+		}
+		if r.URL.Query().Get("mode") == "json" {
+			fmt.Fprintf(w, `{"TarballsVersion": %q, "Tarballs": {"amd64": "tailscale_%s_amd64.tgz"}}`, latest, latest)
+			return
+		}
+		fmt.Fprint(w, page)
+	}))
+	t.Cleanup(server.Close)
+	return server, func() []string {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]string(nil), requested...)
+	}
+}
+
+func TestParseTarget(t *testing.T) {
+	tests := []struct {
+		channel string
+		version string
+		want    download.Target
+		wantErr string
+	}{
+		{want: download.Target{}},
+		{channel: " Unstable ", want: download.Target{Channel: download.ChannelUnstable}},
+		{channel: "stable", version: "v1.76.6", want: download.Target{Channel: download.ChannelStable, Version: "1.76.6"}},
+		{version: " 1.76.6 ", want: download.Target{Version: "1.76.6"}},
+		{channel: "nightly", wantErr: `unknown channel "nightly"`},
+		{version: "1.76", wantErr: `invalid version "1.76"`},
+		{version: "1.76.6-t2cd4a5e5c", wantErr: `invalid version "1.76.6-t2cd4a5e5c"`},
+		{version: "latest", wantErr: `invalid version "latest"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.channel+"/"+tt.version, func(t *testing.T) {
+			got, err := download.ParseTarget(tt.channel, tt.version)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("ParseTarget() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil || got != tt.want {
+				t.Errorf("ParseTarget() = %+v, %v, want %+v", got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestTargetURLs(t *testing.T) {
+	tests := []struct {
+		name        string
+		target      download.Target
+		wantChannel string
+		wantWindows string
+		wantTarball string
+	}{
+		{
+			name:        "official latest",
+			target:      download.Target{},
+			wantChannel: "https://pkgs.tailscale.com/stable",
+			wantWindows: "https://pkgs.tailscale.com/stable/tailscale-setup-latest.exe",
+		},
+		{
+			name:        "official pinned",
+			target:      download.Target{Channel: download.ChannelUnstable, Version: "1.77.12"},
+			wantChannel: "https://pkgs.tailscale.com/unstable",
+			wantWindows: "https://pkgs.tailscale.com/unstable/tailscale-setup-1.77.12.exe",
+			wantTarball: "https://pkgs.tailscale.com/unstable/tailscale_1.77.12_arm64.tgz",
+		},
+		{
+			name:        "mirror",
+			target:      download.Target{Version: "1.76.6", Mirror: "https://mirror.example.com/tailscale/"},
+			wantChannel: "https://mirror.example.com/tailscale/stable",
+			wantWindows: "https://mirror.example.com/tailscale/stable/tailscale-setup-1.76.6.exe",
+			wantTarball: "https://mirror.example.com/tailscale/stable/tailscale_1.76.6_arm64.tgz",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.target.ChannelURL(); got != tt.wantChannel {
+				t.Errorf("ChannelURL() = %q, want %q", got, tt.wantChannel)
+			}
+			if got := tt.target.WindowsURL(); got != tt.wantWindows {
+				t.Errorf("WindowsURL() = %q, want %q", got, tt.wantWindows)
+			}
+			if tt.wantTarball != "" {
+				if got := tt.target.TarballURL("arm64"); got != tt.wantTarball {
+					t.Errorf("TarballURL() = %q, want %q", got, tt.wantTarball)
+				}
+			}
+		})
+	}
+}
+
+func TestLatestVersion(t *testing.T) {
+	server, requested := newIndexServer(t, map[string]string{"unstable": indexPage}, "1.77.12")
+	target := download.Target{Channel: download.ChannelUnstable, Mirror: server.URL}
+
+	latest, err := download.LatestVersion(context.Background(), target)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if latest.String() != "1.77.12" {
+		t.Errorf("LatestVersion() = %s, want 1.77.12", latest)
+	}
+	if got := requested(); len(got) != 1 || got[0] != "/unstable/?mode=json" {
+		t.Errorf("requested %q, want the JSON index of the unstable channel", got)
+	}
+
+	resolved, err := target.Resolve(context.Background())
+	if err != nil || resolved.Version != "1.77.12" || resolved.Channel != download.ChannelUnstable {
+		t.Errorf("Resolve() = %+v, %v", resolved, err)
+	}
+}
+
+func TestLatestVersionErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		target  download.Target
+		latest  string
+		wantErr string
+	}{
+		{name: "missing channel", target: download.Target{Channel: download.ChannelUnstable}, latest: "1.76.6", wantErr: "404 Not Found"},
+		{name: "invalid version", target: download.Target{}, latest: "next", wantErr: "package index of stable does not name a valid version"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server, _ := newIndexServer(t, map[string]string{"stable": indexPage}, tt.latest)
+			tt.target.Mirror = server.URL
+			if _, err := download.LatestVersion(context.Background(), tt.target); err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("LatestVersion() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestListVersions(t *testing.T) {
+	tests := []struct {
+		name    string
+		page    string
+		want    string
+		wantErr string
+	}{
+		{
+			name: "index",
+			page: indexPage,
+			want: "1.76.6 1.74.1 1.9.12",
+		},
+		{
+			name:    "no versions",
+			page:    `<html><body><a href="tailscale-setup-latest.exe">tailscale-setup-latest.exe</a></body></html>`,
+			wantErr: "no versions found at ",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server, requested := newIndexServer(t, map[string]string{"stable": tt.page}, "1.76.6")
+
+			versions, err := download.ListVersions(context.Background(), download.Target{Mirror: server.URL})
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("ListVersions() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, version := range versions {
+				got = append(got, version.String())
+			}
+			if strings.Join(got, " ") != tt.want {
+				t.Errorf("ListVersions() = %q, want %s", got, tt.want)
+			}
+			if got := requested(); len(got) != 1 || got[0] != "/stable/" {
+				t.Errorf("requested %q, want the HTML index of the stable channel", got)
+			}
+		})
+	}
+}
@@ -2,9 +2,13 @@ module tailscale
 
 go 1.23.4
 
-require github.com/nsf/termbox-go v1.1.1 // directgo mod tidy
+require (
+	github.com/mattn/go-runewidth v0.0.16
+	github.com/nsf/termbox-go v1.1.1
+	github.com/rivo/uniseg v0.4.7
+)
 
 require (
-	github.com/mattn/go-runewidth v0.0.16 // indirect
-	github.com/rivo/uniseg v0.4.7 // indirect
+	golang.org/x/crypto v0.31.0
+	golang.org/x/sys v0.28.0 // indirect
 )
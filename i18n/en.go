@@ -0,0 +1,173 @@
+package i18n
+
+// english is the reference catalog every other catalog is validated against.
+var english = map[Key]string{
+	PressEnter:    "Press Enter to continue...",
+	Cancelable:    "%s (press Esc to cancel)",
+	Error:         "Error",
+	ValueRequired: "value must not be empty",
+	AutoExit:      "Press Enter to continue (auto exit in %ds)...",
+	CommandFailed: "Command execution error: %v",
+	ListQuit:      "QUIT",
+
+	MenuConnect:           "Connect",
+	MenuSwitchAccount:     "Switch Account",
+	MenuSignOut:           "Sign Out",
+	MenuListInformation:   "List Information",
+	MenuRemoteDesktop:     "Open Remote Desktop",
+	MenuUpgradeTailscale:  "Upgrade Tailscale",
+	MenuSelfUpdate:        "Update sky-tailscale",
+	MenuForgetCredentials: "Forget Saved Credentials",
+	MenuQuit:              "Quit",
+	MenuUpgradeBanner:     "%s Choose Upgrade Tailscale to install it.",
+	AccountTitle:          "Account : ",
+	AccountInUse:          "It is not possible to select an account that is currently in use!",
+	AccountsFailed:        "Error listing accounts",
+	UsingBroker:           "Using broker: %s",
+	CredentialsForgotten:  "Saved credentials removed.",
+	ForgetFailed:          "Error forgetting credentials",
+	StatusFetching:        "Fetching status...",
+	StatusFailed:          "Error getting status",
+	StatusKeys:            "Enter/Esc: continue",
+	MstscNotWindows:       "Remote Desktop Connection is only available on Windows.",
+	MstscNotFound:         "mstsc.exe not found, please install Remote Desktop Connection.",
+	MstscFailed:           "Failed to start Remote Desktop Connection: %v",
+
+	UpgradeChecking:       "Checking for Tailscale updates...",
+	UpgradeCheckFailed:    "Error checking for Tailscale updates",
+	UpgradeUpToDate:       "Tailscale is up to date.",
+	UpgradeAvailable:      "Tailscale %s is available (installed: %s).",
+	UpgradePinned:         "Tailscale %s is pinned by the configuration (installed: %s).",
+	UpgradeConfirm:        "Press Enter to install it, or Esc to cancel.",
+	UpgradeRunning:        "Upgrading to Tailscale %s...",
+	UpgradeFailed:         "Upgrade failed",
+	UpgradeInstalled:      "Tailscale %s installed.",
+	UpgradeFallback:       "tailscale update failed, using the installer instead",
+	SelfUpdateChecking:    "Checking for sky-tailscale updates...",
+	SelfUpdateCheckFailed: "Error checking for sky-tailscale updates",
+	SelfUpdateUpToDate:    "sky-tailscale %s is up to date.",
+	SelfUpdateAvailable:   "sky-tailscale %s is available (running: %s).",
+	SelfUpdateConfirm:     "Press Enter to update, or Esc to cancel.",
+	SelfUpdateFailed:      "Update failed",
+	SelfUpdateDone:        "Updated to sky-tailscale %s. Restart sky-tailscale to use it.",
+	VersionSummary:        "Tailscale %s (%s)",
+	VersionDaemonDown:     ", daemon not reachable",
+	VersionDaemon:         ", daemon %s",
+
+	InstallChecking:    "Checking for Tailscale...",
+	InstallComplete:    "Installation is complete. Please run it again.",
+	InstallInspected:   "Environmental inspection complete.",
+	InstallFailed:      "Installation error: %v",
+	InstallUsingCached: "Using cached %s",
+	InstallSucceeded:   "Tailscale installed successfully.",
+	InstallRunning:     "Installing Tailscale...",
+	LogCancelling:      "Cancelling...",
+	LogFailed:          "Failed: %v",
+
+	DownloadStarted:      "Downloading %s...",
+	DownloadCompleted:    "Download completed",
+	DownloadRetrying:     "%v, retrying in %s (%d/%d)",
+	DownloadVerifying:    "Verifying checksum...",
+	DownloadVerifyFailed: "Verification failed, the installer was removed.",
+	DownloadVerified:     "Verification: %s",
+	DownloadChecksumOK:   "SHA-256 verified (%s...)",
+	DownloadSignatureOK:  "SHA-256 verified (%s...), signature verified",
+
+	PlanHeader:              "Installing Tailscale %s on %s using %s:",
+	PlanFromArchive:         "from %s",
+	PlanNote:                "Note: %s",
+	PlanDownload:            "download %s",
+	PlanLatestTarball:       "latest %s tarball listed at %s/?mode=json",
+	PlanLatestVersion:       "Latest version: %s",
+	PlanChecksumVerified:    "SHA-256 verified (%s)",
+	PlanInstallFile:         "Install %s",
+	PlanInstallPackage:      "Install the tailscale package",
+	PlanInstallVersion:      "Install tailscale %s",
+	PlanDowngrade:           "Downgrade to tailscale %s if a newer version is installed",
+	PlanAddKey:              "Add the Tailscale signing key",
+	PlanAddRepository:       "Add the Tailscale repository",
+	PlanUpdateIndex:         "Update the package index",
+	PlanRefreshRepositories: "Refresh the repositories",
+	PlanDownloadTarball:     "Download and verify the static binaries",
+	PlanExtractArchive:      "Verify and extract the static binaries",
+	PlanEnableService:       "Enable and start tailscaled",
+	PlanNoSystemd:           "systemd was not found: start tailscaled with your init system after installing.",
+	PlanNoRepository:        "%s has no official Tailscale repository, so updates must be installed the same way.",
+
+	HealthLine:             "tailscaled: %s",
+	HealthChecking:         "checking...",
+	HealthOneWarning:       " (1 warning)",
+	HealthWarnings:         " (%d warnings)",
+	HealthNotRunning:       "not running",
+	HealthStopped:          "stopped",
+	HealthNeedsLogin:       "needs login",
+	HealthNeedsMachineAuth: "waiting for machine approval",
+	HealthStarting:         "starting",
+	HealthRunning:          "running",
+	HealthUnknown:          "unknown",
+	HintNotRunning:         "The Tailscale service is not running.",
+	HintStopped:            "Tailscale is turned off. Connect turns it on again.",
+	HintNeedsLogin:         "This machine is not logged in. Use Connect to log in.",
+	HintNeedsMachineAuth:   "A tailnet admin must approve this machine before it can connect.",
+	ServiceChecking:        "Checking the Tailscale service...",
+	ServiceStartPrompt:     "Press Enter to start it, or Esc to go back.",
+	ServiceStarting:        "Starting the Tailscale service...",
+	ServiceStartFailed:     "Failed to start the Tailscale service",
+
+	HintInvalidCredentials: "Check your account and password and try again.",
+	HintAccountLocked:      "Your account is locked. Contact the administrator of the key broker.",
+	HintRateLimited:        "Too many login attempts. Wait a moment and try again.",
+	HintDaemonNotRunning:   "The Tailscale service is not running. Start the Tailscale service (tailscaled) and try again.",
+	HintPermissionDenied:   "Permission denied. Run this tool as administrator/root or set yourself as operator with 'tailscale set --operator=$USER'.",
+	HintUnknownAccount:     "That account is not known on this machine. Use Connect to log in to it first.",
+	HintNotLoggedIn:        "This machine is not logged in to Tailscale. Use Connect to log in.",
+
+	AccountPrompt:      "Enter your account: ",
+	PasswordPrompt:     "Enter your password: ",
+	Contacting:         "Contacting %s...",
+	LoginLockedWait:    "Too many failed login attempts. Try again in %s.",
+	LoginLocked:        "Too many failed login attempts. Login is locked for %s.",
+	LoginFailed:        "Login failed",
+	SavedUnreadable:    "Saved credentials could not be read",
+	SavedReset:         "Saved credentials could not be decrypted and were reset. Log in again to save new ones.",
+	SavedUsing:         "Using saved credentials of %s...",
+	SavedUnusable:      "Saved credentials could not be used",
+	LoggingIn:          "Logging in...",
+	LoginError:         "Login error",
+	LoggedIn:           "Logged in successfully!",
+	LoggingOut:         "Logging out...",
+	LogoutError:        "Logout error",
+	SwitchingAccount:   "Switching account...",
+	SwitchAccountError: "Error switching account",
+	MyIP:               "My IP: ",
+	MyIPFailed:         "Error getting IP",
+
+	StatusSummary:          "Tailnet: %s  State: %s  Peers: %d",
+	StatusHealth:           "Health: %s",
+	StatusColumnName:       "Name",
+	StatusColumnIP:         "IP",
+	StatusColumnOS:         "OS",
+	StatusColumnOnline:     "Online",
+	StatusColumnConnection: "Connection",
+	StatusColumnLastSeen:   "Last Seen",
+	StatusYes:              "yes",
+	StatusNo:               "no",
+	StatusNow:              "now",
+	StatusNever:            "never",
+	StatusJustNow:          "just now",
+	StatusMinutesAgo:       "%dm ago",
+	StatusHoursAgo:         "%dh ago",
+	StatusDaysAgo:          "%dd ago",
+	StatusDirect:           "direct %s",
+	StatusRelay:            "relay %s",
+	StatusExitNode:         " (exit)",
+	StatusExitOffered:      " (exit?)",
+
+	ViewFooter:    "-- %d-%d of %d -- %s",
+	ViewHelp:      "Up/Down/PgUp/PgDn/Home/End: scroll  /: search  n/N: next/previous match",
+	ViewNotFound:  "Pattern not found: %s",
+	ViewMatch:     "Match %d of %d for %q",
+	LogPaneFooter: "-- lines %d-%d of %d, Up/Down/PgUp/PgDn to scroll --",
+	TableFooter:   "-- row %d of %d -- %s",
+	TableHelp:     "Up/Down: select  Left/Right/1-9: sort column  r: reverse  /: search  n: next match",
+}
@@ -0,0 +1,142 @@
+// Package i18n translates the messages of the terminal UI. Messages are looked up
+// by Key in the catalog of the selected language, falling back to English for keys
+// a catalog lacks, and formatted with fmt when arguments are given.
+package i18n
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Key identifies a message in the catalogs.
+type Key string
+
+// Supported languages, as BCP 47 tags
+const (
+	English            = "en"    // Default language, complete by definition
+	TraditionalChinese = "zh-TW" // Traditional Chinese as used in Taiwan
+)
+
+// catalogs holds the messages of every supported language.
+var catalogs = map[string]map[Key]string{
+	English:            english,
+	TraditionalChinese: traditionalChinese,
+}
+
+// localeEnv are the environment variables naming the user's locale, in POSIX order of precedence.
+var localeEnv = []string{"LC_ALL", "LC_MESSAGES", "LANG"}
+
+// verbPattern matches the fmt verbs of a message, so translations can be checked to take the same arguments.
+var verbPattern = regexp.MustCompile(`%[-+# 0]*[0-9]*(?:\.[0-9]+)?[a-zA-Z%]`)
+
+// current is the language messages are translated to.
+var current = English
+
+// Languages returns the supported languages, sorted.
+func Languages() []string {
+	languages := make([]string, 0, len(catalogs))
+	for language := range catalogs {
+		languages = append(languages, language)
+	}
+	sort.Strings(languages)
+	return languages
+}
+
+// Match returns the supported language for a tag such as "zh-TW", "zh_TW.UTF-8" or "en_US".
+// Every Chinese locale uses the Traditional Chinese catalog, the only Chinese one.
+func Match(tag string) (string, error) {
+	normalized := strings.ToLower(strings.ReplaceAll(tag, "_", "-"))
+	normalized, _, _ = strings.Cut(normalized, ".")
+	normalized, _, _ = strings.Cut(normalized, "@")
+	base, _, _ := strings.Cut(normalized, "-")
+	switch base {
+	case "en", "c", "posix":
+		return English, nil
+	case "zh":
+		return TraditionalChinese, nil
+	}
+	return "", fmt.Errorf("unsupported language %q (available: %s)", tag, strings.Join(Languages(), ", "))
+}
+
+// Detect returns the language to use: configured when it is set, otherwise the first
+// supported locale of the environment, otherwise English.
+func Detect(configured string) string {
+	if configured != "" {
+		if language, err := Match(configured); err == nil {
+			return language
+		}
+	}
+	for _, name := range localeEnv {
+		if value := os.Getenv(name); value != "" {
+			if language, err := Match(value); err == nil {
+				return language
+			}
+			// The first variable set decides, as for other programs
+			break
+		}
+	}
+	return English
+}
+
+// SetLanguage makes messages translate to language, any tag accepted by Match.
+func SetLanguage(language string) error {
+	matched, err := Match(language)
+	if err != nil {
+		return err
+	}
+	current = matched
+	return nil
+}
+
+// Language returns the language messages are translated to.
+func Language() string {
+	return current
+}
+
+// T returns the message of key in the current language, formatted with args when given.
+func T(key Key, args ...any) string {
+	message, found := catalogs[current][key]
+	if !found {
+		message, found = english[key]
+	}
+	if !found {
+		message = string(key)
+	}
+	if len(args) == 0 {
+		return message
+	}
+	return fmt.Sprintf(message, args...)
+}
+
+// Validate checks that every catalog has exactly the keys of the English catalog,
+// with the same fmt verbs in the same order, so no message falls back to English
+// or is formatted with the wrong arguments.
+func Validate() error {
+	var errs []error
+	for _, language := range Languages() {
+		catalog := catalogs[language]
+		for key, message := range english {
+			translated, found := catalog[key]
+			if !found {
+				errs = append(errs, fmt.Errorf("%s: missing %s", language, key))
+				continue
+			}
+			if strings.Join(verbPattern.FindAllString(message, -1), " ") != strings.Join(verbPattern.FindAllString(translated, -1), " ") {
+				errs = append(errs, fmt.Errorf("%s: %s has other format verbs than in English", language, key))
+			}
+		}
+		for key := range catalog {
+			if _, found := english[key]; !found {
+				errs = append(errs, fmt.Errorf("%s: %s is not an English message", language, key))
+			}
+		}
+	}
+	sort.Slice(errs, func(i, j int) bool {
+		return errs[i].Error() < errs[j].Error()
+	})
+	return errors.Join(errs...)
+}
@@ -0,0 +1,96 @@
+package i18n
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestValidate(t *testing.T) {
+	if err := Validate(); err != nil {
+		t.Errorf("catalogs are inconsistent:\n%v", err)
+	}
+}
+
+// useCatalog replaces the Traditional Chinese catalog with a copy edited by edit until the test ends.
+func useCatalog(t *testing.T, edit func(catalog map[Key]string)) {
+	t.Helper()
+	catalog := make(map[Key]string, len(traditionalChinese))
+	for key, message := range traditionalChinese {
+		catalog[key] = message
+	}
+	edit(catalog)
+	catalogs[TraditionalChinese] = catalog
+	t.Cleanup(func() { catalogs[TraditionalChinese] = traditionalChinese })
+}
+
+func TestValidateReportsMistakes(t *testing.T) {
+	tests := []struct {
+		name string
+		edit func(catalog map[Key]string)
+		want string
+	}{
+		{
+			name: "missing message",
+			edit: func(catalog map[Key]string) { delete(catalog, DownloadStarted) },
+			want: "zh-TW: missing download.started",
+		},
+		{
+			name: "other verbs",
+			edit: func(catalog map[Key]string) { catalog[DownloadRetrying] = "%s，%v 後重試（%d/%d）" },
+			want: "zh-TW: download.retrying has other format verbs than in English",
+		},
+		{
+			name: "unknown message",
+			edit: func(catalog map[Key]string) { catalog["download.unknown"] = "未知" },
+			want: "zh-TW: download.unknown is not an English message",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			useCatalog(t, tt.edit)
+			if err := Validate(); err == nil || err.Error() != tt.want {
+				t.Errorf("Validate() = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestMatch(t *testing.T) {
+	tests := []struct {
+		tag     string
+		want    string
+		wantErr bool
+	}{
+		{tag: "en_US.UTF-8", want: English},
+		{tag: "C", want: English},
+		{tag: "zh_TW.UTF-8", want: TraditionalChinese},
+		{tag: "zh-Hant", want: TraditionalChinese},
+		{tag: "fr_FR", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.tag, func(t *testing.T) {
+			got, err := Match(tt.tag)
+			if (err != nil) != tt.wantErr || got != tt.want {
+				t.Errorf("Match(%q) = %q, %v, want %q, error %v", tt.tag, got, err, tt.want, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestTFallsBackToEnglish(t *testing.T) {
+	useCatalog(t, func(catalog map[Key]string) { delete(catalog, DownloadStarted) })
+	if err := SetLanguage(TraditionalChinese); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { current = English })
+
+	if got := T(DownloadStarted, "tailscale.exe"); got != "Downloading tailscale.exe..." {
+		t.Errorf("missing message = %q, want the English one", got)
+	}
+	if got := T(DownloadCompleted); got != traditionalChinese[DownloadCompleted] {
+		t.Errorf("translated message = %q, want %q", got, traditionalChinese[DownloadCompleted])
+	}
+	if got := T("no.such.key"); !strings.Contains(got, "no.such.key") {
+		t.Errorf("unknown key = %q, want the key itself", got)
+	}
+}
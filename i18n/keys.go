@@ -0,0 +1,203 @@
+package i18n
+
+// Messages shared by several screens
+const (
+	PressEnter    Key = "common.pressEnter"
+	Cancelable    Key = "common.cancelable"
+	Error         Key = "common.error"
+	ValueRequired Key = "common.valueRequired"
+	AutoExit      Key = "common.autoExit"
+	CommandFailed Key = "common.commandFailed"
+	ListQuit      Key = "common.listQuit"
+)
+
+// Main menu and its actions
+const (
+	MenuConnect           Key = "menu.connect"
+	MenuSwitchAccount     Key = "menu.switchAccount"
+	MenuSignOut           Key = "menu.signOut"
+	MenuListInformation   Key = "menu.listInformation"
+	MenuRemoteDesktop     Key = "menu.remoteDesktop"
+	MenuUpgradeTailscale  Key = "menu.upgradeTailscale"
+	MenuSelfUpdate        Key = "menu.selfUpdate"
+	MenuForgetCredentials Key = "menu.forgetCredentials"
+	MenuQuit              Key = "menu.quit"
+	MenuUpgradeBanner     Key = "menu.upgradeBanner"
+	AccountTitle          Key = "menu.accountTitle"
+	AccountInUse          Key = "menu.accountInUse"
+	AccountsFailed        Key = "menu.accountsFailed"
+	UsingBroker           Key = "menu.usingBroker"
+	CredentialsForgotten  Key = "menu.credentialsForgotten"
+	ForgetFailed          Key = "menu.forgetFailed"
+	StatusFetching        Key = "menu.statusFetching"
+	StatusFailed          Key = "menu.statusFailed"
+	StatusKeys            Key = "menu.statusKeys"
+	MstscNotWindows       Key = "menu.mstscNotWindows"
+	MstscNotFound         Key = "menu.mstscNotFound"
+	MstscFailed           Key = "menu.mstscFailed"
+)
+
+// Tailscale and sky-tailscale upgrades
+const (
+	UpgradeChecking       Key = "upgrade.checking"
+	UpgradeCheckFailed    Key = "upgrade.checkFailed"
+	UpgradeUpToDate       Key = "upgrade.upToDate"
+	UpgradeAvailable      Key = "upgrade.available"
+	UpgradePinned         Key = "upgrade.pinned"
+	UpgradeConfirm        Key = "upgrade.confirm"
+	UpgradeRunning        Key = "upgrade.running"
+	UpgradeFailed         Key = "upgrade.failed"
+	UpgradeInstalled      Key = "upgrade.installed"
+	UpgradeFallback       Key = "upgrade.fallback"
+	SelfUpdateChecking    Key = "selfUpdate.checking"
+	SelfUpdateCheckFailed Key = "selfUpdate.checkFailed"
+	SelfUpdateUpToDate    Key = "selfUpdate.upToDate"
+	SelfUpdateAvailable   Key = "selfUpdate.available"
+	SelfUpdateConfirm     Key = "selfUpdate.confirm"
+	SelfUpdateFailed      Key = "selfUpdate.failed"
+	SelfUpdateDone        Key = "selfUpdate.done"
+	VersionSummary        Key = "upgrade.versionSummary"
+	VersionDaemonDown     Key = "upgrade.versionDaemonDown"
+	VersionDaemon         Key = "upgrade.versionDaemon"
+)
+
+// Installation of Tailscale
+const (
+	InstallChecking    Key = "install.checking"
+	InstallComplete    Key = "install.complete"
+	InstallInspected   Key = "install.inspected"
+	InstallFailed      Key = "install.failed"
+	InstallUsingCached Key = "install.usingCached"
+	InstallSucceeded   Key = "install.succeeded"
+	InstallRunning     Key = "install.running"
+	LogCancelling      Key = "install.logCancelling"
+	LogFailed          Key = "install.logFailed"
+)
+
+// Downloads of installers
+const (
+	DownloadStarted      Key = "download.started"
+	DownloadCompleted    Key = "download.completed"
+	DownloadRetrying     Key = "download.retrying"
+	DownloadVerifying    Key = "download.verifying"
+	DownloadVerifyFailed Key = "download.verifyFailed"
+	DownloadVerified     Key = "download.verified"
+	DownloadChecksumOK   Key = "download.checksumOK"
+	DownloadSignatureOK  Key = "download.signatureOK"
+)
+
+// Installation plans on Linux
+const (
+	PlanHeader              Key = "plan.header"
+	PlanFromArchive         Key = "plan.fromArchive"
+	PlanNote                Key = "plan.note"
+	PlanDownload            Key = "plan.download"
+	PlanLatestTarball       Key = "plan.latestTarball"
+	PlanLatestVersion       Key = "plan.latestVersion"
+	PlanChecksumVerified    Key = "plan.checksumVerified"
+	PlanInstallFile         Key = "plan.installFile"
+	PlanInstallPackage      Key = "plan.installPackage"
+	PlanInstallVersion      Key = "plan.installVersion"
+	PlanDowngrade           Key = "plan.downgrade"
+	PlanAddKey              Key = "plan.addKey"
+	PlanAddRepository       Key = "plan.addRepository"
+	PlanUpdateIndex         Key = "plan.updateIndex"
+	PlanRefreshRepositories Key = "plan.refreshRepositories"
+	PlanDownloadTarball     Key = "plan.downloadTarball"
+	PlanExtractArchive      Key = "plan.extractArchive"
+	PlanEnableService       Key = "plan.enableService"
+	PlanNoSystemd           Key = "plan.noSystemd"
+	PlanNoRepository        Key = "plan.noRepository"
+)
+
+// State of tailscaled
+const (
+	HealthLine             Key = "health.line"
+	HealthChecking         Key = "health.checking"
+	HealthOneWarning       Key = "health.oneWarning"
+	HealthWarnings         Key = "health.warnings"
+	HealthNotRunning       Key = "health.notRunning"
+	HealthStopped          Key = "health.stopped"
+	HealthNeedsLogin       Key = "health.needsLogin"
+	HealthNeedsMachineAuth Key = "health.needsMachineAuth"
+	HealthStarting         Key = "health.starting"
+	HealthRunning          Key = "health.running"
+	HealthUnknown          Key = "health.unknown"
+	HintNotRunning         Key = "health.hintNotRunning"
+	HintStopped            Key = "health.hintStopped"
+	HintNeedsLogin         Key = "health.hintNeedsLogin"
+	HintNeedsMachineAuth   Key = "health.hintNeedsMachineAuth"
+	ServiceChecking        Key = "health.serviceChecking"
+	ServiceStartPrompt     Key = "health.serviceStartPrompt"
+	ServiceStarting        Key = "health.serviceStarting"
+	ServiceStartFailed     Key = "health.serviceStartFailed"
+)
+
+// Hints for classified errors
+const (
+	HintInvalidCredentials Key = "hint.invalidCredentials"
+	HintAccountLocked      Key = "hint.accountLocked"
+	HintRateLimited        Key = "hint.rateLimited"
+	HintDaemonNotRunning   Key = "hint.daemonNotRunning"
+	HintPermissionDenied   Key = "hint.permissionDenied"
+	HintUnknownAccount     Key = "hint.unknownAccount"
+	HintNotLoggedIn        Key = "hint.notLoggedIn"
+)
+
+// Login, logout and account switching
+const (
+	AccountPrompt      Key = "login.accountPrompt"
+	PasswordPrompt     Key = "login.passwordPrompt"
+	Contacting         Key = "login.contacting"
+	LoginLockedWait    Key = "login.lockedWait"
+	LoginLocked        Key = "login.locked"
+	LoginFailed        Key = "login.failed"
+	SavedUnreadable    Key = "login.savedUnreadable"
+	SavedReset         Key = "login.savedReset"
+	SavedUsing         Key = "login.savedUsing"
+	SavedUnusable      Key = "login.savedUnusable"
+	LoggingIn          Key = "login.loggingIn"
+	LoginError         Key = "login.error"
+	LoggedIn           Key = "login.loggedIn"
+	LoggingOut         Key = "login.loggingOut"
+	LogoutError        Key = "login.logoutError"
+	SwitchingAccount   Key = "login.switchingAccount"
+	SwitchAccountError Key = "login.switchAccountError"
+	MyIP               Key = "login.myIP"
+	MyIPFailed         Key = "login.myIPFailed"
+)
+
+// Status table
+const (
+	StatusSummary          Key = "status.summary"
+	StatusHealth           Key = "status.health"
+	StatusColumnName       Key = "status.columnName"
+	StatusColumnIP         Key = "status.columnIP"
+	StatusColumnOS         Key = "status.columnOS"
+	StatusColumnOnline     Key = "status.columnOnline"
+	StatusColumnConnection Key = "status.columnConnection"
+	StatusColumnLastSeen   Key = "status.columnLastSeen"
+	StatusYes              Key = "status.yes"
+	StatusNo               Key = "status.no"
+	StatusNow              Key = "status.now"
+	StatusNever            Key = "status.never"
+	StatusJustNow          Key = "status.justNow"
+	StatusMinutesAgo       Key = "status.minutesAgo"
+	StatusHoursAgo         Key = "status.hoursAgo"
+	StatusDaysAgo          Key = "status.daysAgo"
+	StatusDirect           Key = "status.direct"
+	StatusRelay            Key = "status.relay"
+	StatusExitNode         Key = "status.exitNode"
+	StatusExitOffered      Key = "status.exitOffered"
+)
+
+// Scrollable views of the drawer
+const (
+	ViewFooter    Key = "view.footer"
+	ViewHelp      Key = "view.help"
+	ViewNotFound  Key = "view.notFound"
+	ViewMatch     Key = "view.match"
+	LogPaneFooter Key = "view.logPaneFooter"
+	TableFooter   Key = "view.tableFooter"
+	TableHelp     Key = "view.tableHelp"
+)
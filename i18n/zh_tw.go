@@ -0,0 +1,173 @@
+package i18n
+
+// traditionalChinese is the Traditional Chinese (Taiwan) catalog.
+var traditionalChinese = map[Key]string{
+	PressEnter:    "按 Enter 繼續...",
+	Cancelable:    "%s（按 Esc 取消）",
+	Error:         "錯誤",
+	ValueRequired: "此欄位不可為空",
+	AutoExit:      "按 Enter 繼續（%d 秒後自動結束）...",
+	CommandFailed: "指令執行錯誤：%v",
+	ListQuit:      "離開",
+
+	MenuConnect:           "連線",
+	MenuSwitchAccount:     "切換帳號",
+	MenuSignOut:           "登出",
+	MenuListInformation:   "列出資訊",
+	MenuRemoteDesktop:     "開啟遠端桌面",
+	MenuUpgradeTailscale:  "升級 Tailscale",
+	MenuSelfUpdate:        "更新 sky-tailscale",
+	MenuForgetCredentials: "清除已儲存的憑證",
+	MenuQuit:              "離開",
+	MenuUpgradeBanner:     "%s 選擇「升級 Tailscale」即可安裝。",
+	AccountTitle:          "帳號：",
+	AccountInUse:          "無法選擇目前正在使用的帳號！",
+	AccountsFailed:        "取得帳號清單時發生錯誤",
+	UsingBroker:           "使用金鑰代理：%s",
+	CredentialsForgotten:  "已清除儲存的憑證。",
+	ForgetFailed:          "清除憑證時發生錯誤",
+	StatusFetching:        "正在取得狀態...",
+	StatusFailed:          "取得狀態時發生錯誤",
+	StatusKeys:            "Enter/Esc：繼續",
+	MstscNotWindows:       "遠端桌面連線僅適用於 Windows。",
+	MstscNotFound:         "找不到 mstsc.exe，請安裝遠端桌面連線。",
+	MstscFailed:           "無法啟動遠端桌面連線：%v",
+
+	UpgradeChecking:       "正在檢查 Tailscale 更新...",
+	UpgradeCheckFailed:    "檢查 Tailscale 更新時發生錯誤",
+	UpgradeUpToDate:       "Tailscale 已是最新版本。",
+	UpgradeAvailable:      "Tailscale %s 已可使用（已安裝：%s）。",
+	UpgradePinned:         "設定檔指定使用 Tailscale %s（已安裝：%s）。",
+	UpgradeConfirm:        "按 Enter 安裝，或按 Esc 取消。",
+	UpgradeRunning:        "正在升級至 Tailscale %s...",
+	UpgradeFailed:         "升級失敗",
+	UpgradeInstalled:      "已安裝 Tailscale %s。",
+	UpgradeFallback:       "tailscale update 失敗，改用安裝程式",
+	SelfUpdateChecking:    "正在檢查 sky-tailscale 更新...",
+	SelfUpdateCheckFailed: "檢查 sky-tailscale 更新時發生錯誤",
+	SelfUpdateUpToDate:    "sky-tailscale %s 已是最新版本。",
+	SelfUpdateAvailable:   "sky-tailscale %s 已可使用（執行中：%s）。",
+	SelfUpdateConfirm:     "按 Enter 更新，或按 Esc 取消。",
+	SelfUpdateFailed:      "更新失敗",
+	SelfUpdateDone:        "已更新至 sky-tailscale %s，請重新啟動 sky-tailscale 以使用新版本。",
+	VersionSummary:        "Tailscale %s（%s）",
+	VersionDaemonDown:     "，無法連線至服務",
+	VersionDaemon:         "，服務 %s",
+
+	InstallChecking:    "正在檢查 Tailscale...",
+	InstallComplete:    "安裝完成，請重新執行本程式。",
+	InstallInspected:   "環境檢查完成。",
+	InstallFailed:      "安裝錯誤：%v",
+	InstallUsingCached: "使用快取的 %s",
+	InstallSucceeded:   "Tailscale 安裝成功。",
+	InstallRunning:     "正在安裝 Tailscale...",
+	LogCancelling:      "正在取消...",
+	LogFailed:          "失敗：%v",
+
+	DownloadStarted:      "正在下載 %s...",
+	DownloadCompleted:    "下載完成",
+	DownloadRetrying:     "%v，%s 後重試（%d/%d）",
+	DownloadVerifying:    "正在驗證校驗碼...",
+	DownloadVerifyFailed: "驗證失敗，已刪除安裝程式。",
+	DownloadVerified:     "驗證結果：%s",
+	DownloadChecksumOK:   "SHA-256 已驗證（%s...）",
+	DownloadSignatureOK:  "SHA-256 已驗證（%s...），簽章已驗證",
+
+	PlanHeader:              "安裝 Tailscale %s 至 %s，使用 %s：",
+	PlanFromArchive:         "（來自 %s）",
+	PlanNote:                "注意：%s",
+	PlanDownload:            "下載 %s",
+	PlanLatestTarball:       "%s 的最新壓縮檔，列於 %s/?mode=json",
+	PlanLatestVersion:       "最新版本：%s",
+	PlanChecksumVerified:    "SHA-256 已驗證（%s）",
+	PlanInstallFile:         "安裝 %s",
+	PlanInstallPackage:      "安裝 tailscale 套件",
+	PlanInstallVersion:      "安裝 tailscale %s",
+	PlanDowngrade:           "若已安裝較新版本，降級至 tailscale %s",
+	PlanAddKey:              "加入 Tailscale 簽署金鑰",
+	PlanAddRepository:       "加入 Tailscale 套件庫",
+	PlanUpdateIndex:         "更新套件索引",
+	PlanRefreshRepositories: "重新整理套件庫",
+	PlanDownloadTarball:     "下載並驗證靜態執行檔",
+	PlanExtractArchive:      "驗證並解壓縮靜態執行檔",
+	PlanEnableService:       "啟用並啟動 tailscaled",
+	PlanNoSystemd:           "找不到 systemd：安裝後請以系統的 init 啟動 tailscaled。",
+	PlanNoRepository:        "%s 沒有官方的 Tailscale 套件庫，之後的更新也須以相同方式安裝。",
+
+	HealthLine:             "tailscaled：%s",
+	HealthChecking:         "檢查中...",
+	HealthOneWarning:       "（1 個警告）",
+	HealthWarnings:         "（%d 個警告）",
+	HealthNotRunning:       "未執行",
+	HealthStopped:          "已停止",
+	HealthNeedsLogin:       "需要登入",
+	HealthNeedsMachineAuth: "等待裝置核准",
+	HealthStarting:         "啟動中",
+	HealthRunning:          "執行中",
+	HealthUnknown:          "未知",
+	HintNotRunning:         "Tailscale 服務未執行。",
+	HintStopped:            "Tailscale 已關閉，選擇「連線」即可重新開啟。",
+	HintNeedsLogin:         "此電腦尚未登入，請選擇「連線」登入。",
+	HintNeedsMachineAuth:   "此電腦需要 tailnet 管理員核准後才能連線。",
+	ServiceChecking:        "正在檢查 Tailscale 服務...",
+	ServiceStartPrompt:     "按 Enter 啟動服務，或按 Esc 返回。",
+	ServiceStarting:        "正在啟動 Tailscale 服務...",
+	ServiceStartFailed:     "無法啟動 Tailscale 服務",
+
+	HintInvalidCredentials: "請檢查帳號與密碼後再試一次。",
+	HintAccountLocked:      "您的帳號已被鎖定，請聯絡金鑰代理的管理員。",
+	HintRateLimited:        "登入嘗試次數過多，請稍候再試。",
+	HintDaemonNotRunning:   "Tailscale 服務未執行，請啟動 Tailscale 服務（tailscaled）後再試一次。",
+	HintPermissionDenied:   "權限不足。請以系統管理員/root 身分執行本程式，或以 'tailscale set --operator=$USER' 將自己設為操作者。",
+	HintUnknownAccount:     "此電腦上沒有這個帳號，請先選擇「連線」登入該帳號。",
+	HintNotLoggedIn:        "此電腦尚未登入 Tailscale，請選擇「連線」登入。",
+
+	AccountPrompt:      "請輸入帳號：",
+	PasswordPrompt:     "請輸入密碼：",
+	Contacting:         "正在連線至 %s...",
+	LoginLockedWait:    "登入失敗次數過多，請於 %s 後再試。",
+	LoginLocked:        "登入失敗次數過多，登入已鎖定 %s。",
+	LoginFailed:        "登入失敗",
+	SavedUnreadable:    "無法讀取已儲存的憑證",
+	SavedReset:         "已儲存的憑證無法解密，已重設。請重新登入以儲存新的憑證。",
+	SavedUsing:         "正在使用 %s 的已儲存憑證...",
+	SavedUnusable:      "無法使用已儲存的憑證",
+	LoggingIn:          "正在登入...",
+	LoginError:         "登入錯誤",
+	LoggedIn:           "登入成功！",
+	LoggingOut:         "正在登出...",
+	LogoutError:        "登出錯誤",
+	SwitchingAccount:   "正在切換帳號...",
+	SwitchAccountError: "切換帳號時發生錯誤",
+	MyIP:               "我的 IP：",
+	MyIPFailed:         "取得 IP 時發生錯誤",
+
+	StatusSummary:          "Tailnet：%s  狀態：%s  裝置數：%d",
+	StatusHealth:           "健康狀態：%s",
+	StatusColumnName:       "名稱",
+	StatusColumnIP:         "IP",
+	StatusColumnOS:         "作業系統",
+	StatusColumnOnline:     "線上",
+	StatusColumnConnection: "連線方式",
+	StatusColumnLastSeen:   "最後上線",
+	StatusYes:              "是",
+	StatusNo:               "否",
+	StatusNow:              "現在",
+	StatusNever:            "從未",
+	StatusJustNow:          "剛剛",
+	StatusMinutesAgo:       "%d 分鐘前",
+	StatusHoursAgo:         "%d 小時前",
+	StatusDaysAgo:          "%d 天前",
+	StatusDirect:           "直連 %s",
+	StatusRelay:            "中繼 %s",
+	StatusExitNode:         "（出口）",
+	StatusExitOffered:      "（可作出口）",
+
+	ViewFooter:    "-- 第 %d-%d 列，共 %d 列 -- %s",
+	ViewHelp:      "上/下/PgUp/PgDn/Home/End：捲動  /：搜尋  n/N：下一個/上一個符合項目",
+	ViewNotFound:  "找不到：%s",
+	ViewMatch:     "第 %d 個符合項目，共 %d 個（%q）",
+	LogPaneFooter: "-- 第 %d-%d 行，共 %d 行，上/下/PgUp/PgDn 捲動 --",
+	TableFooter:   "-- 第 %d 列，共 %d 列 -- %s",
+	TableHelp:     "上/下：選擇  左/右/1-9：排序欄位  r：反向排序  /：搜尋  n：下一個符合項目",
+}
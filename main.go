@@ -1,38 +1,104 @@
 package main
 
 import (
+	"context"
+	"flag"
 	"fmt"
 	"os"
+	"tailscale/cli"
+	"tailscale/config"
+	"tailscale/i18n"
 	"tailscale/menu"
+	"tailscale/selfupdate"
 	"tailscale/utils"
 	"tailscale/utils/debug"
 	"tailscale/utils/drawer"
 )
 
+// Global command-line flags, accepted before any subcommand
+var (
+	debugFlag   = flag.Bool("d", false, "run the interactive UI with execution tracing to trace.out")
+	configFlag  = flag.String("config", "", "path of the config file (default: user config dir)")
+	profileFlag = flag.String("profile", "", "name of the key broker profile to use")
+)
+
 func main() {
-	if err := drawer.Init(); err != nil {
-		drawer.Print(fmt.Sprintf("Error: %v\n", err), drawer.DefaultOption)
-		return
+	flag.Usage = func() {
+		fmt.Fprintln(flag.CommandLine.Output(), "Usage: sky-tailscale [flags] [command] [--json] [arguments]")
+		flag.PrintDefaults()
+		fmt.Fprintln(flag.CommandLine.Output(), "Run 'sky-tailscale help' to list the commands.")
 	}
-	defer drawer.Close()
+	flag.Parse()
 
-	if len(os.Args) > 1 && os.Args[1] == "-d" {
-		debug.Debug()
-		return
+	if err := config.Init(*configFlag, *profileFlag); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	// Themes belong to the drawer, so the configured one is checked here rather than by config
+	theme, err := drawer.DetectTheme(config.Get().Theme)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: theme: %v\n", err)
+		os.Exit(1)
+	}
+
+	// Remove the binary replaced by a previous self-update
+	selfupdate.Cleanup()
+
+	// Subcommands run headlessly without the terminal UI
+	if flag.NArg() > 0 {
+		os.Exit(cli.Run(flag.Args()))
 	}
 
-	utils.CheckTailscale()
+	// Subcommands keep English output for scripts, only the terminal UI is translated
+	i18n.SetLanguage(i18n.Detect(config.Get().Language))
 
-	accounts, err := utils.GetAccounts()
+	d, err := drawer.Init()
 	if err != nil {
-		drawer.Print(fmt.Sprintf("Error: %v\n", err), drawer.DefaultOption)
+		// Without a terminal UI there is nothing to draw the error on
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	defer d.Close()
+	d.SetTheme(theme)
+
+	if *debugFlag {
+		debug.Debug(d)
 		return
-	} else if len(accounts.AllAccounts) == 0 {
-		menu.Connect()
-	} else if len(accounts.AllAccounts) == 1 {
-		utils.SwitchAccount(accounts.AllAccounts[0])
 	}
 
-	drawer.Clear(drawer.DefaultOption)
-	menu.RunTermboxUI()
+	utils.CheckTailscale(d)
+	checkUpgrade(d)
+
+	// Accounts are read from tailscaled; when it stays down the menu shows
+	// its state and offers to start it before each action that needs it
+	if utils.EnsureDaemon(d) {
+		accounts, err := utils.GetAccounts()
+		if err != nil {
+			utils.PrintError(d, i18n.T(i18n.Error), err)
+			return
+		} else if len(accounts.AllAccounts) == 0 {
+			menu.Connect(d)
+		} else if len(accounts.AllAccounts) == 1 {
+			utils.SwitchAccount(d, accounts.AllAccounts[0])
+		}
+	}
+
+	d.Clear(drawer.DefaultOption)
+	menu.RunTermboxUI(d)
+}
+
+// checkUpgrade looks for a Tailscale upgrade so the menu can announce it.
+// Failures are ignored: the check must never keep the tool from starting.
+func checkUpgrade(d *drawer.Drawer) {
+	var upgrade *utils.Upgrade
+	err := utils.RunCancelable(d, i18n.T(i18n.UpgradeChecking), func(ctx context.Context) error {
+		ctx, cancel := context.WithTimeout(ctx, utils.UpgradeCheckTimeout)
+		defer cancel()
+		var err error
+		upgrade, err = utils.CheckUpgrade(ctx)
+		return err
+	})
+	if err == nil {
+		menu.SetUpgrade(upgrade)
+	}
 }
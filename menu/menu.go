@@ -2,7 +2,12 @@
 package menu
 
 import (
+	"context"
 	"strings"
+	"tailscale/config"
+	"tailscale/download"
+	"tailscale/i18n"
+	"tailscale/selfupdate"
 	"tailscale/utils"
 	"tailscale/utils/drawer"
 
@@ -11,32 +16,83 @@ import (
 
 // Menu item constants representing different menu options
 const (
-	CONNECT          = iota // Connect to Tailscale
-	SWITCHACCOUNT           // Switch between Tailscale accounts
-	SIGNOUT                 // Sign out of current account
-	LIST_INFORMATION        // Display Tailscale information
-	OPEN_MSTSC              // Open Remote Desktop Connection
-	QUIT                    // Exit the application
+	CONNECT            = iota // Connect to Tailscale
+	SWITCHACCOUNT             // Switch between Tailscale accounts
+	SIGNOUT                   // Sign out of current account
+	LIST_INFORMATION          // Display Tailscale information
+	OPEN_MSTSC                // Open Remote Desktop Connection
+	UPGRADE                   // Upgrade the Tailscale client
+	SELF_UPDATE               // Update sky-tailscale itself
+	FORGET_CREDENTIALS        // Remove saved broker credentials
+	QUIT                      // Exit the application
 )
 
-// RunTermboxUI starts the Termbox user interface and handles the main menu loop.
+// menuLabels are the messages of the main menu items, in menu order.
+var menuLabels = []i18n.Key{
+	CONNECT:            i18n.MenuConnect,
+	SWITCHACCOUNT:      i18n.MenuSwitchAccount,
+	SIGNOUT:            i18n.MenuSignOut,
+	LIST_INFORMATION:   i18n.MenuListInformation,
+	OPEN_MSTSC:         i18n.MenuRemoteDesktop,
+	UPGRADE:            i18n.MenuUpgradeTailscale,
+	SELF_UPDATE:        i18n.MenuSelfUpdate,
+	FORGET_CREDENTIALS: i18n.MenuForgetCredentials,
+	QUIT:               i18n.MenuQuit,
+}
+
+// upgrade is the Tailscale upgrade announced below the menu, nil when up to date.
+var upgrade *utils.Upgrade
+
+// Drawing options of the main menu
+var (
+	bannerOption   = drawer.NewDefaultDrawerOptionNoFlush().WithStyle(drawer.StyleWarning)   // Upgrade banner
+	selectedOption = drawer.NewDefaultDrawerOptionNoFlush().WithStyle(drawer.StyleHighlight) // Selected menu item
+)
+
+// health is the latest state of tailscaled shown in the menu header, nil until the first check.
+var health *utils.Health
+
+// daemonActions are the menu items that need a reachable tailscaled.
+var daemonActions = map[int]bool{
+	CONNECT:          true,
+	SWITCHACCOUNT:    true,
+	SIGNOUT:          true,
+	LIST_INFORMATION: true,
+}
+
+// SetUpgrade announces an available Tailscale upgrade below the main menu.
+func SetUpgrade(u *utils.Upgrade) {
+	upgrade = u
+}
+
+// RunTermboxUI starts the Termbox user interface drawn with d and handles the main menu loop.
 // It displays menu options and executes corresponding actions based on user input.
-func RunTermboxUI() {
-	options := []string{"Connect", "Switch Account", "Sign Out", "List Information", "Open Remote Desktop", "Quit"}
-	optionToAction := map[int]func(){
-		CONNECT:          Connect,
-		SWITCHACCOUNT:    SwitchAccount,
-		SIGNOUT:          SignOut,
-		LIST_INFORMATION: ListInformation,
-		OPEN_MSTSC:       utils.OpenMstsc,
+func RunTermboxUI(d *drawer.Drawer) {
+	options := make([]string, len(menuLabels))
+	for i, label := range menuLabels {
+		options[i] = i18n.T(label)
+	}
+	optionToAction := map[int]func(*drawer.Drawer){
+		CONNECT:            Connect,
+		SWITCHACCOUNT:      SwitchAccount,
+		SIGNOUT:            SignOut,
+		LIST_INFORMATION:   ListInformation,
+		OPEN_MSTSC:         utils.OpenMstsc,
+		UPGRADE:            UpgradeTailscale,
+		SELF_UPDATE:        SelfUpdate,
+		FORGET_CREDENTIALS: ForgetCredentials,
 	}
 
 	selectedIndex := 0
 
-	for {
-		RenderMenu(options, selectedIndex)
+	// tailscaled is checked periodically while the menu waits for a key
+	results, stopWatching := watchHealth()
+	defer func() { stopWatching() }()
 
-		event := termbox.PollEvent()
+	for {
+		redraw := func() { renderMainMenu(d, options, selectedIndex) }
+		redraw()
+		event := nextEvent(d, results, redraw)
 		isEnter := handleKeyEvent(event, &selectedIndex, options)
 
 		if !isEnter {
@@ -44,17 +100,76 @@ func RunTermboxUI() {
 		}
 
 		if selectedIndex == QUIT {
-			drawer.Clear(drawer.DefaultOption)
+			d.Clear(drawer.DefaultOption)
 			return
 		}
 
 		action, found := optionToAction[selectedIndex]
 		if found {
+			// Actions run commands of their own, so the checks pause meanwhile
+			stopWatching()
 			// Clear the screen before executing the action menu
-			drawer.Clear(drawer.DefaultOptionNoFlush)
-			action()
+			d.Clear(drawer.DefaultOptionNoFlush)
+			if !daemonActions[selectedIndex] || utils.EnsureDaemon(d) {
+				action(d)
+			}
 			// Clear the screen after executing the action menu
-			drawer.Clear(drawer.DefaultOptionNoFlush)
+			d.Clear(drawer.DefaultOptionNoFlush)
+			results, stopWatching = watchHealth()
+		}
+	}
+}
+
+// watchHealth starts the periodic checks of tailscaled shown in the menu header.
+func watchHealth() (<-chan *utils.Health, func()) {
+	return utils.WatchHealth(context.Background(), utils.HealthInterval)
+}
+
+// renderMainMenu draws the health status line, the menu and the upgrade banner.
+func renderMainMenu(d *drawer.Drawer, options []string, selectedIndex int) {
+	d.Clear(drawer.DefaultOptionNoFlush)
+	if health == nil {
+		d.Print(i18n.T(i18n.HealthLine, i18n.T(i18n.HealthChecking)), drawer.MutedOptionNoFlush)
+	} else {
+		line := health.String()
+		if hint := health.Hint(); hint != "" {
+			line += " - " + hint
+		}
+		d.Print(line, healthOption(health.State))
+	}
+	d.NextLine()
+	renderOptions(d, options, selectedIndex)
+	if upgrade != nil {
+		d.NextLine()
+		d.Print(i18n.T(i18n.MenuUpgradeBanner, upgrade), bannerOption)
+	}
+	d.Flush()
+}
+
+// healthOption returns the drawing option of the status line for state.
+func healthOption(state utils.DaemonState) *drawer.DrawerOption {
+	option := drawer.NewDefaultDrawerOptionNoFlush()
+	switch state {
+	case utils.DaemonRunning:
+		return option.WithStyle(drawer.StyleSuccess)
+	case utils.DaemonNotRunning, utils.DaemonUnknown:
+		return option.WithStyle(drawer.StyleError)
+	}
+	return option.WithStyle(drawer.StyleWarning)
+}
+
+// nextEvent waits for the next terminal event. Health results arriving meanwhile
+// update the status line through redraw. Events are applied here rather than on
+// the polling goroutine, so a resize never races with redraw.
+func nextEvent(d *drawer.Drawer, results <-chan *utils.Health, redraw func()) termbox.Event {
+	events, stopPolling := d.Poll()
+	defer stopPolling()
+	for {
+		select {
+		case event := <-events:
+			return d.Apply(event)
+		case health = <-results:
+			redraw()
 		}
 	}
 }
@@ -63,15 +178,23 @@ func RunTermboxUI() {
 // Parameters:
 //   - options: slice of strings containing menu options
 //   - selectedIndex: index of currently selected menu item
-func RenderMenu(options []string, selectedIndex int) {
-	drawer.Clear(drawer.DefaultOptionNoFlush)
+func RenderMenu(d *drawer.Drawer, options []string, selectedIndex int) {
+	d.Clear(drawer.DefaultOptionNoFlush)
+	renderOptions(d, options, selectedIndex)
+	d.Flush()
+}
+
+// renderOptions prints the menu items from the current line without flushing.
+// The selected item is drawn in reverse video and keeps its ">" marker, so it
+// stands out on terminals without attributes as well.
+func renderOptions(d *drawer.Drawer, options []string, selectedIndex int) {
 	for i, option := range options {
 		if selectedIndex == i {
-			option = ">  " + option // Highlight selected option
+			d.Print(">  "+option, selectedOption)
+			continue
 		}
-		drawer.Print(option, drawer.DefaultOptionNoFlush) // Use no-flush option for performance
+		d.Print(option, drawer.DefaultOptionNoFlush) // Use no-flush option for performance
 	}
-	drawer.Flush()
 }
 
 // handleKeyEvent processes keyboard events for selecting menu items.
@@ -108,9 +231,16 @@ func handleKeyEvent(event termbox.Event, selectedIndex *int, options []string) b
 
 // getAccount retrieves the Tailscale account to switch to.
 // It displays a list of available accounts and handles user selection.
-// Returns selected account name or empty string if selection is cancelled.
-func getAccount() string {
-	tailscaleAccount, _ := utils.GetAccounts()
+// Returns selected account name or empty string if selection is cancelled or the
+// accounts cannot be listed, in which case the error is shown with its hint.
+func getAccount(d *drawer.Drawer) string {
+	tailscaleAccount, err := utils.GetAccounts()
+	if err != nil {
+		utils.PrintError(d, i18n.T(i18n.AccountsFailed), err)
+		d.Print(i18n.T(i18n.PressEnter), drawer.DefaultOption)
+		d.WaitKey()
+		return ""
+	}
 	selectedIndex := 0
 
 	// Mark the current account with an asterisk (*)
@@ -120,72 +250,249 @@ func getAccount() string {
 		}
 	})
 
-	tailscaleAccount.AllAccounts = append(tailscaleAccount.AllAccounts, "QUIT")
+	tailscaleAccount.AllAccounts = append(tailscaleAccount.AllAccounts, i18n.T(i18n.ListQuit))
+	quitIndex := len(tailscaleAccount.AllAccounts) - 1
 
 	for {
-		drawer.Clear(drawer.DefaultOptionNoFlush)
-		drawer.Print("Account : ", drawer.DefaultOption)
-		RenderMenu(tailscaleAccount.AllAccounts, selectedIndex)
+		// The title stays above the accounts, so they are not drawn with RenderMenu
+		d.Clear(drawer.DefaultOptionNoFlush)
+		d.Print(i18n.T(i18n.AccountTitle), drawer.DefaultOptionNoFlush)
+		renderOptions(d, tailscaleAccount.AllAccounts, selectedIndex)
+		d.Flush()
 
-		event := termbox.PollEvent()
+		event := d.PollEvent()
 		isEnter := handleKeyEvent(event, &selectedIndex, tailscaleAccount.AllAccounts)
 		if isEnter {
 			break
 		}
 	}
 
-	if tailscaleAccount.AllAccounts[selectedIndex] == "QUIT" {
+	if selectedIndex == quitIndex {
 		return ""
 	}
 	if strings.HasPrefix(tailscaleAccount.AllAccounts[selectedIndex], "*") {
-		drawer.Print("It is not possible to select an account that is currently in use!", drawer.DefaultOption)
-		drawer.Print("Press Enter to continue...", drawer.DefaultOption)
-		termbox.PollEvent()
+		d.Print(i18n.T(i18n.AccountInUse), drawer.WarningOptionNoFlush)
+		d.Print(i18n.T(i18n.PressEnter), drawer.DefaultOption)
+		d.WaitKey()
 		return ""
 	}
 	return tailscaleAccount.AllAccounts[selectedIndex]
 }
 
+// selectProfile lets the user pick the key broker profile used to log in.
+// It is skipped when only one profile is configured.
+// Returns false if the selection is cancelled.
+func selectProfile(d *drawer.Drawer) bool {
+	cfg := config.Get()
+	if len(cfg.Profiles) < 2 {
+		return true
+	}
+
+	options := make([]string, 0, len(cfg.Profiles)+1)
+	selectedIndex := 0
+	for i, profile := range cfg.Profiles {
+		options = append(options, profile.Title())
+		if profile.Name == cfg.Selected().Name {
+			selectedIndex = i
+		}
+	}
+	options = append(options, i18n.T(i18n.ListQuit))
+
+	for {
+		RenderMenu(d, options, selectedIndex)
+
+		event := d.PollEvent()
+		if event.Type == termbox.EventKey && event.Key == termbox.KeyEsc {
+			return false
+		}
+		if handleKeyEvent(event, &selectedIndex, options) {
+			break
+		}
+	}
+
+	if selectedIndex == len(cfg.Profiles) {
+		return false
+	}
+	cfg.Select(cfg.Profiles[selectedIndex].Name)
+	d.Clear(drawer.DefaultOption)
+	d.Print(i18n.T(i18n.UsingBroker, cfg.Selected().Title()), drawer.DefaultOption)
+	return true
+}
+
 // Connect initiates the connection to Tailscale.
-// It handles the login process, checks status, and opens Remote Desktop connection.
-func Connect() {
-	isLogin := utils.Login()
+// It lets the user pick a broker profile, handles the login process,
+// checks status, and opens Remote Desktop connection.
+func Connect(d *drawer.Drawer) {
+	if !selectProfile(d) {
+		return
+	}
+	isLogin := utils.Login(d)
 	if !isLogin {
 		return
 	}
-	utils.Status()
-	utils.OpenMstsc()
-	drawer.Print("Press Enter to continue...", drawer.DefaultOption)
-	termbox.PollEvent()
+	utils.Status(d)
+	utils.OpenMstsc(d)
+	d.Print(i18n.T(i18n.PressEnter), drawer.DefaultOption)
+	d.WaitKey()
 }
 
 // SwitchAccount changes the current Tailscale account.
 // It allows switching between different Tailscale accounts and updates the connection.
-func SwitchAccount() {
-	account := getAccount()
+func SwitchAccount(d *drawer.Drawer) {
+	account := getAccount(d)
 	if account == "" {
 		return
 	}
-	utils.SwitchAccount(account)
-	utils.Status()
-	utils.OpenMstsc()
-	drawer.Print("Press Enter to continue...", drawer.DefaultOption)
-	termbox.PollEvent()
+	utils.SwitchAccount(d, account)
+	utils.Status(d)
+	utils.OpenMstsc(d)
+	d.Print(i18n.T(i18n.PressEnter), drawer.DefaultOption)
+	d.WaitKey()
 }
 
 // SignOut logs the user out of the Tailscale account.
 // It performs the logout operation and waits for user acknowledgment.
-func SignOut() {
-	utils.Logout()
-	drawer.Print("Press Enter to continue...", drawer.DefaultOption)
-	termbox.PollEvent()
+func SignOut(d *drawer.Drawer) {
+	utils.Logout(d)
+	d.Print(i18n.T(i18n.PressEnter), drawer.DefaultOption)
+	d.WaitKey()
+}
+
+// UpgradeTailscale checks for a Tailscale upgrade and installs it after confirmation,
+// through "tailscale update" or the installers of the download package.
+func UpgradeTailscale(d *drawer.Drawer) {
+	defer func() {
+		d.Print(i18n.T(i18n.PressEnter), drawer.DefaultOption)
+		d.WaitKey()
+	}()
+
+	var found *utils.Upgrade
+	err := utils.RunCancelable(d, i18n.T(i18n.UpgradeChecking), func(ctx context.Context) error {
+		var err error
+		found, err = utils.CheckUpgrade(ctx)
+		return err
+	})
+	if err != nil {
+		utils.PrintError(d, i18n.T(i18n.UpgradeCheckFailed), err)
+		return
+	}
+	upgrade = found
+	if found == nil {
+		d.Print(i18n.T(i18n.UpgradeUpToDate), drawer.SuccessOption)
+		return
+	}
+
+	d.Print(found.String(), drawer.WarningOptionNoFlush)
+	d.Print(i18n.T(i18n.UpgradeConfirm), drawer.DefaultOption)
+	for {
+		event := d.PollEvent()
+		if event.Type != termbox.EventKey {
+			continue
+		}
+		if event.Key == termbox.KeyEsc {
+			return
+		}
+		if event.Key == termbox.KeyEnter {
+			break
+		}
+	}
+
+	if err := utils.UpgradeTailscale(d, found); err != nil {
+		utils.PrintError(d, i18n.T(i18n.UpgradeFailed), err)
+		return
+	}
+	upgrade = nil
+	d.Print(i18n.T(i18n.UpgradeInstalled, found.Available), drawer.SuccessOption)
+}
+
+// SelfUpdate checks the release feed for a newer sky-tailscale and, after
+// confirmation, replaces the running binary with it.
+func SelfUpdate(d *drawer.Drawer) {
+	defer func() {
+		d.Print(i18n.T(i18n.PressEnter), drawer.DefaultOption)
+		d.WaitKey()
+	}()
+
+	var update *selfupdate.Update
+	err := utils.RunCancelable(d, i18n.T(i18n.SelfUpdateChecking), func(ctx context.Context) error {
+		var err error
+		update, err = selfupdate.Check(ctx, config.Get().Update.Feed())
+		return err
+	})
+	if err != nil {
+		utils.PrintError(d, i18n.T(i18n.SelfUpdateCheckFailed), err)
+		return
+	}
+	if update == nil {
+		d.Print(i18n.T(i18n.SelfUpdateUpToDate, selfupdate.Version), drawer.SuccessOption)
+		return
+	}
+
+	d.Print(update.String(), drawer.WarningOptionNoFlush)
+	d.Print(i18n.T(i18n.SelfUpdateConfirm), drawer.DefaultOption)
+	for {
+		event := d.PollEvent()
+		if event.Type != termbox.EventKey {
+			continue
+		}
+		if event.Key == termbox.KeyEsc {
+			return
+		}
+		if event.Key == termbox.KeyEnter {
+			break
+		}
+	}
+
+	if err := selfupdate.Apply(context.Background(), update, download.ToDrawer(d)); err != nil {
+		utils.PrintError(d, i18n.T(i18n.SelfUpdateFailed), err)
+		return
+	}
+	d.Print(i18n.T(i18n.SelfUpdateDone, update.Latest), drawer.SuccessOption)
+}
+
+// ForgetCredentials removes the saved broker credentials of every profile,
+// so the next connect asks for the account and password again.
+func ForgetCredentials(d *drawer.Drawer) {
+	if err := utils.ForgetCredentials(nil); err != nil {
+		utils.PrintError(d, i18n.T(i18n.ForgetFailed), err)
+	} else {
+		d.Print(i18n.T(i18n.CredentialsForgotten), drawer.SuccessOption)
+	}
+	d.Print(i18n.T(i18n.PressEnter), drawer.DefaultOption)
+	d.WaitKey()
 }
 
 // ListInformation displays Tailscale-related information to the user.
-// It shows IP address and status information, then waits for user acknowledgment.
-func ListInformation() {
-	utils.MyIP()
-	utils.Status()
-	drawer.Print("Press Enter to continue...", drawer.DefaultOption)
-	termbox.PollEvent()
+// It shows the IP address, a summary of the tailnet and the peer table, which
+// can be scrolled, searched and re-sorted until Enter or Esc is pressed.
+func ListInformation(d *drawer.Drawer) {
+	var status *utils.TailscaleStatus
+	err := utils.RunCancelable(d, i18n.T(i18n.StatusFetching), func(ctx context.Context) error {
+		var err error
+		status, err = utils.GetStatusContext(ctx)
+		return err
+	})
+	if err != nil {
+		utils.MyIP(d)
+		utils.PrintError(d, i18n.T(i18n.StatusFailed), err)
+		d.Print(i18n.T(i18n.PressEnter), drawer.DefaultOption)
+		d.WaitKey()
+		return
+	}
+
+	d.Clear(drawer.DefaultOptionNoFlush)
+	utils.MyIP(d)
+	for _, line := range utils.StatusSummary(status) {
+		d.Print(line, drawer.DefaultOptionNoFlush)
+	}
+	d.Print(i18n.T(i18n.StatusKeys), drawer.MutedOptionNoFlush)
+
+	// The table takes the rest of the screen, above its footer
+	table := utils.StatusTable(d, status, utils.SortByName, d.GetY())
+	for {
+		event := table.Run()
+		if event.Type == termbox.EventKey && (event.Key == termbox.KeyEnter || event.Key == termbox.KeyEsc) {
+			return
+		}
+	}
 }
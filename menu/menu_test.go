@@ -0,0 +1,243 @@
+package menu
+
+import (
+	"strings"
+	"testing"
+
+	"tailscale/download"
+	"tailscale/i18n"
+	"tailscale/utils"
+	"tailscale/utils/drawer"
+	"tailscale/utils/fakerunner"
+
+	"github.com/nsf/termbox-go"
+)
+
+// newScreen returns a drawer on a blank in-memory screen of width columns and height rows.
+func newScreen(width, height int) (*drawer.Drawer, *drawer.MemoryScreen) {
+	screen := drawer.NewMemoryScreen(width, height)
+	return drawer.New(screen), screen
+}
+
+// menuOptions returns the labels of the main menu.
+func menuOptions() []string {
+	options := make([]string, len(menuLabels))
+	for i, label := range menuLabels {
+		options[i] = i18n.T(label)
+	}
+	return options
+}
+
+// mustVersion parses s or fails the test.
+func mustVersion(t *testing.T, s string) download.Version {
+	t.Helper()
+	version, err := download.ParseVersion(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return version
+}
+
+// useRunner makes the utils package run fake instead of tailscale until the test ends.
+func useRunner(t *testing.T, fake *fakerunner.Fake) {
+	t.Helper()
+	previous := utils.SetRunner(fake)
+	t.Cleanup(func() { utils.SetRunner(previous) })
+}
+
+func TestRenderMainMenu(t *testing.T) {
+	tests := []struct {
+		name     string
+		health   *utils.Health
+		upgrade  func(t *testing.T) *utils.Upgrade
+		selected int
+		snapshot string
+	}{
+		{
+			name:     "checking",
+			selected: CONNECT,
+			snapshot: `tailscaled: checking...
+
+>  Connect
+Switch Account
+Sign Out
+List Information
+Open Remote Desktop
+Upgrade Tailscale
+Update sky-tailscale
+Forget Saved Credentials
+Quit`,
+		},
+		{
+			name:     "running with upgrade",
+			health:   &utils.Health{State: utils.DaemonRunning, Backend: "Running", Warnings: []string{"dns"}},
+			selected: QUIT,
+			upgrade: func(t *testing.T) *utils.Upgrade {
+				return &utils.Upgrade{Installed: mustVersion(t, "1.76.6"), Available: mustVersion(t, "1.78.1")}
+			},
+			snapshot: `tailscaled: running (1 warning)
+
+Connect
+Switch Account
+Sign Out
+List Information
+Open Remote Desktop
+Upgrade Tailscale
+Update sky-tailscale
+Forget Saved Credentials
+>  Quit
+
+Tailscale 1.78.1 is available (installed: 1.76.6). Choose Upgrade Tailscale to install it.`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			health, upgrade = tt.health, nil
+			if tt.upgrade != nil {
+				upgrade = tt.upgrade(t)
+			}
+			t.Cleanup(func() { health, upgrade = nil, nil })
+
+			d, screen := newScreen(100, 16)
+			renderMainMenu(d, menuOptions(), tt.selected)
+			if got := screen.String(); got != tt.snapshot {
+				t.Errorf("screen =\n%s\nwant\n%s", got, tt.snapshot)
+			}
+		})
+	}
+}
+
+func TestHandleKeyEvent(t *testing.T) {
+	options := menuOptions()
+	key := func(key termbox.Key) termbox.Event {
+		return termbox.Event{Type: termbox.EventKey, Key: key}
+	}
+	tests := []struct {
+		name      string
+		start     int
+		event     termbox.Event
+		want      int
+		wantEnter bool
+	}{
+		{"down", CONNECT, key(termbox.KeyArrowDown), SWITCHACCOUNT, false},
+		{"down wraps", QUIT, key(termbox.KeyArrowDown), CONNECT, false},
+		{"up wraps", CONNECT, key(termbox.KeyArrowUp), QUIT, false},
+		{"escape selects quit", SIGNOUT, key(termbox.KeyEsc), QUIT, false},
+		{"enter", SIGNOUT, key(termbox.KeyEnter), SIGNOUT, true},
+		{"resize", SIGNOUT, termbox.Event{Type: termbox.EventResize}, SIGNOUT, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			selected := tt.start
+			enter := handleKeyEvent(tt.event, &selected, options)
+			if selected != tt.want || enter != tt.wantEnter {
+				t.Errorf("handleKeyEvent = %d, %v, want %d, %v", selected, enter, tt.want, tt.wantEnter)
+			}
+		})
+	}
+}
+
+func TestGetAccount(t *testing.T) {
+	list := fakerunner.SwitchList("alice@example.com", "alice@example.com", "bob@example.com")
+	tests := []struct {
+		name     string
+		keys     []termbox.Key
+		want     string
+		snapshot string
+	}{
+		{
+			name: "other account",
+			keys: []termbox.Key{termbox.KeyArrowDown, termbox.KeyEnter},
+			want: "bob@example.com",
+			snapshot: `Account :
+*alice@example.com
+>  bob@example.com
+QUIT`,
+		},
+		{
+			name: "quit",
+			keys: []termbox.Key{termbox.KeyArrowUp, termbox.KeyEnter},
+			want: "",
+			snapshot: `Account :
+*alice@example.com
+bob@example.com
+>  QUIT`,
+		},
+		{
+			name: "account in use",
+			keys: []termbox.Key{termbox.KeyEnter, termbox.KeyEnter},
+			want: "",
+			snapshot: `Account :
+>  *alice@example.com
+bob@example.com
+QUIT
+It is not possible to select an account that is currently in use!
+Press Enter to continue...`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			useRunner(t, fakerunner.New().On(list, "switch", "--list"))
+			d, screen := newScreen(80, 10)
+			screen.PressKey(tt.keys...)
+
+			if got := getAccount(d); got != tt.want {
+				t.Errorf("getAccount() = %q, want %q", got, tt.want)
+			}
+			if got := screen.String(); got != tt.snapshot {
+				t.Errorf("screen =\n%s\nwant\n%s", got, tt.snapshot)
+			}
+		})
+	}
+}
+
+func TestGetAccountError(t *testing.T) {
+	useRunner(t, fakerunner.New().Fail(1, fakerunner.DaemonNotRunning, "switch", "--list"))
+	d, screen := newScreen(200, 10)
+	screen.PressKey(termbox.KeyEnter)
+
+	if got := getAccount(d); got != "" {
+		t.Errorf("getAccount() = %q, want no account", got)
+	}
+	lines := strings.Split(screen.String(), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("screen =\n%s\nwant the error, its hint and the prompt", screen.String())
+	}
+	if !strings.HasPrefix(lines[0], "Error listing accounts: ") {
+		t.Errorf("error line = %q", lines[0])
+	}
+	if want := i18n.T(i18n.HintDaemonNotRunning); lines[1] != want {
+		t.Errorf("hint line = %q, want %q", lines[1], want)
+	}
+	if want := i18n.T(i18n.PressEnter); lines[2] != want {
+		t.Errorf("prompt line = %q, want %q", lines[2], want)
+	}
+}
+
+func TestMainMenuFollowsResize(t *testing.T) {
+	upgrade = &utils.Upgrade{Installed: mustVersion(t, "1.76.6"), Available: mustVersion(t, "1.78.1")}
+	t.Cleanup(func() { upgrade = nil })
+	options := menuOptions()
+	d, screen := newScreen(100, 16)
+	redraw := func() { renderMainMenu(d, options, QUIT) }
+	redraw()
+	banner := i18n.T(i18n.MenuUpgradeBanner, upgrade)
+	if got := screen.Line(12); got != banner {
+		t.Fatalf("banner at 100 columns = %q, want %q", got, banner)
+	}
+
+	screen.Resize(40, 16)
+	if event := nextEvent(d, nil, redraw); event.Type != termbox.EventResize {
+		t.Fatalf("event = %+v, want EventResize", event)
+	}
+	redraw()
+	// The banner is cut at the new edge instead of running off the screen
+	if got, want := screen.Line(12), drawer.Truncate(banner, 40); got != want {
+		t.Errorf("banner at 40 columns = %q, want %q", got, want)
+	}
+	if got := screen.Line(10); got != ">  "+options[QUIT] {
+		t.Errorf("selected item = %q, want %q", got, ">  "+options[QUIT])
+	}
+}
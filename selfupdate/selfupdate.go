@@ -0,0 +1,366 @@
+// Package selfupdate replaces the running sky-tailscale binary with the newest
+// release published on a release feed. The feed is a JSON document in the format
+// of the GitHub "latest release" API; the asset for the running OS and architecture
+// is downloaded next to the executable, checked against its published SHA-256 and
+// then moved over the executable.
+package selfupdate
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"tailscale/download"
+	"tailscale/i18n"
+)
+
+// Suffixes of the files used while replacing the executable
+const (
+	newSuffix = ".new" // Downloaded binary waiting to replace the executable
+	oldSuffix = ".old" // Previous executable on Windows, removed by Cleanup
+)
+
+// maxFeedSize limits the size of the release feed and of checksum files.
+const maxFeedSize = 4 << 20
+
+// Version is the version of this build, set at build time with
+// -ldflags "-X tailscale/selfupdate.Version=v1.2.3". Development builds are
+// older than every release.
+var Version = "dev"
+
+// checksumFiles are the asset names of combined checksum lists, as written by sha256sum.
+var checksumFiles = []string{"checksums.txt", "sha256sums.txt", "SHA256SUMS"}
+
+// archAliases maps GOARCH values to other names used in asset names.
+var archAliases = map[string][]string{
+	"amd64": {"amd64", "x86_64", "x64"},
+	"386":   {"386", "i386", "x86"},
+	"arm64": {"arm64", "aarch64"},
+	"arm":   {"armv7", "armv6", "arm"},
+}
+
+// Asset is a file attached to a release.
+type Asset struct {
+	Name string `json:"name"`                 // File name
+	URL  string `json:"browser_download_url"` // Download URL
+	Size int64  `json:"size"`                 // Size in bytes
+}
+
+// Release is a published version of sky-tailscale.
+type Release struct {
+	TagName string  `json:"tag_name"` // Git tag such as "v1.2.0"
+	Name    string  `json:"name"`     // Title of the release
+	Notes   string  `json:"body"`     // Release notes
+	PageURL string  `json:"html_url"` // Page of the release
+	Assets  []Asset `json:"assets"`   // Attached files
+}
+
+// ParseVersion parses a release tag such as "v1", "v1.2" or "v1.2.3", treating
+// missing components as zero.
+func ParseVersion(tag string) (download.Version, error) {
+	short, _, _ := strings.Cut(strings.TrimPrefix(strings.TrimSpace(tag), "v"), "-")
+	parts := strings.Split(short, ".")
+	if len(parts) > 3 {
+		return download.Version{}, fmt.Errorf("invalid release version %q", tag)
+	}
+	var numbers [3]int
+	for i, part := range parts {
+		n, err := strconv.Atoi(part)
+		if err != nil || n < 0 {
+			return download.Version{}, fmt.Errorf("invalid release version %q", tag)
+		}
+		numbers[i] = n
+	}
+	return download.Version{Major: numbers[0], Minor: numbers[1], Patch: numbers[2]}, nil
+}
+
+// CurrentVersion returns the version of this build, zero for development builds.
+func CurrentVersion() download.Version {
+	version, _ := ParseVersion(Version)
+	return version
+}
+
+// FetchRelease downloads the latest release from the feed at feedURL.
+func FetchRelease(ctx context.Context, feedURL string) (*Release, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, feedURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Accept", "application/vnd.github+json")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch release feed: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to fetch release feed %s: %s", feedURL, resp.Status)
+	}
+
+	var release Release
+	if err := json.NewDecoder(io.LimitReader(resp.Body, maxFeedSize)).Decode(&release); err != nil {
+		return nil, fmt.Errorf("failed to parse release feed: %w", err)
+	}
+	if release.TagName == "" {
+		return nil, fmt.Errorf("release feed %s does not name a release", feedURL)
+	}
+	return &release, nil
+}
+
+// Version returns the version of the release.
+func (r *Release) Version() (download.Version, error) {
+	return ParseVersion(r.TagName)
+}
+
+// Asset returns the binary built for goos and goarch. Asset names must mention the
+// OS, except Windows executables recognized by their .exe extension. Names without
+// an architecture are taken to be amd64 builds.
+func (r *Release) Asset(goos, goarch string) (*Asset, error) {
+	var fallback *Asset
+	for i := range r.Assets {
+		asset := &r.Assets[i]
+		name := strings.ToLower(asset.Name)
+		if isChecksumFile(asset.Name) || strings.HasSuffix(name, ".sig") {
+			continue
+		}
+		if !matchesOS(name, goos) {
+			continue
+		}
+		arch, found := assetArch(name)
+		switch {
+		case found && arch == goarch:
+			return asset, nil
+		case !found && goarch == "amd64" && fallback == nil:
+			fallback = asset
+		}
+	}
+	if fallback != nil {
+		return fallback, nil
+	}
+	return nil, fmt.Errorf("release %s has no binary for %s/%s", r.TagName, goos, goarch)
+}
+
+// matchesOS reports whether the lowercase asset name is a build for goos.
+func matchesOS(name, goos string) bool {
+	if goos == "windows" {
+		return strings.HasSuffix(name, ".exe")
+	}
+	return strings.Contains(name, goos) && !strings.HasSuffix(name, ".exe")
+}
+
+// assetArch returns the GOARCH named by the lowercase asset name, if any.
+// Longer aliases are tried first, so "arm64" is not mistaken for "arm".
+func assetArch(name string) (string, bool) {
+	for _, goarch := range []string{"amd64", "arm64", "386", "arm"} {
+		for _, alias := range archAliases[goarch] {
+			if strings.Contains(name, alias) {
+				return goarch, true
+			}
+		}
+	}
+	return "", false
+}
+
+// isChecksumFile reports whether name is a checksum file rather than a binary.
+func isChecksumFile(name string) bool {
+	for _, file := range checksumFiles {
+		if strings.EqualFold(name, file) {
+			return true
+		}
+	}
+	return strings.HasSuffix(strings.ToLower(name), download.ChecksumSuffix)
+}
+
+// Checksum returns the published hex SHA-256 of asset, read from its own .sha256
+// asset or from a combined checksum list of the release.
+func (r *Release) Checksum(ctx context.Context, asset *Asset) (string, error) {
+	for _, candidate := range r.Assets {
+		if candidate.Name == asset.Name+download.ChecksumSuffix {
+			data, err := fetchText(ctx, candidate.URL)
+			if err != nil {
+				return "", err
+			}
+			fields := strings.Fields(data)
+			if len(fields) == 0 {
+				return "", fmt.Errorf("checksum file %s is empty", candidate.Name)
+			}
+			return validChecksum(fields[0], candidate.Name)
+		}
+	}
+	for _, candidate := range r.Assets {
+		if !isChecksumFile(candidate.Name) || strings.HasSuffix(candidate.Name, download.ChecksumSuffix) {
+			continue
+		}
+		data, err := fetchText(ctx, candidate.URL)
+		if err != nil {
+			return "", err
+		}
+		for _, line := range strings.Split(data, "\n") {
+			fields := strings.Fields(line)
+			if len(fields) == 2 && strings.TrimPrefix(fields[1], "*") == asset.Name {
+				return validChecksum(fields[0], candidate.Name)
+			}
+		}
+		return "", fmt.Errorf("%s does not list %s", candidate.Name, asset.Name)
+	}
+	return "", fmt.Errorf("release %s publishes no checksum for %s", r.TagName, asset.Name)
+}
+
+// validChecksum checks that checksum, read from source, is a hex SHA-256 digest.
+func validChecksum(checksum, source string) (string, error) {
+	checksum = strings.ToLower(checksum)
+	if len(checksum) != 64 || strings.Trim(checksum, "0123456789abcdef") != "" {
+		return "", fmt.Errorf("%s does not contain a SHA-256 digest", source)
+	}
+	return checksum, nil
+}
+
+// fetchText downloads a small text file such as a checksum list.
+func fetchText(ctx context.Context, url string) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to create request: %w", err)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to fetch %s: %w", url, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("failed to fetch %s: %s", url, resp.Status)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxFeedSize))
+	return string(data), err
+}
+
+// Update is a release newer than the running build.
+type Update struct {
+	Current download.Version // Version of the running build, zero for development builds
+	Latest  download.Version // Version of the release
+	Release *Release         // Release to install
+	Asset   *Asset           // Binary for this OS and architecture
+}
+
+// String describes the update.
+func (u *Update) String() string {
+	current := Version
+	if !u.Current.IsZero() {
+		current = u.Current.String()
+	}
+	return i18n.T(i18n.SelfUpdateAvailable, u.Latest, current)
+}
+
+// Check fetches the latest release from feedURL and returns it as an update
+// when it is newer than the running build, or nil when up to date.
+func Check(ctx context.Context, feedURL string) (*Update, error) {
+	release, err := FetchRelease(ctx, feedURL)
+	if err != nil {
+		return nil, err
+	}
+	latest, err := release.Version()
+	if err != nil {
+		return nil, err
+	}
+	current := CurrentVersion()
+	if !current.Less(latest) {
+		return nil, nil
+	}
+	asset, err := release.Asset(runtime.GOOS, runtime.GOARCH)
+	if err != nil {
+		return nil, err
+	}
+	return &Update{Current: current, Latest: latest, Release: release, Asset: asset}, nil
+}
+
+// executable returns the path of the running binary, a variable so it can be redirected.
+var executable = func() (string, error) {
+	exe, err := os.Executable()
+	if err != nil {
+		return "", err
+	}
+	return filepath.EvalSymlinks(exe)
+}
+
+// SetExecutable makes Apply and Cleanup replace the binary at path instead of the running one.
+func SetExecutable(path string) {
+	executable = func() (string, error) {
+		return path, nil
+	}
+}
+
+// Apply downloads the binary of update next to the executable, verifies its
+// published checksum and moves it over the executable. Progress is reported to
+// out. The new version runs the next time sky-tailscale starts.
+func Apply(ctx context.Context, update *Update, out download.Output) error {
+	exe, err := executable()
+	if err != nil {
+		return fmt.Errorf("failed to locate the executable: %w", err)
+	}
+	expected, err := update.Release.Checksum(ctx, update.Asset)
+	if err != nil {
+		return err
+	}
+
+	// The new binary is written in the same directory so the final rename is atomic
+	staged := exe + newSuffix
+	// A failed update is started over, so a partial download is not kept for resuming
+	defer removeStaged(exe)
+	if err := download.DownloadUnverified(ctx, update.Asset.URL, staged, out); err != nil {
+		return err
+	}
+	if err := download.VerifyFile(staged, expected); err != nil {
+		return err
+	}
+	if err := os.Chmod(staged, 0o755); err != nil {
+		return fmt.Errorf("failed to make the new binary executable: %w", err)
+	}
+	return replace(exe, staged)
+}
+
+// replace moves staged over exe. Windows cannot overwrite a running executable
+// but can rename it, so the old binary is moved aside first and put back if the
+// new one cannot be moved into place.
+func replace(exe, staged string) error {
+	if runtime.GOOS != "windows" {
+		if err := os.Rename(staged, exe); err != nil {
+			return fmt.Errorf("failed to replace %s: %w", exe, err)
+		}
+		return nil
+	}
+
+	old := exe + oldSuffix
+	os.Remove(old)
+	if err := os.Rename(exe, old); err != nil {
+		return fmt.Errorf("failed to move %s aside: %w", exe, err)
+	}
+	if err := os.Rename(staged, exe); err != nil {
+		if restoreErr := os.Rename(old, exe); restoreErr != nil {
+			return fmt.Errorf("failed to replace %s: %w (restoring the old binary also failed: %v)", exe, err, restoreErr)
+		}
+		return fmt.Errorf("failed to replace %s: %w", exe, err)
+	}
+	return nil
+}
+
+// removeStaged removes the new binary staged next to exe and its partial download.
+func removeStaged(exe string) {
+	os.Remove(exe + newSuffix)
+	os.Remove(exe + newSuffix + download.PartSuffix)
+}
+
+// Cleanup removes the binary left behind by an update on Windows, and the files
+// of an update that was killed before it could clean up. Errors are ignored
+// because the old binary is still locked while the old version runs.
+func Cleanup() {
+	exe, err := executable()
+	if err != nil {
+		return
+	}
+	os.Remove(exe + oldSuffix)
+	removeStaged(exe)
+}
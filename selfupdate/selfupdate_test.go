@@ -0,0 +1,300 @@
+package selfupdate
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"tailscale/download"
+)
+
+func TestParseVersion(t *testing.T) {
+	tests := []struct {
+		tag     string
+		want    download.Version
+		wantErr bool
+	}{
+		{tag: "v1.2.3", want: download.Version{Major: 1, Minor: 2, Patch: 3}},
+		{tag: "1.2.3", want: download.Version{Major: 1, Minor: 2, Patch: 3}},
+		{tag: " v2.0 ", want: download.Version{Major: 2}},
+		{tag: "v3", want: download.Version{Major: 3}},
+		{tag: "v1.4.0-rc1", want: download.Version{Major: 1, Minor: 4}},
+		{tag: "dev", wantErr: true},
+		{tag: "", wantErr: true},
+		{tag: "v1.2.3.4", wantErr: true},
+		{tag: "v1.-2", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.tag, func(t *testing.T) {
+			got, err := ParseVersion(tt.tag)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseVersion(%q) error = %v, want error %v", tt.tag, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("ParseVersion(%q) = %v, want %v", tt.tag, got, tt.want)
+			}
+		})
+	}
+}
+
+// assets returns a release publishing assets of the given names, at URLs under base.
+func assets(base string, names ...string) *Release {
+	release := &Release{TagName: "v1.2.0"}
+	for _, name := range names {
+		release.Assets = append(release.Assets, Asset{Name: name, URL: base + "/" + name})
+	}
+	return release
+}
+
+func TestReleaseAsset(t *testing.T) {
+	release := assets("https://example.com",
+		"sky-tailscale-linux-amd64",
+		"sky-tailscale-linux-amd64.sha256",
+		"sky-tailscale-linux-arm64",
+		"sky-tailscale-linux-armv7",
+		"sky-tailscale-darwin-aarch64",
+		"sky-tailscale-windows-x86_64.exe",
+		"sky-tailscale-windows-x86_64.exe.sig",
+		"checksums.txt",
+	)
+	tests := []struct {
+		goos, goarch string
+		want         string
+	}{
+		{"linux", "amd64", "sky-tailscale-linux-amd64"},
+		{"linux", "arm64", "sky-tailscale-linux-arm64"},
+		{"linux", "arm", "sky-tailscale-linux-armv7"},
+		{"darwin", "arm64", "sky-tailscale-darwin-aarch64"},
+		{"windows", "amd64", "sky-tailscale-windows-x86_64.exe"},
+		{"linux", "386", ""},
+		{"windows", "arm64", ""},
+		{"freebsd", "amd64", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.goos+"/"+tt.goarch, func(t *testing.T) {
+			asset, err := release.Asset(tt.goos, tt.goarch)
+			if tt.want == "" {
+				if err == nil {
+					t.Errorf("Asset = %s, want an error", asset.Name)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if asset.Name != tt.want {
+				t.Errorf("Asset = %s, want %s", asset.Name, tt.want)
+			}
+		})
+	}
+
+	// Names without an architecture are amd64 builds
+	plain := assets("https://example.com", "sky-tailscale.exe")
+	if asset, err := plain.Asset("windows", "amd64"); err != nil || asset.Name != "sky-tailscale.exe" {
+		t.Errorf("Asset without architecture = %v, %v", asset, err)
+	}
+	if _, err := plain.Asset("windows", "arm64"); err == nil {
+		t.Error("amd64 build without architecture returned for arm64")
+	}
+}
+
+// checksum is the hex SHA-256 of "binary", the content served for binaries.
+var checksum = func() string {
+	digest := sha256.Sum256([]byte("binary"))
+	return hex.EncodeToString(digest[:])
+}()
+
+// newFeedServer serves files by name, answering 404 for others. When requested
+// is not nil, it is called with each request before answering.
+func newFeedServer(t *testing.T, files map[string]string, requested func(w http.ResponseWriter, r *http.Request)) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if requested != nil {
+			requested(w, r)
+		}
+		data, found := files[filepath.Base(r.URL.Path)]
+		if !found {
+			http.NotFound(w, r)
+			return
+		}
+		io.WriteString(w, data)
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestReleaseChecksum(t *testing.T) {
+	tests := []struct {
+		name    string
+		files   map[string]string
+		want    string
+		wantErr bool
+	}{
+		{
+			name:  "own checksum file",
+			files: map[string]string{"app-linux-amd64.sha256": checksum + "  app-linux-amd64\n"},
+			want:  checksum,
+		},
+		{
+			name:  "combined list",
+			files: map[string]string{"checksums.txt": "0000000000000000000000000000000000000000000000000000000000000000  other\n" + checksum + " *app-linux-amd64\n"},
+			want:  checksum,
+		},
+		{
+			name:    "not listed",
+			files:   map[string]string{"checksums.txt": checksum + "  other\n"},
+			wantErr: true,
+		},
+		{
+			name:    "not a digest",
+			files:   map[string]string{"app-linux-amd64.sha256": "not-a-digest\n"},
+			wantErr: true,
+		},
+		{
+			name:    "none published",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := newFeedServer(t, tt.files, nil)
+			names := []string{"app-linux-amd64"}
+			for name := range tt.files {
+				names = append(names, name)
+			}
+			release := assets(server.URL, names...)
+
+			got, err := release.Checksum(context.Background(), &release.Assets[0])
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Checksum error = %v, want error %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("Checksum = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// useExecutable makes Apply replace a temporary file holding "old" until the test ends.
+func useExecutable(t *testing.T) string {
+	t.Helper()
+	exe := filepath.Join(t.TempDir(), "sky-tailscale")
+	if err := os.WriteFile(exe, []byte("old"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	previous := executable
+	SetExecutable(exe)
+	t.Cleanup(func() { executable = previous })
+	return exe
+}
+
+// dirNames returns the names of the files in dir.
+func dirNames(t *testing.T, dir string) []string {
+	t.Helper()
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, entry := range entries {
+		names = append(names, entry.Name())
+	}
+	return names
+}
+
+// hasData reports whether the file at path exists and is not empty.
+func hasData(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && info.Size() > 0
+}
+
+func TestApply(t *testing.T) {
+	tests := []struct {
+		name     string
+		checksum string
+		cancel   bool
+		want     string
+		wantErr  func(error) bool
+	}{
+		{name: "verified", checksum: checksum, want: "binary"},
+		{
+			name:     "checksum mismatch",
+			checksum: "0000000000000000000000000000000000000000000000000000000000000000",
+			want:     "old",
+			wantErr: func(err error) bool {
+				var checksumErr *download.ChecksumError
+				return errors.As(err, &checksumErr)
+			},
+		},
+		{
+			name:     "cancelled",
+			checksum: checksum,
+			cancel:   true,
+			want:     "old",
+			wantErr:  func(err error) bool { return errors.Is(err, context.Canceled) },
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			exe := useExecutable(t)
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			// Cancelling once part of the binary is received stops the download itself
+			server := newFeedServer(t, map[string]string{
+				"app-linux-amd64": "binary",
+				"checksums.txt":   fmt.Sprintf("%s  app-linux-amd64\n", tt.checksum),
+			}, func(w http.ResponseWriter, r *http.Request) {
+				if tt.cancel && path.Base(r.URL.Path) == "app-linux-amd64" {
+					w.Header().Set("Content-Length", "6")
+					io.WriteString(w, "bin")
+					w.(http.Flusher).Flush()
+					// The client has written the partial file once it received the first bytes
+					for !hasData(exe + newSuffix + download.PartSuffix) {
+						time.Sleep(time.Millisecond)
+					}
+					cancel()
+					<-r.Context().Done()
+				}
+			})
+			release := assets(server.URL, "app-linux-amd64", "checksums.txt")
+			update := &Update{Release: release, Asset: &release.Assets[0]}
+
+			err := Apply(ctx, update, download.ToLog(io.Discard))
+			if tt.wantErr == nil && err != nil || tt.wantErr != nil && !tt.wantErr(err) {
+				t.Fatalf("Apply error = %v", err)
+			}
+			if data, _ := os.ReadFile(exe); string(data) != tt.want {
+				t.Errorf("executable holds %q, want %q", data, tt.want)
+			}
+			// Neither the staged binary nor its partial download is left behind
+			if files := dirNames(t, filepath.Dir(exe)); len(files) != 1 || files[0] != filepath.Base(exe) {
+				t.Errorf("directory holds %q, want only the executable", files)
+			}
+		})
+	}
+}
+
+func TestCleanup(t *testing.T) {
+	exe := useExecutable(t)
+	// Files of an update on Windows and of one killed while downloading
+	for _, suffix := range []string{oldSuffix, newSuffix, newSuffix + download.PartSuffix} {
+		if err := os.WriteFile(exe+suffix, []byte("left"), 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	Cleanup()
+	if files := dirNames(t, filepath.Dir(exe)); len(files) != 1 || files[0] != filepath.Base(exe) {
+		t.Errorf("directory holds %q after Cleanup, want only the executable", files)
+	}
+}
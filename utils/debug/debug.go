@@ -1,17 +1,16 @@
 package debug
 
 import (
-	"fmt"
 	"os"
 	"runtime/trace"
+	"tailscale/i18n"
 	"tailscale/menu"
 	"tailscale/utils"
 	"tailscale/utils/drawer"
-
-	"github.com/nsf/termbox-go"
 )
 
-func Debug() {
+// Debug runs the interactive UI drawn with d while tracing its execution to trace.out.
+func Debug(d *drawer.Drawer) {
 	f, err := os.Create("trace.out")
 	if err != nil {
 		panic(err)
@@ -19,24 +18,23 @@ func Debug() {
 
 	defer f.Close()
 	defer trace.Stop()
-	defer termbox.Close()
 
 	if err := trace.Start(f); err != nil {
 		panic(err)
 	}
 
-	utils.CheckTailscale()
+	utils.CheckTailscale(d)
 
 	accounts, err := utils.GetAccounts()
 	if err != nil {
-		drawer.Print(fmt.Sprintf("Error: %v\n", err), drawer.DefaultOption)
+		utils.PrintError(d, i18n.T(i18n.Error), err)
 		return
 	} else if len(accounts.AllAccounts) == 0 {
-		menu.Connect()
+		menu.Connect(d)
 	} else if len(accounts.AllAccounts) == 1 {
-		utils.SwitchAccount(accounts.AllAccounts[0])
+		utils.SwitchAccount(d, accounts.AllAccounts[0])
 	}
 
-	drawer.Clear(drawer.DefaultOption)
-	menu.RunTermboxUI()
+	d.Clear(drawer.DefaultOption)
+	menu.RunTermboxUI(d)
 }
@@ -3,24 +3,56 @@ package drawer
 import (
 	"fmt"
 	"strings"
+	"sync"
 
 	"github.com/nsf/termbox-go"
 )
 
-// Drawer represents the Termbox-based UI drawer.
-// It maintains the current cursor position for drawing operations.
+// Drawer draws text, bars and widgets on a Screen.
+// It maintains the current cursor position and the size of the screen.
 type Drawer struct {
-	x int // x is the current horizontal position (column)
-	y int // y is the current vertical position (row)
+	screen       Screen                          // screen is where everything is drawn
+	theme        *Theme                          // theme gives the colors of styles
+	x            int                             // x is the current horizontal position (column)
+	y            int                             // y is the current vertical position (row)
+	mu           sync.Mutex                      // mu guards the size and the resize listeners
+	width        int                             // width is the number of columns of the screen
+	height       int                             // height is the number of rows of the screen
+	listeners    map[int]func(width, height int) // listeners are called when the size changes, by registration id
+	nextListener int                             // nextListener is the id of the next registration
+}
+
+// New creates a drawer on screen with the cursor at the top left corner.
+func New(screen Screen) *Drawer {
+	width, height := screen.Size()
+	return &Drawer{
+		screen:    screen,
+		theme:     defaultTheme(),
+		width:     width,
+		height:    height,
+		listeners: make(map[int]func(width, height int)),
+	}
+}
+
+// Init initializes the terminal and returns a drawer on it.
+// Returns an error if termbox initialization fails.
+func Init() (*Drawer, error) {
+	screen, err := NewTermboxScreen()
+	if err != nil {
+		return nil, err
+	}
+	return New(screen), nil
 }
 
 // DrawerOption defines drawing options for terminal output operations.
-// It controls line breaks, buffer flushing, and text colors.
+// It controls line breaks, buffer flushing, and text colors. A style other than
+// StyleNormal takes its colors from the theme of the drawer instead of fg and bg.
 type DrawerOption struct {
 	newLine bool              // determines if cursor moves to next line after drawing
 	flush   bool              // determines if buffer should be flushed after drawing
 	fg      termbox.Attribute // specifies the foreground color attribute
 	bg      termbox.Attribute // specifies the background color attribute
+	style   Style             // specifies the style resolved by the theme
 }
 
 // NewDrawerOption creates a new DrawerOption with the given parameters
@@ -77,6 +109,12 @@ func (opt *DrawerOption) WithBg(bg termbox.Attribute) *DrawerOption {
 	return opt
 }
 
+// WithStyle sets the style of the DrawerOption
+func (opt *DrawerOption) WithStyle(style Style) *DrawerOption {
+	opt.style = style
+	return opt
+}
+
 // Default drawing options
 var (
 	// DefaultOption provides standard drawing options with buffer flush
@@ -84,126 +122,318 @@ var (
 
 	// DefaultOptionNoFlush provides standard drawing options without buffer flush
 	DefaultOptionNoFlush = NewDefaultDrawerOptionNoFlush()
+
+	// SuccessOption draws a completed operation with buffer flush
+	SuccessOption = NewDefaultDrawerOption().WithStyle(StyleSuccess)
+
+	// ErrorOptionNoFlush draws a failure without buffer flush
+	ErrorOptionNoFlush = NewDefaultDrawerOptionNoFlush().WithStyle(StyleError)
+
+	// WarningOptionNoFlush draws a problem needing attention without buffer flush
+	WarningOptionNoFlush = NewDefaultDrawerOptionNoFlush().WithStyle(StyleWarning)
+
+	// MutedOptionNoFlush draws secondary text such as key help without buffer flush
+	MutedOptionNoFlush = NewDefaultDrawerOptionNoFlush().WithStyle(StyleMuted)
 )
 
-// Global instance of Drawer
-var instance *Drawer
+// Screen returns the screen the drawer draws on.
+func (d *Drawer) Screen() Screen {
+	return d.screen
+}
 
-// Init initializes the Termbox environment and creates a new drawer instance.
-// Returns an error if termbox initialization fails.
-func Init() error {
-	err := termbox.Init()
-	if err != nil {
-		return err
+// Close releases the screen of the drawer.
+func (d *Drawer) Close() {
+	d.screen.Close()
+}
+
+// Flush forces the screen to display all pending drawing operations.
+// Termbox reads the terminal size while flushing, so a resize is noticed
+// here even when nobody is polling events.
+func (d *Drawer) Flush() {
+	d.screen.Flush()
+	d.updateSize(d.screen.Size())
+}
+
+// Size returns the number of columns and rows of the screen.
+func (d *Drawer) Size() (int, int) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.width, d.height
+}
+
+// OnResize registers fn to be called with the new size whenever the screen is resized.
+// It returns a function removing the registration. fn runs on the goroutine that noticed
+// the resize, in Flush, PollEvent or Apply, so it should only record the change or redraw.
+func (d *Drawer) OnResize(fn func(width, height int)) func() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	id := d.nextListener
+	d.nextListener++
+	d.listeners[id] = fn
+	return func() {
+		d.mu.Lock()
+		defer d.mu.Unlock()
+		delete(d.listeners, id)
 	}
-	instance = &Drawer{x: 0, y: 0}
-	return nil
 }
 
-// Close cleans up the Termbox environment and releases resources.
-// Should be called when the drawer is no longer needed.
-func Close() {
-	if instance != nil {
-		termbox.Close()
-		instance = nil
+// updateSize records the screen size and notifies the resize listeners when it changed.
+func (d *Drawer) updateSize(width, height int) {
+	d.mu.Lock()
+	if d.width == width && d.height == height {
+		d.mu.Unlock()
+		return
+	}
+	d.width, d.height = width, height
+	listeners := make([]func(width, height int), 0, len(d.listeners))
+	for _, fn := range d.listeners {
+		listeners = append(listeners, fn)
+	}
+	d.mu.Unlock()
+
+	// Listeners may draw and flush, so they run without the lock
+	for _, fn := range listeners {
+		fn(width, height)
 	}
 }
 
-// Flush forces the terminal to display all pending drawing operations.
-func Flush() {
-	termbox.Flush()
+// PollEvent waits for the next event of the screen and applies it, see Apply.
+func (d *Drawer) PollEvent() termbox.Event {
+	return d.Apply(d.screen.PollEvent())
 }
 
-// Render draws a string at the specified coordinates (x, y).
-// The string is drawn with default colors and the buffer is flushed immediately.
-func Render(y int, x int, str string) {
-	for i, ch := range str {
-		termbox.SetCell(x+i, y, ch, termbox.ColorDefault, termbox.ColorDefault)
+// Apply brings the drawer up to date with an event read from the screen before it is
+// handled. A resize event updates the size returned by Size, redraws the screen at the
+// new size and notifies the resize listeners, so callers only need to lay out again.
+// It draws, so it must run on the goroutine drawing, not the one polling events.
+func (d *Drawer) Apply(event termbox.Event) termbox.Event {
+	if event.Type == termbox.EventResize {
+		// Flushing resizes the buffers of termbox and repaints what they hold
+		d.screen.Flush()
+		d.updateSize(event.Width, event.Height)
+	}
+	return event
+}
+
+// Poll reads the events of the screen on a goroutine and sends them on the returned
+// channel without applying them, so they can be waited for together with other work
+// and applied with Apply on the goroutine drawing. stop ends the polling and returns
+// once the goroutine is done; events read meanwhile are dropped. stop may be called
+// more than once.
+func (d *Drawer) Poll() (events <-chan termbox.Event, stop func()) {
+	out := make(chan termbox.Event)
+	done := make(chan struct{})
+	finished := make(chan struct{})
+	go func() {
+		defer close(finished)
+		// The loop only ends on the interrupt sent by stop, so a blocking
+		// Interrupt is always received
+		for {
+			event := d.screen.PollEvent()
+			if event.Type == termbox.EventInterrupt {
+				return
+			}
+			select {
+			case out <- event:
+			case <-done:
+			}
+		}
+	}()
+
+	var once sync.Once
+	return out, func() {
+		once.Do(func() {
+			close(done)
+			d.screen.Interrupt()
+			<-finished
+		})
+	}
+}
+
+// WaitKey waits for the next key press, ignoring resizes and other events,
+// e.g. after "Press Enter to continue...".
+func (d *Drawer) WaitKey() termbox.Event {
+	for {
+		event := d.PollEvent()
+		if event.Type == termbox.EventKey {
+			return event
+		}
 	}
-	termbox.Flush()
+}
+
+// Interrupt makes a pending PollEvent return an EventInterrupt.
+func (d *Drawer) Interrupt() {
+	d.screen.Interrupt()
+}
+
+// Render draws a string at the specified coordinates (x, y).
+// The string is drawn in the normal style and the buffer is flushed immediately.
+// Text reaching the edge of the screen is truncated with an ellipsis.
+func (d *Drawer) Render(y int, x int, str string) {
+	width, _ := d.Size()
+	fg, bg := d.styleColors(StyleNormal)
+	d.drawText(x, y, str, fg, bg, width)
+	d.Flush()
 }
 
 // Print displays a message at the current cursor position with specified options.
-// Handles multi-line strings and updates cursor position accordingly.
-func Print(message string, opt *DrawerOption) {
+// Handles multi-line strings and updates cursor position accordingly. Characters
+// take their display width, and lines reaching the edge of the screen are
+// truncated with an ellipsis.
+func (d *Drawer) Print(message string, opt *DrawerOption) {
+	width, _ := d.Size()
+	fg, bg := d.colors(opt)
 	lines := strings.Split(message, "\n")
 	for _, line := range lines {
-		for _, ch := range line {
-			termbox.SetCell(instance.x, instance.y, ch, opt.fg, opt.bg)
-			instance.x++
-		}
-		instance.y++
-		instance.x = 0
+		d.x = d.drawText(d.x, d.y, line, fg, bg, width)
+		d.y++
+		d.x = 0
 	}
 
 	if !opt.newLine {
-		instance.y--
+		d.y--
 	}
 
 	if opt.flush {
-		termbox.Flush()
+		d.Flush()
 	}
 }
 
-// Clear clears the entire terminal screen and resets cursor position.
-// Uses specified background and foreground colors from the option.
-func Clear(opt *DrawerOption) {
-	instance.x = 0
-	instance.y = 0
+// Clear clears the entire screen and resets cursor position.
+// Uses the colors of the option, see DrawerOption.
+func (d *Drawer) Clear(opt *DrawerOption) {
+	d.x = 0
+	d.y = 0
 
-	termbox.Clear(opt.bg, opt.fg)
+	d.screen.Clear(d.colors(opt))
+	d.updateSize(d.screen.Size())
 	if opt.flush {
-		termbox.Flush()
+		d.Flush()
 	}
 }
 
 // DrawProgressBar draws a progress bar at specified Y coordinate with given percentage.
-// The progress bar includes a percentage indicator and uses the full terminal width.
-func DrawProgressBar(y int, percent int, opt *DrawerOption) {
-	width, _ := termbox.Size()
-	totalWidth := width - len("  100/100") - 4
-	fillWidth := int(float64(totalWidth) * float64(percent) / 100)
+// The progress bar includes a percentage indicator and uses the full screen width.
+func (d *Drawer) DrawProgressBar(y int, percent int, opt *DrawerOption) {
+	d.DrawProgressBarWithInfo(y, percent, "", opt)
+}
 
-	// Draw the left boundary of the progress bar
-	termbox.SetCell(0, y, '|', opt.fg, opt.bg)
+// DrawProgressBarWithInfo draws a progress bar followed by the percentage and info,
+// such as the transfer speed. The bar shrinks to leave room for info.
+func (d *Drawer) DrawProgressBarWithInfo(y int, percent int, info string, opt *DrawerOption) {
+	suffix := fmt.Sprintf(" %d/100", percent)
+	if info != "" {
+		suffix += "  " + info
+	}
+	d.drawBar(y, suffix, func(i, totalWidth int) rune {
+		if i < int(float64(totalWidth)*float64(percent)/100) {
+			return '='
+		}
+		return '-'
+	}, opt)
+}
+
+// indeterminateWidth is the width of the block moving across an indeterminate bar.
+const indeterminateWidth = 6
+
+// DrawIndeterminateBar draws a bar with a block bouncing across it, followed by info,
+// for work of unknown size. Callers advance frame on every update to animate it.
+func (d *Drawer) DrawIndeterminateBar(y int, frame int, info string, opt *DrawerOption) {
+	suffix := ""
+	if info != "" {
+		suffix = "  " + info
+	}
+	d.drawBar(y, suffix, func(i, totalWidth int) rune {
+		span := totalWidth - 1 - indeterminateWidth
+		if span <= 0 {
+			return '='
+		}
+		start := frame % (2 * span)
+		if start > span {
+			start = 2*span - start
+		}
+		if i > start && i <= start+indeterminateWidth {
+			return '='
+		}
+		return '-'
+	}, opt)
+}
 
-	// Draw the completed part
-	for i := 1; i < fillWidth; i++ {
-		termbox.SetCell(i, y, '=', opt.fg, opt.bg)
+// drawBar draws the boundaries of a bar on line y, fills it with the runes returned by cell
+// and prints suffix after it, clearing the rest of the line.
+func (d *Drawer) drawBar(y int, suffix string, cell func(i, totalWidth int) rune, opt *DrawerOption) {
+	width, _ := d.Size()
+	totalWidth := width - TextWidth(suffix) - 4
+	if totalWidth < 10 {
+		totalWidth = 10
 	}
+	fg, bg := d.colors(opt)
+
+	// Draw the left boundary of the progress bar
+	d.screen.SetCell(0, y, '|', fg, bg)
 
-	// Draw the remaining part
-	for i := fillWidth; i < totalWidth; i++ {
-		termbox.SetCell(i, y, '-', opt.fg, opt.bg)
+	// Draw the body of the progress bar
+	for i := 1; i < totalWidth; i++ {
+		d.screen.SetCell(i, y, cell(i, totalWidth), fg, bg)
 	}
 
 	// Draw the right boundary of the progress bar
-	termbox.SetCell(totalWidth, y, '|', opt.fg, opt.bg)
+	d.screen.SetCell(totalWidth, y, '|', fg, bg)
 
-	// Draw the progress percentage text
-	progressText := fmt.Sprintf(" %d/100", percent)
-	for i, r := range progressText {
-		termbox.SetCell(totalWidth+1+i, y, r, opt.fg, opt.bg)
+	// Draw the suffix and clear what a longer previous suffix left behind
+	x := d.drawText(totalWidth+1, y, suffix, fg, bg, width)
+	for ; x < width; x++ {
+		d.screen.SetCell(x, y, ' ', fg, bg)
 	}
 
 	// Flush if necessary
 	if opt.flush {
-		termbox.Flush()
+		d.Flush()
+	}
+}
+
+// spinnerFrames are the characters cycled through by DrawSpinner.
+var spinnerFrames = []rune{'|', '/', '-', '\\'}
+
+// DrawSpinner draws one frame of a spinner followed by message at the start of line y.
+// Callers advance frame on every tick to animate the spinner.
+func (d *Drawer) DrawSpinner(y int, frame int, message string, opt *DrawerOption) {
+	fg, bg := d.colors(opt)
+	d.screen.SetCell(0, y, spinnerFrames[frame%len(spinnerFrames)], fg, bg)
+	d.screen.SetCell(1, y, ' ', fg, bg)
+	width, _ := d.Size()
+	d.drawText(2, y, message, fg, bg, width)
+
+	if opt.flush {
+		d.Flush()
+	}
+}
+
+// ClearLine blanks the entire line y using the option's colors.
+func (d *Drawer) ClearLine(y int, opt *DrawerOption) {
+	width, _ := d.Size()
+	fg, bg := d.colors(opt)
+	for x := 0; x < width; x++ {
+		d.screen.SetCell(x, y, ' ', fg, bg)
+	}
+
+	if opt.flush {
+		d.Flush()
 	}
 }
 
 // GetY returns the current vertical cursor position.
-func GetY() int {
-	return instance.y
+func (d *Drawer) GetY() int {
+	return d.y
 }
 
 // GetX returns the current horizontal cursor position.
-func GetX() int {
-	return instance.x
+func (d *Drawer) GetX() int {
+	return d.x
 }
 
 // NextLine moves the cursor to the beginning of the next line.
-func NextLine() {
-	instance.y++
-	instance.x = 0
+func (d *Drawer) NextLine() {
+	d.y++
+	d.x = 0
 }
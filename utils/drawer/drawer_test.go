@@ -0,0 +1,94 @@
+package drawer
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nsf/termbox-go"
+)
+
+// receive returns the next event of events, failing the test if none arrives in time.
+func receive(t *testing.T, events <-chan termbox.Event) termbox.Event {
+	t.Helper()
+	select {
+	case event := <-events:
+		return event
+	case <-time.After(time.Second):
+		t.Fatal("no event received")
+		return termbox.Event{}
+	}
+}
+
+func TestPollForwardsRawEvents(t *testing.T) {
+	screen := NewMemoryScreen(40, 10)
+	d := New(screen)
+	resized := 0
+	d.OnResize(func(width, height int) { resized++ })
+
+	events, stop := d.Poll()
+	defer stop()
+
+	screen.Resize(60, 20)
+	event := receive(t, events)
+	if event.Type != termbox.EventResize {
+		t.Fatalf("event type = %v, want EventResize", event.Type)
+	}
+	// The polling goroutine leaves the resize to the goroutine drawing
+	if width, height := d.Size(); width != 40 || height != 10 || resized != 0 {
+		t.Errorf("before Apply: size = %dx%d, listeners called %d times, want 40x10 and 0", width, height, resized)
+	}
+	d.Apply(event)
+	if width, height := d.Size(); width != 60 || height != 20 || resized != 1 {
+		t.Errorf("after Apply: size = %dx%d, listeners called %d times, want 60x20 and 1", width, height, resized)
+	}
+
+	screen.PressKey(termbox.KeyEsc)
+	if event := receive(t, events); event.Type != termbox.EventKey || event.Key != termbox.KeyEsc {
+		t.Errorf("event = %+v, want Esc", event)
+	}
+}
+
+func TestPollStop(t *testing.T) {
+	screen := NewMemoryScreen(40, 10)
+	d := New(screen)
+	_, stop := d.Poll()
+
+	// An event nobody receives must not keep stop from returning
+	screen.PressKey(termbox.KeyEnter)
+	stopped := make(chan struct{})
+	go func() {
+		stop()
+		stop()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+	case <-time.After(time.Second):
+		t.Fatal("stop did not return")
+	}
+
+	// Events posted after stop are left for the next reader
+	screen.PressKey(termbox.KeyEsc)
+	if event := d.PollEvent(); event.Key != termbox.KeyEsc {
+		t.Errorf("PollEvent after stop = %+v, want Esc", event)
+	}
+}
+
+func TestProgressBarFollowsResize(t *testing.T) {
+	screen := NewMemoryScreen(40, 4)
+	d := New(screen)
+	d.DrawProgressBar(0, 50, DefaultOption)
+	if want := "|" + strings.Repeat("=", 13) + strings.Repeat("-", 15) + "| 50/100"; screen.Line(0) != want {
+		t.Errorf("bar at 40 columns = %q, want %q", screen.Line(0), want)
+	}
+
+	screen.Resize(30, 4)
+	if event := d.PollEvent(); event.Type != termbox.EventResize {
+		t.Fatalf("event = %+v, want EventResize", event)
+	}
+	d.DrawProgressBar(0, 50, DefaultOption)
+	if want := "|" + strings.Repeat("=", 8) + strings.Repeat("-", 10) + "| 50/100"; screen.Line(0) != want {
+		t.Errorf("bar at 30 columns = %q, want %q", screen.Line(0), want)
+	}
+}
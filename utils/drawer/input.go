@@ -0,0 +1,213 @@
+package drawer
+
+import (
+	"strings"
+	"unicode"
+
+	"github.com/mattn/go-runewidth"
+	"github.com/nsf/termbox-go"
+)
+
+// InputResult reports what an event did to an Input.
+type InputResult int
+
+// Results returned by Input.HandleEvent
+const (
+	InputContinue InputResult = iota // The input is still being edited
+	InputSubmit                      // Enter was pressed and the value is valid
+	InputCancel                      // Esc was pressed
+)
+
+// Input is a single-line text field with rune-correct editing, cursor movement and optional masking.
+type Input struct {
+	prompt    string             // text drawn before the value
+	mask      rune               // when non-zero, every character of the value is drawn as mask
+	maxLength int                // maximum number of runes, 0 for unlimited
+	validate  func(string) error // called on Enter, a non-nil error keeps the input open
+	value     []rune             // current value
+	cursor    int                // cursor position as a rune index into value
+	message   string             // validation message shown below the field
+	d         *Drawer            // drawer the input is drawn with
+}
+
+// NewInput creates an Input drawn with d, showing prompt before the value.
+func NewInput(d *Drawer, prompt string) *Input {
+	return &Input{prompt: prompt, d: d}
+}
+
+// WithMask draws every character of the value as mask, e.g. '*' for passwords
+func (in *Input) WithMask(mask rune) *Input {
+	in.mask = mask
+	return in
+}
+
+// WithMaxLength limits the value to maxLength runes, 0 means unlimited
+func (in *Input) WithMaxLength(maxLength int) *Input {
+	in.maxLength = maxLength
+	return in
+}
+
+// WithValidate sets the callback that must accept the value before it is submitted
+func (in *Input) WithValidate(validate func(string) error) *Input {
+	in.validate = validate
+	return in
+}
+
+// WithValue sets the initial value and moves the cursor to its end
+func (in *Input) WithValue(value string) *Input {
+	in.value = nil
+	in.cursor = 0
+	in.Insert(value)
+	return in
+}
+
+// Value returns the current value.
+func (in *Input) Value() string {
+	return string(in.value)
+}
+
+// Insert inserts text at the cursor, as typed or pasted characters.
+// Control characters such as pasted newlines are dropped and the maximum length is enforced.
+func (in *Input) Insert(text string) {
+	for _, ch := range text {
+		if unicode.IsControl(ch) {
+			continue
+		}
+		if in.maxLength > 0 && len(in.value) >= in.maxLength {
+			return
+		}
+		in.value = append(in.value, 0)
+		copy(in.value[in.cursor+1:], in.value[in.cursor:])
+		in.value[in.cursor] = ch
+		in.cursor++
+	}
+}
+
+// HandleEvent applies a terminal event to the input and reports the outcome.
+func (in *Input) HandleEvent(event termbox.Event) InputResult {
+	if event.Type != termbox.EventKey {
+		return InputContinue
+	}
+
+	switch event.Key {
+	case termbox.KeyEsc:
+		return InputCancel
+	case termbox.KeyEnter:
+		if in.validate != nil {
+			if err := in.validate(in.Value()); err != nil {
+				in.message = err.Error()
+				return InputContinue
+			}
+		}
+		in.message = ""
+		return InputSubmit
+	case termbox.KeyArrowLeft, termbox.KeyCtrlB:
+		if in.cursor > 0 {
+			in.cursor--
+		}
+	case termbox.KeyArrowRight, termbox.KeyCtrlF:
+		if in.cursor < len(in.value) {
+			in.cursor++
+		}
+	case termbox.KeyHome, termbox.KeyCtrlA:
+		in.cursor = 0
+	case termbox.KeyEnd, termbox.KeyCtrlE:
+		in.cursor = len(in.value)
+	case termbox.KeyBackspace, termbox.KeyBackspace2:
+		if in.cursor > 0 {
+			in.value = append(in.value[:in.cursor-1], in.value[in.cursor:]...)
+			in.cursor--
+		}
+	case termbox.KeyDelete, termbox.KeyCtrlD:
+		if in.cursor < len(in.value) {
+			in.value = append(in.value[:in.cursor], in.value[in.cursor+1:]...)
+		}
+	case termbox.KeyCtrlU:
+		in.value = append(in.value[:0], in.value[in.cursor:]...)
+		in.cursor = 0
+	case termbox.KeySpace:
+		in.Insert(" ")
+	default:
+		if event.Ch != 0 {
+			in.Insert(string(event.Ch))
+		}
+	}
+	return InputContinue
+}
+
+// display returns the value as drawn on screen, masked if required.
+func (in *Input) display() []rune {
+	if in.mask == 0 {
+		return in.value
+	}
+	return []rune(strings.Repeat(string(in.mask), len(in.value)))
+}
+
+// Draw renders the prompt, value and validation message at line y and places the terminal cursor.
+// When the value does not fit on the line it scrolls horizontally to keep the cursor visible.
+// Characters take their display width, so the cursor stays on wide characters such as CJK.
+func (in *Input) Draw(y int) {
+	width, _ := in.d.Size()
+	in.d.ClearLine(y, DefaultOptionNoFlush)
+	in.d.ClearLine(y+1, DefaultOptionNoFlush)
+
+	fg, bg := in.d.styleColors(StyleNormal)
+	start := in.d.drawText(0, y, in.prompt, fg, bg, width)
+
+	// Scroll just enough for the value up to the cursor to fit
+	display := in.display()
+	room := width - start - 1
+	offset := 0
+	for offset < in.cursor && runesWidth(display[offset:in.cursor]) > room {
+		offset++
+	}
+	x := start
+	for _, ch := range display[offset:] {
+		w := runewidth.RuneWidth(ch)
+		if w == 0 {
+			continue
+		}
+		if x+w > width {
+			break
+		}
+		in.d.screen.SetCell(x, y, ch, fg, bg)
+		x += w
+	}
+	in.d.screen.SetCursor(start+runesWidth(display[offset:in.cursor]), y)
+
+	fg, bg = in.d.styleColors(StyleError)
+	in.d.drawText(0, y+1, in.message, fg, bg, width)
+	in.d.Flush()
+}
+
+// runesWidth returns the number of cells runes take on the screen.
+func runesWidth(runes []rune) int {
+	width := 0
+	for _, ch := range runes {
+		width += runewidth.RuneWidth(ch)
+	}
+	return width
+}
+
+// Read edits the input on the current line until Enter or Esc is pressed.
+// It returns the value and true on Enter, or the value and false on Esc.
+// The input is redrawn for the new width when the terminal is resized.
+// The cursor moves to the next line once reading is done.
+func (in *Input) Read() (string, bool) {
+	y := in.d.GetY()
+	defer func() {
+		in.d.screen.HideCursor()
+		in.d.ClearLine(y+1, DefaultOption)
+		in.d.NextLine()
+	}()
+
+	for {
+		in.Draw(y)
+		switch in.HandleEvent(in.d.PollEvent()) {
+		case InputSubmit:
+			return in.Value(), true
+		case InputCancel:
+			return in.Value(), false
+		}
+	}
+}
@@ -0,0 +1,135 @@
+package drawer
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/nsf/termbox-go"
+)
+
+func TestInputRead(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    func(d *Drawer) *Input
+		typed    string
+		keys     []termbox.Key
+		want     string
+		wantOK   bool
+		snapshot string
+	}{
+		{
+			name:     "plain",
+			input:    func(d *Drawer) *Input { return NewInput(d, "Account: ") },
+			typed:    "alice",
+			keys:     []termbox.Key{termbox.KeyEnter},
+			want:     "alice",
+			wantOK:   true,
+			snapshot: "Account: alice",
+		},
+		{
+			name:     "masked",
+			input:    func(d *Drawer) *Input { return NewInput(d, "Password: ").WithMask('*') },
+			typed:    "secret",
+			keys:     []termbox.Key{termbox.KeyEnter},
+			want:     "secret",
+			wantOK:   true,
+			snapshot: "Password: ******",
+		},
+		{
+			name:     "edited",
+			input:    func(d *Drawer) *Input { return NewInput(d, "> ") },
+			typed:    "helo",
+			keys:     []termbox.Key{termbox.KeyArrowLeft, termbox.KeyBackspace2, termbox.KeyEnter},
+			want:     "heo",
+			wantOK:   true,
+			snapshot: "> heo",
+		},
+		{
+			name:     "wide characters",
+			input:    func(d *Drawer) *Input { return NewInput(d, "帳號：") },
+			typed:    "王小明",
+			keys:     []termbox.Key{termbox.KeyEnter},
+			want:     "王小明",
+			wantOK:   true,
+			snapshot: "帳號：王小明",
+		},
+		{
+			name:     "cancelled",
+			input:    func(d *Drawer) *Input { return NewInput(d, "Account: ") },
+			typed:    "bob",
+			keys:     []termbox.Key{termbox.KeyEsc},
+			want:     "bob",
+			wantOK:   false,
+			snapshot: "Account: bob",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			screen := NewMemoryScreen(40, 4)
+			d := New(screen)
+			screen.Type(tt.typed)
+			screen.PressKey(tt.keys...)
+
+			got, ok := tt.input(d).Read()
+			if got != tt.want || ok != tt.wantOK {
+				t.Errorf("Read() = %q, %v, want %q, %v", got, ok, tt.want, tt.wantOK)
+			}
+			if snapshot := screen.String(); snapshot != tt.snapshot {
+				t.Errorf("screen =\n%s\nwant\n%s", snapshot, tt.snapshot)
+			}
+			if _, _, shown := screen.Cursor(); shown {
+				t.Error("cursor still shown after Read")
+			}
+			if d.GetY() != 1 {
+				t.Errorf("cursor line = %d, want 1", d.GetY())
+			}
+		})
+	}
+}
+
+func TestInputValidation(t *testing.T) {
+	screen := NewMemoryScreen(40, 4)
+	d := New(screen)
+	input := NewInput(d, "Account: ").WithValidate(func(value string) error {
+		if value == "" {
+			return errors.New("value must not be empty")
+		}
+		return nil
+	})
+
+	if result := input.HandleEvent(termbox.Event{Type: termbox.EventKey, Key: termbox.KeyEnter}); result != InputContinue {
+		t.Fatalf("Enter on an empty value = %v, want InputContinue", result)
+	}
+	input.Draw(0)
+	if want := "Account:\nvalue must not be empty"; screen.String() != want {
+		t.Errorf("screen =\n%s\nwant\n%s", screen.String(), want)
+	}
+	if x, y, _ := screen.Cursor(); x != 9 || y != 0 {
+		t.Errorf("cursor = %d,%d, want 9,0", x, y)
+	}
+
+	input.Insert("alice")
+	if result := input.HandleEvent(termbox.Event{Type: termbox.EventKey, Key: termbox.KeyEnter}); result != InputSubmit {
+		t.Fatalf("Enter on a valid value = %v, want InputSubmit", result)
+	}
+	input.Draw(0)
+	if want := "Account: alice"; screen.String() != want {
+		t.Errorf("screen =\n%s\nwant\n%s", screen.String(), want)
+	}
+}
+
+func TestInputScrollsLongValue(t *testing.T) {
+	screen := NewMemoryScreen(12, 2)
+	d := New(screen)
+	input := NewInput(d, "> ").WithValue("abcdefghijklmnop")
+	input.Draw(0)
+
+	// The end of the value stays visible with the cursor after it
+	if got, want := screen.Line(0), "> hijklmnop"; got != want {
+		t.Errorf("line = %q, want %q", got, want)
+	}
+	if x, _, _ := screen.Cursor(); x != 11 {
+		t.Errorf("cursor column = %d, want 11", x)
+	}
+}
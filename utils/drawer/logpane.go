@@ -0,0 +1,199 @@
+package drawer
+
+import (
+	"strings"
+	"sync"
+	"tailscale/i18n"
+
+	"github.com/nsf/termbox-go"
+)
+
+// maxLogLines bounds the number of lines kept by a LogPane.
+const maxLogLines = 5000
+
+// LogPane is a scrollable region of the screen showing lines written to it.
+// It implements io.Writer and is safe for concurrent use, so command output
+// can be written to it from any goroutine while the user scrolls. Writing only
+// records the lines: the screen is not safe for concurrent use, so the goroutine
+// drawing redraws the pane when Changed signals new output.
+type LogPane struct {
+	mu      sync.Mutex
+	changed chan struct{} // Signaled when lines were written since the last draw
+	y       int           // First line of the pane
+	height  int           // Number of lines showing output, the footer is drawn below them
+	reserve int           // Lines left free below the footer when the height follows the terminal, -1 for a fixed height
+	lines   []string      // Complete lines
+	partial string        // Text after the last newline
+	offset  int           // Number of lines scrolled up from the bottom
+	d       *Drawer       // Drawer the pane is drawn with
+}
+
+// NewLogPane creates a pane drawn with d, showing height lines from line y, with a footer
+// on line y+height.
+func NewLogPane(d *Drawer, y, height int) *LogPane {
+	return &LogPane{y: y, height: max(height, 1), reserve: -1, d: d, changed: make(chan struct{}, 1)}
+}
+
+// NewFillLogPane creates a pane drawn with d from line y to the bottom of the terminal, leaving
+// reserve lines free below its footer. Its height follows the terminal when it is resized.
+func NewFillLogPane(d *Drawer, y, reserve int) *LogPane {
+	p := &LogPane{y: y, reserve: reserve, d: d, changed: make(chan struct{}, 1)}
+	p.layout()
+	return p
+}
+
+// Height returns the number of lines showing output, not counting the footer.
+func (p *LogPane) Height() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.height
+}
+
+// layout fits the height of the pane to the terminal when it follows its size.
+// The caller holds the lock.
+func (p *LogPane) layout() {
+	if p.reserve < 0 {
+		return
+	}
+	_, height := p.d.Size()
+	p.height = max(height-p.y-p.reserve-1, 1)
+	p.offset = min(p.offset, max(len(p.all())-p.height, 0))
+}
+
+// Write appends output to the pane and signals Changed, without drawing. A carriage
+// return restarts the current line, so progress meters overwrite themselves.
+func (p *LogPane) Write(data []byte) (int, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	text := p.partial + strings.ReplaceAll(string(data), "\r\n", "\n")
+	lines := strings.Split(text, "\n")
+	for _, line := range lines[:len(lines)-1] {
+		p.append(lastSegment(line))
+	}
+	p.partial = lastSegment(lines[len(lines)-1])
+
+	select {
+	case p.changed <- struct{}{}:
+	default:
+		// A redraw is already pending and will show these lines too
+	}
+	return len(data), nil
+}
+
+// Changed returns a channel receiving a value after output was written, so the
+// goroutine drawing the pane knows to call Draw. Several writes may be signaled once.
+func (p *LogPane) Changed() <-chan struct{} {
+	return p.changed
+}
+
+// lastSegment returns what remains visible of line after carriage returns.
+func lastSegment(line string) string {
+	if i := strings.LastIndexByte(line, '\r'); i >= 0 {
+		return line[i+1:]
+	}
+	return line
+}
+
+// append adds a complete line, keeping the view still when scrolled up.
+func (p *LogPane) append(line string) {
+	p.lines = append(p.lines, strings.ReplaceAll(line, "\t", "    "))
+	if p.offset > 0 {
+		p.offset++
+	}
+	if len(p.lines) > maxLogLines {
+		p.lines = p.lines[len(p.lines)-maxLogLines:]
+	}
+}
+
+// Lines returns the lines written so far, including an unterminated last line.
+func (p *LogPane) Lines() []string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.all()
+}
+
+// all returns the complete lines followed by the partial one, if any.
+func (p *LogPane) all() []string {
+	lines := p.lines
+	if p.partial != "" {
+		lines = append(lines[:len(lines):len(lines)], p.partial)
+	}
+	return lines
+}
+
+// Scroll moves the view by delta lines, towards older output when delta is positive.
+func (p *LogPane) Scroll(delta int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.offset = min(max(p.offset+delta, 0), max(len(p.all())-p.height, 0))
+	p.draw()
+}
+
+// HandleEvent scrolls the pane on Up, Down, PgUp, PgDn, Home and End, and
+// lays it out again when the terminal is resized. It reports whether the event was used.
+func (p *LogPane) HandleEvent(event termbox.Event) bool {
+	if event.Type == termbox.EventResize {
+		p.mu.Lock()
+		defer p.mu.Unlock()
+		p.layout()
+		p.draw()
+		return true
+	}
+	if event.Type != termbox.EventKey {
+		return false
+	}
+	switch event.Key {
+	case termbox.KeyArrowUp:
+		p.Scroll(1)
+	case termbox.KeyArrowDown:
+		p.Scroll(-1)
+	case termbox.KeyPgup:
+		p.Scroll(p.Height())
+	case termbox.KeyPgdn:
+		p.Scroll(-p.Height())
+	case termbox.KeyHome:
+		p.Scroll(maxLogLines)
+	case termbox.KeyEnd:
+		p.Scroll(-maxLogLines)
+	default:
+		return false
+	}
+	return true
+}
+
+// Draw redraws the pane.
+func (p *LogPane) Draw() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.draw()
+}
+
+// draw renders the visible lines and the footer. The caller holds the lock.
+func (p *LogPane) draw() {
+	width, _ := p.d.Size()
+	lines := p.all()
+	end := len(lines) - p.offset
+	start := max(end-p.height, 0)
+
+	for row := 0; row < p.height; row++ {
+		line := ""
+		if start+row < end {
+			line = lines[start+row]
+		}
+		p.d.drawRow(p.y+row, width, line, StyleNormal)
+	}
+
+	footer := i18n.T(i18n.LogPaneFooter, min(start+1, end), end, len(lines))
+	p.d.drawRow(p.y+p.height, width, footer, StyleMuted)
+	p.d.Flush()
+}
+
+// drawRow draws text in style on line y, truncated to width with an ellipsis and padded with spaces.
+func (d *Drawer) drawRow(y, width int, text string, style Style) {
+	fg, bg := d.styleColors(style)
+	x := d.drawText(0, y, text, fg, bg, width)
+	for ; x < width; x++ {
+		d.screen.SetCell(x, y, ' ', fg, bg)
+	}
+}
@@ -0,0 +1,29 @@
+package drawer
+
+import (
+	"fmt"
+	"testing"
+
+	"tailscale/i18n"
+)
+
+func TestLogPaneWriteLeavesDrawingToCaller(t *testing.T) {
+	screen := NewMemoryScreen(30, 4)
+	d := New(screen)
+	pane := NewLogPane(d, 0, 2)
+
+	fmt.Fprint(pane, "first\nprogress 10%\rprogress 90%")
+	if got := screen.String(); got != "" {
+		t.Errorf("Write drew the pane:\n%s", got)
+	}
+	select {
+	case <-pane.Changed():
+	default:
+		t.Fatal("Write did not signal Changed")
+	}
+
+	pane.Draw()
+	if got, want := screen.String(), "first\nprogress 90%\n"+Truncate(i18n.T(i18n.LogPaneFooter, 1, 2, 2), 30); got != want {
+		t.Errorf("screen =\n%s\nwant\n%s", got, want)
+	}
+}
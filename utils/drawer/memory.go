@@ -0,0 +1,198 @@
+package drawer
+
+import (
+	"strings"
+	"sync"
+
+	"github.com/mattn/go-runewidth"
+	"github.com/nsf/termbox-go"
+)
+
+// memoryEventBuffer is the number of events a MemoryScreen queues before Post blocks.
+const memoryEventBuffer = 256
+
+// MemoryScreen is a Screen keeping its cells in memory, with events posted by the
+// caller instead of read from a keyboard. It lets code that draws run without a
+// terminal, e.g. to compare what a menu draws with a snapshot.
+type MemoryScreen struct {
+	mu      sync.Mutex
+	width   int                // Number of columns
+	height  int                // Number of rows
+	back    []termbox.Cell     // Cells drawn since the last flush
+	front   []termbox.Cell     // Cells displayed by the last flush
+	cursorX int                // Column of the cursor, -1 when hidden
+	cursorY int                // Row of the cursor, -1 when hidden
+	events  chan termbox.Event // Events returned by PollEvent
+}
+
+// NewMemoryScreen creates a blank screen of width columns and height rows.
+func NewMemoryScreen(width, height int) *MemoryScreen {
+	s := &MemoryScreen{cursorX: -1, cursorY: -1, events: make(chan termbox.Event, memoryEventBuffer)}
+	s.resize(width, height)
+	return s
+}
+
+// Size returns the number of columns and rows.
+func (s *MemoryScreen) Size() (int, int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.width, s.height
+}
+
+// SetCell sets a cell of the back buffer. Cells outside the screen are ignored.
+func (s *MemoryScreen) SetCell(x, y int, ch rune, fg, bg termbox.Attribute) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if x < 0 || y < 0 || x >= s.width || y >= s.height {
+		return
+	}
+	s.back[y*s.width+x] = termbox.Cell{Ch: ch, Fg: fg, Bg: bg}
+	// A wide character covers the next cell as well, as on a terminal
+	if runewidth.RuneWidth(ch) == 2 && x+1 < s.width {
+		s.back[y*s.width+x+1] = termbox.Cell{Fg: fg, Bg: bg}
+	}
+}
+
+// Clear blanks the back buffer with the given colors.
+func (s *MemoryScreen) Clear(fg, bg termbox.Attribute) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i := range s.back {
+		s.back[i] = termbox.Cell{Ch: ' ', Fg: fg, Bg: bg}
+	}
+}
+
+// Flush copies the back buffer to the displayed cells.
+func (s *MemoryScreen) Flush() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	copy(s.front, s.back)
+}
+
+// SetCursor shows the cursor at x, y.
+func (s *MemoryScreen) SetCursor(x, y int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.cursorX, s.cursorY = x, y
+}
+
+// HideCursor hides the cursor.
+func (s *MemoryScreen) HideCursor() {
+	s.SetCursor(-1, -1)
+}
+
+// PollEvent returns the next posted event, waiting until one is posted.
+func (s *MemoryScreen) PollEvent() termbox.Event {
+	return <-s.events
+}
+
+// Interrupt posts an EventInterrupt.
+func (s *MemoryScreen) Interrupt() {
+	s.Post(termbox.Event{Type: termbox.EventInterrupt})
+}
+
+// Close does nothing, a MemoryScreen holds no resources.
+func (s *MemoryScreen) Close() {}
+
+// Post queues events to be returned by PollEvent.
+func (s *MemoryScreen) Post(events ...termbox.Event) {
+	for _, event := range events {
+		s.events <- event
+	}
+}
+
+// PressKey posts a key event for each key, such as termbox.KeyEnter.
+func (s *MemoryScreen) PressKey(keys ...termbox.Key) {
+	for _, key := range keys {
+		s.Post(termbox.Event{Type: termbox.EventKey, Key: key})
+	}
+}
+
+// Type posts a key event for each rune of text, as if it was typed.
+func (s *MemoryScreen) Type(text string) {
+	for _, ch := range text {
+		if ch == ' ' {
+			s.PressKey(termbox.KeySpace)
+			continue
+		}
+		s.Post(termbox.Event{Type: termbox.EventKey, Ch: ch})
+	}
+}
+
+// Resize changes the size of the screen, keeping the cells that still fit, and
+// posts an EventResize as a terminal does.
+func (s *MemoryScreen) Resize(width, height int) {
+	s.mu.Lock()
+	s.resize(width, height)
+	s.mu.Unlock()
+	s.Post(termbox.Event{Type: termbox.EventResize, Width: width, Height: height})
+}
+
+// resize reallocates the buffers for width columns and height rows. The caller holds the lock.
+func (s *MemoryScreen) resize(width, height int) {
+	width, height = max(width, 0), max(height, 0)
+	resized := func(cells []termbox.Cell) []termbox.Cell {
+		grid := make([]termbox.Cell, width*height)
+		for i := range grid {
+			grid[i] = termbox.Cell{Ch: ' '}
+		}
+		for y := 0; y < min(height, s.height); y++ {
+			copy(grid[y*width:y*width+min(width, s.width)], cells[y*s.width:])
+		}
+		return grid
+	}
+	s.back, s.front = resized(s.back), resized(s.front)
+	s.width, s.height = width, height
+}
+
+// Cell returns the displayed cell at x, y, or a blank cell outside the screen.
+func (s *MemoryScreen) Cell(x, y int) termbox.Cell {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if x < 0 || y < 0 || x >= s.width || y >= s.height {
+		return termbox.Cell{Ch: ' '}
+	}
+	return s.front[y*s.width+x]
+}
+
+// Cursor returns the position of the cursor and whether it is shown.
+func (s *MemoryScreen) Cursor() (int, int, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.cursorX, s.cursorY, s.cursorX >= 0 && s.cursorY >= 0
+}
+
+// Line returns the displayed text of row y without trailing spaces.
+func (s *MemoryScreen) Line(y int) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.line(y)
+}
+
+// line returns the text of row y. The caller holds the lock.
+func (s *MemoryScreen) line(y int) string {
+	if y < 0 || y >= s.height {
+		return ""
+	}
+	var line strings.Builder
+	for _, cell := range s.front[y*s.width : (y+1)*s.width] {
+		if cell.Ch == 0 {
+			// Second half of a wide character
+			continue
+		}
+		line.WriteRune(cell.Ch)
+	}
+	return strings.TrimRight(line.String(), " ")
+}
+
+// String returns the displayed text, one line per row, without trailing spaces
+// or trailing empty rows, as a snapshot of the screen.
+func (s *MemoryScreen) String() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	lines := make([]string, s.height)
+	for y := range lines {
+		lines[y] = s.line(y)
+	}
+	return strings.TrimRight(strings.Join(lines, "\n"), "\n")
+}
@@ -0,0 +1,83 @@
+package drawer
+
+import "github.com/nsf/termbox-go"
+
+// Screen is a grid of cells a Drawer draws on, with the events of its keyboard.
+// Colors and events use the termbox types, whatever the backend.
+type Screen interface {
+	// Size returns the number of columns and rows.
+	Size() (width, height int)
+	// SetCell sets the cell at column x of row y. Cells outside the screen are ignored.
+	SetCell(x, y int, ch rune, fg, bg termbox.Attribute)
+	// Clear blanks every cell with the given colors.
+	Clear(fg, bg termbox.Attribute)
+	// Flush displays what was drawn since the last flush.
+	Flush()
+	// SetCursor shows the cursor at column x of row y.
+	SetCursor(x, y int)
+	// HideCursor hides the cursor.
+	HideCursor()
+	// PollEvent waits for the next event.
+	PollEvent() termbox.Event
+	// Interrupt makes a pending PollEvent return an EventInterrupt.
+	Interrupt()
+	// Close releases the screen.
+	Close()
+}
+
+// termboxScreen is the Screen of the terminal, drawn with termbox.
+type termboxScreen struct{}
+
+// NewTermboxScreen initializes termbox and returns the terminal as a Screen.
+// Only one can be open at a time; Close restores the terminal.
+func NewTermboxScreen() (Screen, error) {
+	if err := termbox.Init(); err != nil {
+		return nil, err
+	}
+	return termboxScreen{}, nil
+}
+
+// Size returns the size of the terminal as of the last flush or clear.
+func (termboxScreen) Size() (int, int) {
+	return termbox.Size()
+}
+
+// SetCell sets a cell of the termbox back buffer.
+func (termboxScreen) SetCell(x, y int, ch rune, fg, bg termbox.Attribute) {
+	termbox.SetCell(x, y, ch, fg, bg)
+}
+
+// Clear blanks the termbox back buffer.
+func (termboxScreen) Clear(fg, bg termbox.Attribute) {
+	termbox.Clear(fg, bg)
+}
+
+// Flush copies the back buffer to the terminal.
+func (termboxScreen) Flush() {
+	termbox.Flush()
+}
+
+// SetCursor shows the terminal cursor at x, y.
+func (termboxScreen) SetCursor(x, y int) {
+	termbox.SetCursor(x, y)
+}
+
+// HideCursor hides the terminal cursor.
+func (termboxScreen) HideCursor() {
+	termbox.HideCursor()
+}
+
+// PollEvent waits for the next terminal event.
+func (termboxScreen) PollEvent() termbox.Event {
+	return termbox.PollEvent()
+}
+
+// Interrupt interrupts a pending PollEvent.
+func (termboxScreen) Interrupt() {
+	termbox.Interrupt()
+}
+
+// Close restores the terminal.
+func (termboxScreen) Close() {
+	termbox.Close()
+}
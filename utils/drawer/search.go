@@ -0,0 +1,47 @@
+package drawer
+
+import "github.com/nsf/termbox-go"
+
+// searchable is a scrollable view with a search, such as a Viewport or a Table.
+type searchable interface {
+	layout()                              // Fits the view to the current terminal size
+	searchRow() int                       // Line where the search query is read
+	Draw()                                // Renders the view
+	Search(query string) bool             // Looks for query and shows its first match
+	HandleEvent(event termbox.Event) bool // Handles an event, reporting whether it was used
+}
+
+// readSearch reads a search query for view, starting from query, and searches for
+// it unless Esc is pressed. When the terminal is resized, the view is laid out and
+// drawn again under the input.
+func readSearch(d *Drawer, view searchable, query string) {
+	input := NewInput(d, "/").WithValue(query)
+	for {
+		input.Draw(view.searchRow())
+		event := d.PollEvent()
+		if event.Type == termbox.EventResize {
+			view.layout()
+			view.Draw()
+		}
+		switch input.HandleEvent(event) {
+		case InputSubmit:
+			d.screen.HideCursor()
+			view.Search(input.Value())
+			return
+		case InputCancel:
+			d.screen.HideCursor()
+			return
+		}
+	}
+}
+
+// run draws view and handles events until one is not used by it, which is returned.
+func run(d *Drawer, view searchable) termbox.Event {
+	for {
+		view.Draw()
+		event := d.PollEvent()
+		if !view.HandleEvent(event) {
+			return event
+		}
+	}
+}
@@ -0,0 +1,409 @@
+package drawer
+
+import (
+	"sort"
+	"strings"
+	"tailscale/i18n"
+
+	"github.com/nsf/termbox-go"
+)
+
+// Layout of tables
+const (
+	columnGap      = 2 // Cells between two columns
+	minColumnWidth = 3 // Narrowest width an automatic column shrinks to when the table does not fit
+)
+
+// Markers appended to the title of the sort column
+const (
+	ascendingMarker  = " v"
+	descendingMarker = " ^"
+)
+
+// Align is the horizontal alignment of the cells of a column.
+type Align int
+
+// Alignments of cells
+const (
+	AlignLeft Align = iota
+	AlignRight
+	AlignCenter
+)
+
+// Column describes a column of a Table.
+type Column struct {
+	Title    string              // Header of the column
+	Width    int                 // Fixed width in cells, 0 to fit the widest cell
+	MaxWidth int                 // Upper bound of a fitted width, 0 for none
+	Align    Align               // Alignment of the header and the cells
+	Less     func(a, b int) bool // Orders rows by index when sorting by the column, by cell text when nil
+}
+
+// Table is a region of the screen showing rows of cells under a header, with one
+// selected row. Rows can be sorted by any column from the keyboard, and cells that
+// do not fit their column are truncated with an ellipsis. The first rows can be
+// fixed, so they stay on top whatever the sort order, e.g. the local node above
+// its peers.
+type Table struct {
+	columns    []Column   // Columns in display order
+	rows       [][]string // Cells of each row, in the order they were set
+	fixed      int        // Number of first rows kept on top when sorting
+	order      []int      // Indexes of the rows in display order
+	sortColumn int        // Column the rows are sorted by, -1 when unsorted
+	descending bool       // Whether the sort order is reversed
+	selected   int        // Position in order of the selected row
+	top        int        // Position in order of the first visible row
+	query      string     // Last search, empty when not searching
+	message    string     // Shown in the footer instead of the key help, e.g. when a search fails
+	y          int        // Line of the header
+	height     int        // Number of rows showing the body, between the header and the footer
+	reserve    int        // Lines left free below the footer when the height follows the terminal, -1 for a fixed height
+	d          *Drawer    // Drawer the table is drawn with
+}
+
+// NewTable creates a table drawn with d, with its header on line y, height rows of body
+// below it and a footer below them.
+func NewTable(d *Drawer, y, height int, columns ...Column) *Table {
+	return &Table{columns: columns, sortColumn: -1, y: y, height: max(height, 1), reserve: -1, d: d}
+}
+
+// NewFillTable creates a table drawn with d from line y to the bottom of the terminal, leaving
+// reserve lines free below its footer. Its height follows the terminal when it is resized.
+func NewFillTable(d *Drawer, y, reserve int, columns ...Column) *Table {
+	t := &Table{columns: columns, sortColumn: -1, y: y, reserve: reserve, d: d}
+	t.layout()
+	return t
+}
+
+// WithFixedRows keeps the first n rows on top of the table when it is sorted
+func (t *Table) WithFixedRows(n int) *Table {
+	t.fixed = n
+	t.sort()
+	return t
+}
+
+// Height returns the number of rows showing the body, not counting the header and the footer.
+func (t *Table) Height() int {
+	return t.height
+}
+
+// SetRows replaces the rows of the table, keeping the sort order and, when its
+// index still exists, the selected row.
+func (t *Table) SetRows(rows [][]string) {
+	selected := t.Selected()
+	t.rows = rows
+	t.sort()
+	t.Select(selected)
+}
+
+// Rows returns the rows of the table in the order they were set.
+func (t *Table) Rows() [][]string {
+	return t.rows
+}
+
+// Selected returns the index of the selected row, or -1 when the table is empty.
+func (t *Table) Selected() int {
+	if t.selected >= len(t.order) {
+		return -1
+	}
+	return t.order[t.selected]
+}
+
+// Select selects the row with index row and scrolls it into view.
+func (t *Table) Select(row int) {
+	for position, index := range t.order {
+		if index == row {
+			t.selected = position
+			break
+		}
+	}
+	t.clamp()
+}
+
+// SortBy sorts the rows by column, in reverse order when descending is set.
+// The selected row stays selected. A column out of range restores the order
+// the rows were set in.
+func (t *Table) SortBy(column int, descending bool) {
+	if column < 0 || column >= len(t.columns) {
+		column, descending = -1, false
+	}
+	selected := t.Selected()
+	t.sortColumn, t.descending = column, descending
+	t.sort()
+	t.Select(selected)
+}
+
+// SortColumn returns the column the rows are sorted by, -1 when unsorted, and whether the order is reversed.
+func (t *Table) SortColumn() (int, bool) {
+	return t.sortColumn, t.descending
+}
+
+// sort computes the display order of the rows.
+func (t *Table) sort() {
+	t.order = make([]int, len(t.rows))
+	for i := range t.order {
+		t.order[i] = i
+	}
+	if t.sortColumn < 0 {
+		return
+	}
+
+	less := t.columns[t.sortColumn].Less
+	if less == nil {
+		column := t.sortColumn
+		less = func(a, b int) bool {
+			return strings.ToLower(t.cell(a, column)) < strings.ToLower(t.cell(b, column))
+		}
+	}
+	sorted := t.order[min(t.fixed, len(t.order)):]
+	sort.SliceStable(sorted, func(i, j int) bool {
+		if t.descending {
+			return less(sorted[j], sorted[i])
+		}
+		return less(sorted[i], sorted[j])
+	})
+}
+
+// cell returns the text of a cell, empty when the row has fewer cells than the table has columns.
+func (t *Table) cell(row, column int) string {
+	if column < len(t.rows[row]) {
+		return t.rows[row][column]
+	}
+	return ""
+}
+
+// layout fits the height of the table to the terminal when it fills the terminal.
+func (t *Table) layout() {
+	if t.reserve >= 0 {
+		_, height := t.d.Size()
+		t.height = max(height-t.y-t.reserve-2, 1)
+	}
+	t.clamp()
+}
+
+// clamp keeps the selection within the rows and scrolls it into view.
+func (t *Table) clamp() {
+	t.selected = min(max(t.selected, 0), max(len(t.order)-1, 0))
+	if t.selected < t.top {
+		t.top = t.selected
+	}
+	if t.selected >= t.top+t.height {
+		t.top = t.selected - t.height + 1
+	}
+	t.top = min(max(t.top, 0), max(len(t.order)-t.height, 0))
+}
+
+// Move moves the selection by delta rows, down when delta is positive.
+func (t *Table) Move(delta int) {
+	t.layout()
+	t.selected += delta
+	t.message = ""
+	t.clamp()
+}
+
+// Search selects the next row after the selected one with a cell containing query,
+// ignoring case and wrapping around at the end. It reports whether a row was found.
+func (t *Table) Search(query string) bool {
+	t.query = query
+	t.message = ""
+	if query == "" {
+		return false
+	}
+	folded := strings.ToLower(query)
+	for i := 1; i <= len(t.order); i++ {
+		position := (t.selected + i) % len(t.order)
+		for _, cell := range t.rows[t.order[position]] {
+			if strings.Contains(strings.ToLower(cell), folded) {
+				t.selected = position
+				t.layout()
+				return true
+			}
+		}
+	}
+	t.message = i18n.T(i18n.ViewNotFound, query)
+	return false
+}
+
+// widths returns the width of each column. Fitted columns take the width of their
+// widest cell or header, and shrink from the widest when the table is wider than
+// the terminal.
+func (t *Table) widths() []int {
+	widths := make([]int, len(t.columns))
+	total := columnGap * max(len(t.columns)-1, 0)
+	for i, column := range t.columns {
+		if column.Width > 0 {
+			widths[i] = column.Width
+		} else {
+			widths[i] = TextWidth(column.Title) + len(ascendingMarker)
+			for row := range t.rows {
+				widths[i] = max(widths[i], TextWidth(t.cell(row, i)))
+			}
+			if column.MaxWidth > 0 {
+				widths[i] = min(widths[i], column.MaxWidth)
+			}
+		}
+		total += widths[i]
+	}
+
+	width, _ := t.d.Size()
+	for total > width {
+		widest := -1
+		for i, column := range t.columns {
+			if column.Width == 0 && widths[i] > minColumnWidth && (widest < 0 || widths[i] > widths[widest]) {
+				widest = i
+			}
+		}
+		if widest < 0 {
+			// Fixed columns are cut at the edge of the screen
+			break
+		}
+		widths[widest]--
+		total--
+	}
+	return widths
+}
+
+// formatRow aligns cells in columns of the given widths, separated by columnGap spaces.
+func (t *Table) formatRow(cells []string, widths []int) string {
+	var line strings.Builder
+	for i, column := range t.columns {
+		if i > 0 {
+			line.WriteString(strings.Repeat(" ", columnGap))
+		}
+		text := ""
+		if i < len(cells) {
+			text = Truncate(cells[i], widths[i])
+		}
+		padding := max(widths[i]-TextWidth(text), 0)
+		switch column.Align {
+		case AlignRight:
+			line.WriteString(strings.Repeat(" ", padding) + text)
+		case AlignCenter:
+			line.WriteString(strings.Repeat(" ", padding/2) + text + strings.Repeat(" ", padding-padding/2))
+		default:
+			line.WriteString(text + strings.Repeat(" ", padding))
+		}
+	}
+	return strings.TrimRight(line.String(), " ")
+}
+
+// Draw renders the header, the visible rows with the selected one highlighted, and the footer.
+func (t *Table) Draw() {
+	t.layout()
+	width, _ := t.d.Size()
+	widths := t.widths()
+
+	header := make([]string, len(t.columns))
+	for i, column := range t.columns {
+		header[i] = column.Title
+		if i == t.sortColumn {
+			marker := ascendingMarker
+			if t.descending {
+				marker = descendingMarker
+			}
+			// The title is cut before the marker, so the sort column stays recognizable
+			header[i] = Truncate(column.Title, widths[i]-len(marker)) + marker
+		}
+	}
+	t.d.drawRow(t.y, width, t.formatRow(header, widths), StyleHeader)
+
+	for i := 0; i < t.height; i++ {
+		position := t.top + i
+		if position >= len(t.order) {
+			t.d.drawRow(t.y+1+i, width, "", StyleNormal)
+			continue
+		}
+		style := StyleNormal
+		if position == t.selected {
+			style = StyleHighlight
+		}
+		t.d.drawRow(t.y+1+i, width, t.formatRow(t.rows[t.order[position]], widths), style)
+	}
+
+	footer := t.message
+	if footer == "" {
+		footer = i18n.T(i18n.TableHelp)
+	}
+	current := 0
+	if len(t.order) > 0 {
+		current = t.selected + 1
+	}
+	t.d.drawRow(t.y+1+t.height, width, i18n.T(i18n.TableFooter, current, len(t.order), footer), StyleMuted)
+	t.d.Flush()
+}
+
+// HandleEvent moves the selection on Up, Down, PgUp, PgDn, Home and End, changes
+// the sort column on Left, Right and Tab or on the digit of the column, reverses
+// the order on 'r', searches on '/' and moves to the next match on 'n'. It reports
+// whether the event was used.
+func (t *Table) HandleEvent(event termbox.Event) bool {
+	if event.Type == termbox.EventResize {
+		t.layout()
+		return true
+	}
+	if event.Type != termbox.EventKey {
+		return false
+	}
+	switch event.Key {
+	case termbox.KeyArrowUp:
+		t.Move(-1)
+	case termbox.KeyArrowDown:
+		t.Move(1)
+	case termbox.KeyPgup:
+		t.Move(-t.height)
+	case termbox.KeyPgdn:
+		t.Move(t.height)
+	case termbox.KeyHome:
+		t.Move(-len(t.order))
+	case termbox.KeyEnd:
+		t.Move(len(t.order))
+	case termbox.KeyArrowRight, termbox.KeyTab:
+		t.cycleSort(1)
+	case termbox.KeyArrowLeft:
+		t.cycleSort(-1)
+	default:
+		switch {
+		case event.Ch >= '1' && event.Ch <= '9' && int(event.Ch-'1') < len(t.columns):
+			// Choosing the sort column again reverses the order
+			column := int(event.Ch - '1')
+			t.SortBy(column, column == t.sortColumn && !t.descending)
+		case event.Ch == 'r':
+			if t.sortColumn >= 0 {
+				t.SortBy(t.sortColumn, !t.descending)
+			}
+		case event.Ch == '/':
+			readSearch(t.d, t, t.query)
+		case event.Ch == 'n':
+			t.Search(t.query)
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// cycleSort sorts by the next column when delta is 1 and the previous one when delta
+// is -1, wrapping around, in ascending order.
+func (t *Table) cycleSort(delta int) {
+	if len(t.columns) == 0 {
+		return
+	}
+	column := t.sortColumn + delta
+	if t.sortColumn < 0 && delta < 0 {
+		column = len(t.columns) - 1
+	}
+	t.SortBy((column+len(t.columns))%len(t.columns), false)
+}
+
+// searchRow returns the last row of the body, where a search query is read above
+// the footer.
+func (t *Table) searchRow() int {
+	return t.y + t.height
+}
+
+// Run draws the table and handles events until one is not used by it, such as
+// Enter or Esc, which is returned so the caller can act on it, e.g. on the
+// row returned by Selected.
+func (t *Table) Run() termbox.Event {
+	return run(t.d, t)
+}
@@ -0,0 +1,41 @@
+package drawer
+
+import (
+	"testing"
+
+	"github.com/nsf/termbox-go"
+)
+
+func TestTableRunSearches(t *testing.T) {
+	screen := NewMemoryScreen(40, 8)
+	d := New(screen)
+	table := NewTable(d, 0, 4, Column{Title: "Name"}, Column{Title: "Address"})
+	table.SetRows([][]string{
+		{"laptop", "100.64.0.1"},
+		{"phone", "100.64.0.2"},
+		{"server", "100.64.0.3"},
+	})
+	screen.Type("/SERVER")
+	screen.PressKey(termbox.KeyEnter, termbox.KeyEnter)
+
+	event := table.Run()
+	if event.Key != termbox.KeyEnter {
+		t.Fatalf("Run returned %+v, want Enter", event)
+	}
+	if table.Selected() != 2 {
+		t.Errorf("selected row = %d, want 2", table.Selected())
+	}
+	if _, _, shown := screen.Cursor(); shown {
+		t.Error("cursor still shown after the search")
+	}
+
+	// A cancelled search keeps the selection
+	screen.Type("/phone")
+	screen.PressKey(termbox.KeyEsc, termbox.KeyEsc)
+	if event := table.Run(); event.Key != termbox.KeyEsc {
+		t.Fatalf("Run returned %+v, want Esc", event)
+	}
+	if table.Selected() != 2 {
+		t.Errorf("selected row after a cancelled search = %d, want 2", table.Selected())
+	}
+}
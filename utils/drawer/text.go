@@ -0,0 +1,90 @@
+package drawer
+
+import (
+	"strings"
+
+	"github.com/mattn/go-runewidth"
+	"github.com/nsf/termbox-go"
+	"github.com/rivo/uniseg"
+)
+
+// Ellipsis marks text truncated to fit its space.
+const Ellipsis = '…'
+
+// glyph is a grapheme cluster as drawn in the cells of a screen.
+type glyph struct {
+	ch    rune // Rune drawn in the first cell
+	width int  // Number of cells, 1 or 2
+}
+
+// glyphs splits text into the glyphs drawn for it. A cell holds a single rune, so
+// a cluster such as a letter with combining accents or an emoji sequence is drawn
+// as its first visible rune, taking the cells of that rune as termbox does. Clusters
+// without a visible rune, such as control characters, are dropped.
+func glyphs(text string) []glyph {
+	var result []glyph
+	graphemes := uniseg.NewGraphemes(text)
+	for graphemes.Next() {
+		for _, r := range graphemes.Runes() {
+			if w := runewidth.RuneWidth(r); w > 0 {
+				result = append(result, glyph{ch: r, width: w})
+				break
+			}
+		}
+	}
+	return result
+}
+
+// TextWidth returns the number of cells text takes on the screen.
+func TextWidth(text string) int {
+	width := 0
+	for _, g := range glyphs(text) {
+		width += g.width
+	}
+	return width
+}
+
+// Truncate shortens text to at most width cells, ending it with Ellipsis when
+// something was cut. Grapheme clusters are never split.
+func Truncate(text string, width int) string {
+	if TextWidth(text) <= width {
+		return text
+	}
+	room := width - runewidth.RuneWidth(Ellipsis)
+	if room < 0 {
+		return ""
+	}
+
+	var result strings.Builder
+	used := 0
+	graphemes := uniseg.NewGraphemes(text)
+	for graphemes.Next() {
+		w := TextWidth(graphemes.Str())
+		if used+w > room {
+			break
+		}
+		result.WriteString(graphemes.Str())
+		used += w
+	}
+	result.WriteRune(Ellipsis)
+	return result.String()
+}
+
+// PadRight truncates text to width cells and fills the rest with spaces.
+func PadRight(text string, width int) string {
+	text = Truncate(text, width)
+	return text + strings.Repeat(" ", max(width-TextWidth(text), 0))
+}
+
+// drawText draws text from column x of line y, stopping before column limit. Text
+// that does not fit is truncated with Ellipsis. It returns the column after the text.
+func (d *Drawer) drawText(x, y int, text string, fg, bg termbox.Attribute, limit int) int {
+	if x+TextWidth(text) > limit {
+		text = Truncate(text, limit-x)
+	}
+	for _, g := range glyphs(text) {
+		d.screen.SetCell(x, y, g.ch, fg, bg)
+		x += g.width
+	}
+	return x
+}
@@ -0,0 +1,127 @@
+package drawer
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/rivo/uniseg"
+)
+
+// Texts whose cells differ from their runes and bytes
+const (
+	cjk       = "台灣網路"                                       // Four wide characters
+	combining = "Cafe\u0301 Noe\u0308l"                      // Accents written as combining marks
+	family    = "\U0001F468\u200d\U0001F469\u200d\U0001F467" // One emoji joined from three with zero width joiners
+	mixed     = "a中" + family + "e\u0301"
+)
+
+func TestTextWidth(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want int
+	}{
+		{name: "empty", text: "", want: 0},
+		{name: "ASCII", text: "tailscale", want: 9},
+		{name: "CJK", text: cjk, want: 8},
+		{name: "combining marks", text: combining, want: 9},
+		{name: "emoji ZWJ sequence", text: family, want: 2},
+		{name: "mixed", text: mixed, want: 6},
+		{name: "control characters", text: "a\tb\x1b", want: 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := TextWidth(tt.text); got != tt.want {
+				t.Errorf("TextWidth(%q) = %d, want %d", tt.text, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTruncate(t *testing.T) {
+	tests := []struct {
+		name  string
+		text  string
+		width int
+		want  string
+	}{
+		{name: "fits", text: "tailscale", width: 9, want: "tailscale"},
+		{name: "ASCII", text: "tailscale", width: 5, want: "tail…"},
+		{name: "no room", text: "tailscale", width: 0, want: ""},
+		{name: "only the ellipsis", text: "tailscale", width: 1, want: "…"},
+		{name: "CJK", text: cjk, width: 6, want: "台灣…"},
+		// A wide character that would overflow the column is left out
+		{name: "CJK odd width", text: cjk, width: 5, want: "台灣…"},
+		{name: "combining marks", text: combining, width: 5, want: "Cafe\u0301…"},
+		{name: "emoji ZWJ sequence", text: "hi " + family + " there", width: 6, want: "hi " + family + "…"},
+		{name: "emoji cut before", text: "hi " + family + " there", width: 5, want: "hi …"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Truncate(tt.text, tt.width); got != tt.want {
+				t.Errorf("Truncate(%q, %d) = %q, want %q", tt.text, tt.width, got, tt.want)
+			}
+		})
+	}
+}
+
+// clusters returns the grapheme clusters of text.
+func clusters(text string) []string {
+	var result []string
+	graphemes := uniseg.NewGraphemes(text)
+	for graphemes.Next() {
+		result = append(result, graphemes.Str())
+	}
+	return result
+}
+
+func TestTruncateKeepsClusters(t *testing.T) {
+	for _, text := range []string{cjk, combining, family, mixed, "x" + family + cjk + combining} {
+		whole := clusters(text)
+		for width := 0; width <= TextWidth(text)+1; width++ {
+			got := Truncate(text, width)
+			if w := TextWidth(got); w > width {
+				t.Errorf("Truncate(%q, %d) = %q takes %d cells", text, width, got, w)
+			}
+			// Every cluster kept is a whole cluster of the text, in order
+			kept := clusters(strings.TrimSuffix(got, string(Ellipsis)))
+			if len(kept) > len(whole) {
+				t.Fatalf("Truncate(%q, %d) = %q has more clusters than the text", text, width, got)
+			}
+			for i, cluster := range kept {
+				if cluster != whole[i] {
+					t.Errorf("Truncate(%q, %d) = %q splits cluster %q", text, width, got, whole[i])
+					break
+				}
+			}
+		}
+	}
+}
+
+func TestPadRight(t *testing.T) {
+	tests := []struct {
+		name  string
+		text  string
+		width int
+		want  string
+	}{
+		{name: "ASCII", text: "ip", width: 5, want: "ip   "},
+		{name: "CJK", text: "名稱", width: 6, want: "名稱  "},
+		{name: "combining marks", text: "e\u0301", width: 3, want: "e\u0301  "},
+		{name: "emoji ZWJ sequence", text: family, width: 4, want: family + "  "},
+		{name: "truncated", text: cjk, width: 5, want: "台灣…"},
+		{name: "exact", text: cjk, width: 8, want: cjk},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := PadRight(tt.text, tt.width)
+			if got != tt.want {
+				t.Errorf("PadRight(%q, %d) = %q, want %q", tt.text, tt.width, got, tt.want)
+			}
+			// Columns padded with it line up
+			if w := TextWidth(got); w != tt.width {
+				t.Errorf("PadRight(%q, %d) takes %d cells", tt.text, tt.width, w)
+			}
+		})
+	}
+}
@@ -0,0 +1,176 @@
+package drawer
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/nsf/termbox-go"
+)
+
+// NoColorEnv is the environment variable asking programs not to use colors, see https://no-color.org.
+const NoColorEnv = "NO_COLOR"
+
+// Style names the role of text. The theme of the drawer decides how each style looks.
+type Style int
+
+// Styles of text, resolved to colors by the theme
+const (
+	StyleNormal    Style = iota // Plain text
+	StyleError                  // Failures
+	StyleWarning                // Problems that need attention and announcements
+	StyleSuccess                // Completed operations
+	StyleHighlight              // The selected item and search matches
+	StyleMuted                  // Secondary text such as key help and footers
+	StyleHeader                 // Titles and column headers
+)
+
+// attributes are the bits of an Attribute that are not a color.
+const attributes = termbox.AttrBold | termbox.AttrBlink | termbox.AttrHidden | termbox.AttrDim |
+	termbox.AttrUnderline | termbox.AttrCursive | termbox.AttrReverse
+
+// Colors are the foreground and background of a style.
+type Colors struct {
+	Fg termbox.Attribute // Foreground color, combined with attributes such as AttrBold
+	Bg termbox.Attribute // Background color
+}
+
+// Theme maps styles to colors.
+type Theme struct {
+	Name      string           // Name selecting the theme in the configuration
+	Styles    map[Style]Colors // Colors of each style, the terminal defaults for missing ones
+	Colorless bool             // Draw attributes only, dropping the colors of options as well
+}
+
+// Colors returns the colors of style.
+func (t *Theme) Colors(style Style) Colors {
+	return t.Styles[style]
+}
+
+// resolve returns the colors drawn for fg and bg, without their colors when the theme is colorless.
+func (t *Theme) resolve(fg, bg termbox.Attribute) (termbox.Attribute, termbox.Attribute) {
+	if t.Colorless {
+		return fg & attributes, bg & attributes
+	}
+	return fg, bg
+}
+
+// Built-in themes
+var (
+	// DarkTheme suits terminals with a dark background and is the default
+	DarkTheme = &Theme{
+		Name: "dark",
+		Styles: map[Style]Colors{
+			StyleError:     {Fg: termbox.ColorLightRed},
+			StyleWarning:   {Fg: termbox.ColorLightYellow},
+			StyleSuccess:   {Fg: termbox.ColorLightGreen},
+			StyleHighlight: {Fg: termbox.AttrReverse},
+			StyleMuted:     {Fg: termbox.ColorCyan},
+			StyleHeader:    {Fg: termbox.AttrBold},
+		},
+	}
+
+	// LightTheme suits terminals with a light background, avoiding pale colors
+	LightTheme = &Theme{
+		Name: "light",
+		Styles: map[Style]Colors{
+			StyleError:     {Fg: termbox.ColorRed},
+			StyleWarning:   {Fg: termbox.ColorMagenta},
+			StyleSuccess:   {Fg: termbox.ColorGreen},
+			StyleHighlight: {Fg: termbox.AttrReverse},
+			StyleMuted:     {Fg: termbox.ColorBlue},
+			StyleHeader:    {Fg: termbox.AttrBold},
+		},
+	}
+
+	// HighContrastTheme draws white on black and marks problems with solid backgrounds
+	HighContrastTheme = &Theme{
+		Name: "high-contrast",
+		Styles: map[Style]Colors{
+			StyleNormal:    {Fg: termbox.ColorWhite, Bg: termbox.ColorBlack},
+			StyleError:     {Fg: termbox.ColorWhite | termbox.AttrBold, Bg: termbox.ColorRed},
+			StyleWarning:   {Fg: termbox.ColorBlack, Bg: termbox.ColorYellow},
+			StyleSuccess:   {Fg: termbox.ColorBlack, Bg: termbox.ColorGreen},
+			StyleHighlight: {Fg: termbox.ColorBlack | termbox.AttrBold, Bg: termbox.ColorWhite},
+			StyleMuted:     {Fg: termbox.ColorWhite, Bg: termbox.ColorBlack},
+			StyleHeader:    {Fg: termbox.ColorWhite | termbox.AttrBold | termbox.AttrUnderline, Bg: termbox.ColorBlack},
+		},
+	}
+
+	// NoColorTheme uses the colors of the terminal and tells styles apart by attributes only
+	NoColorTheme = &Theme{
+		Name: "no-color",
+		Styles: map[Style]Colors{
+			StyleError:     {Fg: termbox.AttrBold},
+			StyleWarning:   {Fg: termbox.AttrBold},
+			StyleHighlight: {Fg: termbox.AttrReverse},
+			StyleHeader:    {Fg: termbox.AttrUnderline},
+		},
+		Colorless: true,
+	}
+)
+
+// themes lists the built-in themes in the order they are documented.
+var themes = []*Theme{DarkTheme, LightTheme, HighContrastTheme, NoColorTheme}
+
+// ThemeNames returns the names of the built-in themes.
+func ThemeNames() []string {
+	names := make([]string, len(themes))
+	for i, theme := range themes {
+		names[i] = theme.Name
+	}
+	return names
+}
+
+// LookupTheme returns the built-in theme named name, ignoring case.
+func LookupTheme(name string) (*Theme, error) {
+	for _, theme := range themes {
+		if strings.EqualFold(theme.Name, name) {
+			return theme, nil
+		}
+	}
+	return nil, fmt.Errorf("unknown theme %q (available: %s)", name, strings.Join(ThemeNames(), ", "))
+}
+
+// DetectTheme returns the theme to use: the one named configured when it is set,
+// otherwise NoColorTheme when NO_COLOR is set, otherwise DarkTheme.
+// It returns an error when configured names no theme.
+func DetectTheme(configured string) (*Theme, error) {
+	if configured != "" {
+		return LookupTheme(configured)
+	}
+	return defaultTheme(), nil
+}
+
+// defaultTheme returns NoColorTheme when NO_COLOR is set, otherwise DarkTheme.
+func defaultTheme() *Theme {
+	if os.Getenv(NoColorEnv) != "" {
+		return NoColorTheme
+	}
+	return DarkTheme
+}
+
+// SetTheme makes the drawer draw styles with theme.
+func (d *Drawer) SetTheme(theme *Theme) {
+	d.theme = theme
+}
+
+// Theme returns the theme the drawer draws styles with.
+func (d *Drawer) Theme() *Theme {
+	return d.theme
+}
+
+// colors returns the colors drawn for opt: those of its style, or its own colors
+// when it has none, falling back to the normal style of the theme.
+func (d *Drawer) colors(opt *DrawerOption) (termbox.Attribute, termbox.Attribute) {
+	if opt.style == StyleNormal && (opt.fg != termbox.ColorDefault || opt.bg != termbox.ColorDefault) {
+		return d.theme.resolve(opt.fg, opt.bg)
+	}
+	return d.styleColors(opt.style)
+}
+
+// styleColors returns the colors drawn for style.
+func (d *Drawer) styleColors(style Style) (termbox.Attribute, termbox.Attribute) {
+	colors := d.theme.Colors(style)
+	return d.theme.resolve(colors.Fg, colors.Bg)
+}
@@ -0,0 +1,31 @@
+package drawer
+
+import "testing"
+
+func TestDetectTheme(t *testing.T) {
+	tests := []struct {
+		name       string
+		configured string
+		noColor    string
+		want       *Theme
+		wantErr    bool
+	}{
+		{name: "default", want: DarkTheme},
+		{name: "NO_COLOR", noColor: "1", want: NoColorTheme},
+		{name: "configured", configured: "light", want: LightTheme},
+		{name: "configured wins over NO_COLOR", configured: "High-Contrast", noColor: "1", want: HighContrastTheme},
+		{name: "unknown", configured: "solarized", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv(NoColorEnv, tt.noColor)
+			theme, err := DetectTheme(tt.configured)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("DetectTheme(%q) error = %v, want error %v", tt.configured, err, tt.wantErr)
+			}
+			if theme != tt.want {
+				t.Errorf("DetectTheme(%q) = %v, want %v", tt.configured, theme, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,384 @@
+package drawer
+
+import (
+	"strings"
+	"tailscale/i18n"
+	"unicode"
+
+	"github.com/nsf/termbox-go"
+	"github.com/rivo/uniseg"
+)
+
+// tabWidth is the number of spaces a tab is expanded to.
+const tabWidth = 4
+
+// viewRow is one screen row of a wrapped line.
+type viewRow struct {
+	line  int    // Index of the line in the buffer
+	start int    // Rune offset of the row in the line, after tab expansion
+	text  string // Part of the line shown on this row
+}
+
+// Viewport is a scrollable region of the screen showing a buffer of lines, with
+// search and line wrapping. Lines longer than the terminal are wrapped using the
+// display width of their grapheme clusters, so wide characters never overflow a row.
+type Viewport struct {
+	y       int       // First line of the viewport
+	height  int       // Number of rows showing the buffer, the footer is drawn below them
+	reserve int       // Rows left free below the footer when the height follows the terminal, -1 for a fixed height
+	lines   []string  // Buffer of lines
+	rows    []viewRow // Lines wrapped to width
+	width   int       // Terminal width the rows were wrapped for
+	top     int       // First visible row
+	query   string    // Current search, empty when not searching
+	matches []int     // Rows where a match of query starts
+	match   int       // Index into matches of the current match
+	message string    // Shown in the footer instead of the key help, e.g. when a search fails
+	d       *Drawer   // Drawer the viewport is drawn with
+}
+
+// NewViewport creates a viewport drawn with d, showing height rows from line y, with a
+// footer on line y+height.
+func NewViewport(d *Drawer, y, height int) *Viewport {
+	return &Viewport{y: y, height: max(height, 1), reserve: -1, d: d}
+}
+
+// NewFillViewport creates a viewport drawn with d from line y to the bottom of the terminal,
+// leaving reserve rows free below its footer. Its height follows the terminal when it is resized.
+func NewFillViewport(d *Drawer, y, reserve int) *Viewport {
+	v := &Viewport{y: y, reserve: reserve, d: d}
+	v.layout()
+	return v
+}
+
+// Height returns the number of rows showing the buffer, not counting the footer.
+func (v *Viewport) Height() int {
+	return v.height
+}
+
+// SetLines replaces the buffer, keeping the scroll position where possible.
+func (v *Viewport) SetLines(lines []string) {
+	v.lines = lines
+	v.width = 0
+	v.wrap()
+	if v.query != "" {
+		v.findMatches()
+	}
+}
+
+// Lines returns the buffer.
+func (v *Viewport) Lines() []string {
+	return v.lines
+}
+
+// Fits reports whether the whole buffer fits in the viewport without scrolling.
+func (v *Viewport) Fits() bool {
+	v.layout()
+	return len(v.rows) <= v.height
+}
+
+// layout fits the viewport to the current terminal size: its height when it fills
+// the terminal, and the wrapping of its rows.
+func (v *Viewport) layout() {
+	if v.reserve >= 0 {
+		_, height := v.d.Size()
+		v.height = max(height-v.y-v.reserve-1, 1)
+		v.clamp()
+	}
+	v.wrap()
+}
+
+// wrap splits the buffer into rows for the current terminal width, if it changed,
+// keeping the first visible line on top.
+func (v *Viewport) wrap() {
+	width, _ := v.d.Size()
+	width = max(width, 1)
+	if width == v.width {
+		return
+	}
+
+	topLine := 0
+	if v.top < len(v.rows) {
+		topLine = v.rows[v.top].line
+	}
+	v.width = width
+	v.rows = v.rows[:0]
+	v.top = 0
+	for i, line := range v.lines {
+		if i == topLine {
+			v.top = len(v.rows)
+		}
+		start := 0
+		for _, text := range WrapText(line, width) {
+			v.rows = append(v.rows, viewRow{line: i, start: start, text: text})
+			start += len([]rune(text))
+		}
+	}
+	v.clamp()
+}
+
+// expandTabs replaces the tabs of text with spaces.
+func expandTabs(text string) string {
+	return strings.ReplaceAll(text, "\t", strings.Repeat(" ", tabWidth))
+}
+
+// WrapText splits text into rows at most width cells wide, expanding tabs
+// and measuring grapheme clusters by their display width as they are drawn.
+// Clusters are never split across rows.
+func WrapText(text string, width int) []string {
+	text = expandTabs(text)
+	var rows []string
+	var row strings.Builder
+	cells := 0
+	graphemes := uniseg.NewGraphemes(text)
+	for graphemes.Next() {
+		w := TextWidth(graphemes.Str())
+		if cells+w > width && cells > 0 {
+			rows = append(rows, row.String())
+			row.Reset()
+			cells = 0
+		}
+		row.WriteString(graphemes.Str())
+		cells += w
+	}
+	return append(rows, row.String())
+}
+
+// clamp keeps the scroll position within the buffer.
+func (v *Viewport) clamp() {
+	v.top = min(max(v.top, 0), max(len(v.rows)-v.height, 0))
+}
+
+// Scroll moves the view by delta rows, towards the end of the buffer when delta is positive.
+func (v *Viewport) Scroll(delta int) {
+	v.layout()
+	v.top += delta
+	v.clamp()
+}
+
+// Search looks for query, ignoring case, and scrolls to its first match from the top of the view.
+// It reports whether the query was found. An empty query ends the search.
+func (v *Viewport) Search(query string) bool {
+	v.layout()
+	v.query = query
+	v.message = ""
+	if query == "" {
+		v.matches = nil
+		return false
+	}
+	v.findMatches()
+	if len(v.matches) == 0 {
+		v.message = i18n.T(i18n.ViewNotFound, query)
+		return false
+	}
+	v.match = 0
+	for i, row := range v.matches {
+		if row >= v.top {
+			v.match = i
+			break
+		}
+	}
+	v.showMatch()
+	return true
+}
+
+// findMatches collects the rows where a match of the query starts. Lines are
+// searched whole, so matches split by wrapping are found too.
+func (v *Viewport) findMatches() {
+	v.matches = v.matches[:0]
+	query := foldRunes(v.query)
+	row := 0
+	for i, line := range v.lines {
+		for _, position := range matchPositions(foldRunes(expandTabs(line)), query) {
+			for row+1 < len(v.rows) && v.rows[row+1].line == i && v.rows[row+1].start <= position {
+				row++
+			}
+			if len(v.matches) == 0 || v.matches[len(v.matches)-1] != row {
+				v.matches = append(v.matches, row)
+			}
+		}
+		// Move to the first row of the next line
+		for row < len(v.rows) && v.rows[row].line == i {
+			row++
+		}
+	}
+	v.match = min(v.match, max(len(v.matches)-1, 0))
+}
+
+// NextMatch scrolls to the next match of the search, wrapping around at the end.
+func (v *Viewport) NextMatch() {
+	if len(v.matches) == 0 {
+		return
+	}
+	v.match = (v.match + 1) % len(v.matches)
+	v.showMatch()
+}
+
+// PrevMatch scrolls to the previous match of the search, wrapping around at the start.
+func (v *Viewport) PrevMatch() {
+	if len(v.matches) == 0 {
+		return
+	}
+	v.match = (v.match + len(v.matches) - 1) % len(v.matches)
+	v.showMatch()
+}
+
+// showMatch scrolls the current match into view and describes it in the footer.
+func (v *Viewport) showMatch() {
+	row := v.matches[v.match]
+	if row < v.top || row >= v.top+v.height {
+		v.top = row - v.height/2
+		v.clamp()
+	}
+	v.message = i18n.T(i18n.ViewMatch, v.match+1, len(v.matches), v.query)
+}
+
+// HandleEvent scrolls on Up, Down, PgUp, PgDn, Space, Home and End, starts a
+// search on '/' and moves between matches on 'n' and 'N'. It reports whether
+// the event was used.
+func (v *Viewport) HandleEvent(event termbox.Event) bool {
+	if event.Type == termbox.EventResize {
+		v.layout()
+		return true
+	}
+	if event.Type != termbox.EventKey {
+		return false
+	}
+	switch event.Key {
+	case termbox.KeyArrowUp:
+		v.Scroll(-1)
+	case termbox.KeyArrowDown:
+		v.Scroll(1)
+	case termbox.KeyPgup:
+		v.Scroll(-v.height)
+	case termbox.KeyPgdn, termbox.KeySpace:
+		v.Scroll(v.height)
+	case termbox.KeyHome:
+		v.Scroll(-len(v.rows))
+	case termbox.KeyEnd:
+		v.Scroll(len(v.rows))
+	default:
+		switch event.Ch {
+		case '/':
+			readSearch(v.d, v, v.query)
+		case 'n':
+			v.NextMatch()
+		case 'N':
+			v.PrevMatch()
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// searchRow returns the last row of the viewport, where a search query is read
+// above the footer.
+func (v *Viewport) searchRow() int {
+	return v.y + v.height - 1
+}
+
+// Draw renders the visible rows, highlighting search matches, and the footer.
+func (v *Viewport) Draw() {
+	v.layout()
+	query := foldRunes(v.query)
+	for i := 0; i < v.height; i++ {
+		v.d.ClearLine(v.y+i, DefaultOptionNoFlush)
+		if v.top+i >= len(v.rows) {
+			continue
+		}
+		row := v.rows[v.top+i]
+		highlighted := make(map[int]bool)
+		if len(query) > 0 {
+			for _, start := range matchPositions(foldRunes(expandTabs(v.lines[row.line])), query) {
+				for j := start; j < start+len(query); j++ {
+					highlighted[j] = true
+				}
+			}
+		}
+		x := 0
+		normalFg, normalBg := v.d.styleColors(StyleNormal)
+		matchFg, matchBg := v.d.styleColors(StyleHighlight)
+		offset := row.start
+		graphemes := uniseg.NewGraphemes(row.text)
+		for graphemes.Next() {
+			fg, bg := normalFg, normalBg
+			for range graphemes.Runes() {
+				if highlighted[offset] {
+					fg, bg = matchFg, matchBg
+				}
+				offset++
+			}
+			// A cluster is drawn as one glyph, as by drawText, so accents stay with their letter
+			for _, g := range glyphs(graphemes.Str()) {
+				v.d.screen.SetCell(x, v.y+i, g.ch, fg, bg)
+				x += g.width
+			}
+		}
+	}
+
+	footer := v.message
+	if footer == "" {
+		footer = i18n.T(i18n.ViewHelp)
+	}
+	last := min(v.top+v.height, len(v.rows))
+	footer = i18n.T(i18n.ViewFooter, min(v.top+1, last), last, len(v.rows), footer)
+	v.d.drawRow(v.y+v.height, v.width, footer, StyleMuted)
+	v.d.Flush()
+}
+
+// Run draws the viewport and handles events until one is not used by it, such
+// as Enter or Esc, which is returned so the caller can act on it.
+func (v *Viewport) Run() termbox.Event {
+	return run(v.d, v)
+}
+
+// foldRunes returns text as runes mapped to lower case one by one, so positions
+// in the result are positions in text.
+func foldRunes(text string) []rune {
+	runes := []rune(text)
+	for i, r := range runes {
+		runes[i] = unicode.ToLower(r)
+	}
+	return runes
+}
+
+// matchPositions returns the rune positions where query starts in text.
+func matchPositions(text, query []rune) []int {
+	if len(query) == 0 {
+		return nil
+	}
+	var positions []int
+	for i := 0; i+len(query) <= len(text); i++ {
+		if string(text[i:i+len(query)]) == string(query) {
+			positions = append(positions, i)
+			i += len(query) - 1
+		}
+	}
+	return positions
+}
+
+// Page shows lines from the cursor, leaving reserve lines free at the bottom of the
+// screen. Lines that fit are printed as usual; otherwise they are shown in a viewport
+// the user scrolls until pressing Enter or Esc. The cursor ends below the shown lines.
+func (d *Drawer) Page(lines []string, reserve int) {
+	viewport := NewFillViewport(d, d.GetY(), reserve)
+	viewport.SetLines(lines)
+	if viewport.Fits() {
+		for _, line := range lines {
+			d.Print(line, DefaultOptionNoFlush)
+		}
+		d.Flush()
+		return
+	}
+
+	for {
+		event := viewport.Run()
+		if event.Type == termbox.EventKey && (event.Key == termbox.KeyEnter || event.Key == termbox.KeyEsc) {
+			break
+		}
+	}
+	for i := 0; i <= viewport.height; i++ {
+		d.NextLine()
+	}
+}
@@ -0,0 +1,110 @@
+package drawer
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"tailscale/i18n"
+
+	"github.com/nsf/termbox-go"
+)
+
+func TestWrapText(t *testing.T) {
+	tests := []struct {
+		name  string
+		text  string
+		width int
+		want  []string
+	}{
+		{name: "empty", text: "", width: 4, want: []string{""}},
+		{name: "fits", text: "abcd", width: 4, want: []string{"abcd"}},
+		{name: "tabs", text: "a\tb", width: 3, want: []string{"a  ", "  b"}},
+		{name: "wide characters", text: "王小明", width: 5, want: []string{"王小", "明"}},
+		{name: "combining accent", text: "cafe\u0301s", width: 4, want: []string{"cafe\u0301", "s"}},
+		{name: "emoji sequence", text: "a👩‍💻b", width: 3, want: []string{"a👩‍💻", "b"}},
+		{name: "narrower than a character", text: "王", width: 1, want: []string{"王"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := WrapText(tt.text, tt.width); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("WrapText(%q, %d) = %q, want %q", tt.text, tt.width, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestViewportDrawsClusters(t *testing.T) {
+	screen := NewMemoryScreen(6, 4)
+	d := New(screen)
+	viewport := NewViewport(d, 0, 3)
+	viewport.SetLines([]string{"cafe\u0301 👩‍💻 ok"})
+	viewport.Search("E\u0301")
+	viewport.Draw()
+
+	// Each cluster is drawn as its first visible rune, in the cells wrapping counted for it
+	for y, want := range []string{"cafe", "👩 ok"} {
+		if got := screen.Line(y); got != want {
+			t.Errorf("line %d = %q, want %q", y, got, want)
+		}
+	}
+	matchFg, _ := d.styleColors(StyleHighlight)
+	for x, want := range []bool{false, false, false, true, false} {
+		if highlighted := screen.Cell(x, 0).Fg == matchFg; highlighted != want {
+			t.Errorf("cell %d highlighted = %v, want %v", x, highlighted, want)
+		}
+	}
+}
+
+func TestViewportRunSearches(t *testing.T) {
+	screen := NewMemoryScreen(40, 6)
+	d := New(screen)
+	viewport := NewViewport(d, 0, 4)
+	viewport.SetLines([]string{"alpha", "beta", "gamma", "delta", "epsilon", "beta two"})
+	screen.Type("/beta")
+	screen.PressKey(termbox.KeyEnter)
+	screen.Type("n")
+	screen.PressKey(termbox.KeyEsc)
+
+	event := viewport.Run()
+	if event.Key != termbox.KeyEsc {
+		t.Fatalf("Run returned %+v, want Esc", event)
+	}
+	if want := i18n.T(i18n.ViewMatch, 2, 2, "beta"); viewport.message != want {
+		t.Errorf("footer message = %q, want %q", viewport.message, want)
+	}
+	if _, _, shown := screen.Cursor(); shown {
+		t.Error("cursor still shown after the search")
+	}
+}
+
+func TestViewportFollowsResize(t *testing.T) {
+	screen := NewMemoryScreen(40, 10)
+	d := New(screen)
+	viewport := NewFillViewport(d, 1, 0)
+	lines := make([]string, 30)
+	for i := range lines {
+		lines[i] = strings.Repeat(string(rune('a'+i%26)), 30)
+	}
+	viewport.SetLines(lines)
+	if viewport.Height() != 8 {
+		t.Fatalf("height at 10 rows = %d, want 8", viewport.Height())
+	}
+
+	// Narrower lines wrap on two rows, and the footer moves up with the bottom of the terminal
+	screen.Resize(20, 6)
+	screen.PressKey(termbox.KeyEsc)
+	viewport.Run()
+	if viewport.Height() != 4 {
+		t.Errorf("height at 6 rows = %d, want 4", viewport.Height())
+	}
+	want := []string{"", strings.Repeat("a", 20), strings.Repeat("a", 10), strings.Repeat("b", 20), strings.Repeat("b", 10)}
+	for y, line := range want {
+		if got := screen.Line(y); got != line {
+			t.Errorf("line %d = %q, want %q", y, got, line)
+		}
+	}
+	if footer := Truncate(i18n.T(i18n.ViewFooter, 1, 4, 60, i18n.T(i18n.ViewHelp)), 20); screen.Line(5) != footer {
+		t.Errorf("footer = %q, want %q", screen.Line(5), footer)
+	}
+}
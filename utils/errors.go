@@ -0,0 +1,178 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"tailscale/broker"
+	"tailscale/i18n"
+	"tailscale/utils/drawer"
+)
+
+// secretFlags lists tailscale flags whose value must never be shown or logged.
+var secretFlags = map[string]bool{
+	"--authkey":  true,
+	"--auth-key": true,
+}
+
+// redactedValue replaces secrets in redacted argument lists.
+const redactedValue = "[REDACTED]"
+
+// CommandError describes a failed tailscale invocation.
+// Args are redacted so the error can be displayed or logged safely.
+type CommandError struct {
+	Subcommand string   // Subcommand that was run, e.g. "login"
+	Args       []string // Full argument list with secrets redacted
+	ExitCode   int      // Exit code of the process, or -1 if it did not exit normally
+	Stdout     string   // What the command printed on stdout
+	Stderr     string   // What the command printed on stderr
+	Err        error    // Underlying error from the runner or context
+}
+
+// Error formats the command, its exit code and the most relevant line of its output.
+func (e *CommandError) Error() string {
+	command := "tailscale " + strings.Join(e.Args, " ")
+	detail := e.detail()
+
+	var msg string
+	if e.ExitCode >= 0 {
+		msg = fmt.Sprintf("%s failed with exit code %d", command, e.ExitCode)
+	} else {
+		msg = fmt.Sprintf("%s failed: %v", command, e.Err)
+	}
+	if detail != "" {
+		msg += ": " + detail
+	}
+	return msg
+}
+
+// Unwrap returns the underlying runner or context error.
+func (e *CommandError) Unwrap() error {
+	return e.Err
+}
+
+// detail returns the first non-empty line of stderr, falling back to stdout.
+func (e *CommandError) detail() string {
+	for _, output := range []string{e.Stderr, e.Stdout} {
+		for _, line := range strings.Split(output, "\n") {
+			if line = strings.TrimSpace(line); line != "" {
+				return line
+			}
+		}
+	}
+	return ""
+}
+
+// output returns the lower-cased stdout and stderr used for classification.
+func (e *CommandError) output() string {
+	return strings.ToLower(e.Stderr + "\n" + e.Stdout)
+}
+
+// RedactArgs returns a copy of args with the values of secret flags and auth keys replaced.
+func RedactArgs(args []string) []string {
+	redacted := make([]string, len(args))
+	for i, arg := range args {
+		switch {
+		case i > 0 && secretFlags[args[i-1]]:
+			redacted[i] = redactedValue
+		case strings.HasPrefix(arg, "tskey-"):
+			redacted[i] = redactedValue
+		default:
+			redacted[i] = arg
+			if name, _, found := strings.Cut(arg, "="); found && secretFlags[name] {
+				redacted[i] = name + "=" + redactedValue
+			}
+		}
+	}
+	return redacted
+}
+
+// newCommandError builds a CommandError for a failed invocation of args.
+func newCommandError(args []string, stdout, stderr string, err error) *CommandError {
+	exitCode := -1
+	var coder interface{ ExitCode() int }
+	if errors.As(err, &coder) {
+		exitCode = coder.ExitCode()
+	}
+	return &CommandError{
+		Subcommand: args[0],
+		Args:       RedactArgs(args),
+		ExitCode:   exitCode,
+		Stdout:     stdout,
+		Stderr:     stderr,
+		Err:        err,
+	}
+}
+
+// Substrings printed by tailscale for each class of failure.
+var (
+	notLoggedInMessages      = []string{"logged out", "needslogin", "not logged in", "log in at:"}
+	daemonNotRunningMessages = []string{"failed to connect to local tailscaled", "doesn't appear to be running", "is tailscaled running", "tailscaled.sock: connect"}
+	permissionDeniedMessages = []string{"access denied", "permission denied", "operation not permitted", "use 'sudo tailscale"}
+	unknownAccountMessages   = []string{"no profile matching", "profile not found", "unknown profile"}
+)
+
+// matches reports whether err is a CommandError whose output contains one of the messages.
+func matches(err error, messages []string) bool {
+	var cmdErr *CommandError
+	if !errors.As(err, &cmdErr) {
+		return false
+	}
+	output := cmdErr.output()
+	for _, message := range messages {
+		if strings.Contains(output, message) {
+			return true
+		}
+	}
+	return false
+}
+
+// IsNotLoggedIn reports whether err was caused by the node being logged out.
+func IsNotLoggedIn(err error) bool {
+	return matches(err, notLoggedInMessages)
+}
+
+// IsDaemonNotRunning reports whether err was caused by tailscaled being unreachable.
+func IsDaemonNotRunning(err error) bool {
+	return matches(err, daemonNotRunningMessages)
+}
+
+// IsPermissionDenied reports whether err was caused by missing privileges.
+func IsPermissionDenied(err error) bool {
+	return matches(err, permissionDeniedMessages)
+}
+
+// IsUnknownAccount reports whether err was caused by switching to an account that does not exist.
+func IsUnknownAccount(err error) bool {
+	return matches(err, unknownAccountMessages)
+}
+
+// Hint returns an actionable suggestion for a classified error, or an empty string.
+func Hint(err error) string {
+	switch {
+	case broker.IsInvalidCredentials(err):
+		return i18n.T(i18n.HintInvalidCredentials)
+	case broker.IsAccountLocked(err):
+		return i18n.T(i18n.HintAccountLocked)
+	case broker.IsRateLimited(err):
+		return i18n.T(i18n.HintRateLimited)
+	case IsDaemonNotRunning(err):
+		return i18n.T(i18n.HintDaemonNotRunning)
+	case IsPermissionDenied(err):
+		return i18n.T(i18n.HintPermissionDenied)
+	case IsUnknownAccount(err):
+		return i18n.T(i18n.HintUnknownAccount)
+	case IsNotLoggedIn(err):
+		return i18n.T(i18n.HintNotLoggedIn)
+	}
+	return ""
+}
+
+// PrintError displays err with d prefixed by prefix, followed by an actionable hint when one is known.
+func PrintError(d *drawer.Drawer, prefix string, err error) {
+	d.Print(fmt.Sprintf("%s: %v", prefix, err), drawer.ErrorOptionNoFlush)
+	if hint := Hint(err); hint != "" {
+		d.Print(hint, drawer.WarningOptionNoFlush)
+	}
+	d.Flush()
+}
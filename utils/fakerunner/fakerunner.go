@@ -0,0 +1,159 @@
+// Package fakerunner provides a scripted stand-in for the tailscale CLI.
+// A Fake satisfies utils.Runner and replays canned outputs, so code in the
+// utils package can be exercised on machines without Tailscale installed.
+package fakerunner
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Response is a canned result for one tailscale invocation.
+type Response struct {
+	Args     []string      `json:"args"`               // Arguments the response answers to
+	Stdout   string        `json:"stdout,omitempty"`   // Standard output printed by the command
+	Stderr   string        `json:"stderr,omitempty"`   // Standard error printed by the command
+	ExitCode int           `json:"exitCode,omitempty"` // Non-zero exit codes make Run fail with an *ExitError
+	Delay    time.Duration `json:"delay,omitempty"`    // Simulated run time, interrupted when the context is done
+}
+
+// ExitError is returned by Run for responses with a non-zero exit code.
+// It mirrors the ExitCode method of *exec.ExitError.
+type ExitError struct {
+	Code int // Exit code of the simulated process
+}
+
+// Error implements the error interface using the wording of os/exec.
+func (e *ExitError) Error() string {
+	return fmt.Sprintf("exit status %d", e.Code)
+}
+
+// ExitCode returns the simulated exit code.
+func (e *ExitError) ExitCode() int {
+	return e.Code
+}
+
+// Fake is a scripted tailscale CLI. Responses registered for the same
+// arguments are replayed in order; the last one keeps being replayed once
+// the queue is exhausted. Every invocation is recorded.
+type Fake struct {
+	mu        sync.Mutex
+	responses map[string][]Response
+	calls     [][]string
+}
+
+// New creates an empty Fake. Any invocation without a registered response fails.
+func New() *Fake {
+	return &Fake{responses: make(map[string][]Response)}
+}
+
+// key builds the lookup key for a list of arguments.
+func key(args []string) string {
+	return strings.Join(args, "\x00")
+}
+
+// Add registers a response that is replayed when the fake is run with its Args.
+func (f *Fake) Add(response Response) *Fake {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	k := key(response.Args)
+	f.responses[k] = append(f.responses[k], response)
+	return f
+}
+
+// On registers a successful response printing stdout for args.
+func (f *Fake) On(stdout string, args ...string) *Fake {
+	return f.Add(Response{Args: args, Stdout: stdout})
+}
+
+// Fail registers a failing response with the given exit code and stderr for args.
+func (f *Fake) Fail(exitCode int, stderr string, args ...string) *Fake {
+	return f.Add(Response{Args: args, Stderr: stderr, ExitCode: exitCode})
+}
+
+// Hang registers a response for args that only returns once the context is done.
+func (f *Fake) Hang(args ...string) *Fake {
+	return f.Add(Response{Args: args, Delay: time.Duration(1<<63 - 1)})
+}
+
+// Run replays the next response registered for args, honouring its Delay.
+// If ctx is done before the delay elapses, ctx.Err() is returned.
+func (f *Fake) Run(ctx context.Context, args ...string) (string, string, error) {
+	response, err := f.next(args)
+	if err != nil {
+		return "", "", err
+	}
+
+	if response.Delay > 0 {
+		timer := time.NewTimer(response.Delay)
+		defer timer.Stop()
+		select {
+		case <-ctx.Done():
+			return "", "", ctx.Err()
+		case <-timer.C:
+		}
+	}
+
+	if response.ExitCode != 0 {
+		return response.Stdout, response.Stderr, &ExitError{Code: response.ExitCode}
+	}
+	return response.Stdout, response.Stderr, nil
+}
+
+// next records the invocation and pops the response registered for args.
+func (f *Fake) next(args []string) (Response, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.calls = append(f.calls, append([]string(nil), args...))
+
+	k := key(args)
+	queue := f.responses[k]
+	if len(queue) == 0 {
+		return Response{}, fmt.Errorf("fakerunner: no response for tailscale %s", strings.Join(args, " "))
+	}
+	response := queue[0]
+	if len(queue) > 1 {
+		f.responses[k] = queue[1:]
+	}
+	return response, nil
+}
+
+// Calls returns a copy of every argument list the fake has been run with, in order.
+func (f *Fake) Calls() [][]string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	calls := make([][]string, len(f.calls))
+	for i, call := range f.calls {
+		calls[i] = append([]string(nil), call...)
+	}
+	return calls
+}
+
+// Called reports whether the fake has been run with exactly args.
+func (f *Fake) Called(args ...string) bool {
+	k := key(args)
+	for _, call := range f.Calls() {
+		if key(call) == k {
+			return true
+		}
+	}
+	return false
+}
+
+// Load reads a JSON array of responses, as written by Recorder.Save, and registers them.
+func (f *Fake) Load(r io.Reader) error {
+	var responses []Response
+	if err := json.NewDecoder(r).Decode(&responses); err != nil {
+		return fmt.Errorf("fakerunner: failed to decode script: %w", err)
+	}
+	for _, response := range responses {
+		f.Add(response)
+	}
+	return nil
+}
@@ -0,0 +1,62 @@
+package fakerunner
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Canned outputs of common tailscale commands.
+const (
+	// Version is the output of `tailscale --version`
+	Version = "1.76.6\n  tailscale commit: 8f5e7a1b2c3d\n  other commit: 1a2b3c4d5e6f\n  go version: go1.23.4\n"
+
+	// SwitchListEmpty is the output of `tailscale switch --list` when no account is logged in
+	SwitchListEmpty = "ID    Tailnet    Account\n"
+
+	// IP is the output of `tailscale ip`
+	IP = "100.101.102.103\nfd7a:115c:a1e0::1\n"
+
+	// NotLoggedIn is printed on stderr by most commands when the node is logged out
+	NotLoggedIn = "Logged out.\n"
+
+	// DaemonNotRunning is printed on stderr when tailscaled cannot be reached
+	DaemonNotRunning = "failed to connect to local tailscaled; it doesn't appear to be running\n"
+
+	// PermissionDenied is printed on stderr when the user may not change preferences
+	PermissionDenied = "checkprefs access denied: use 'sudo tailscale up' or 'tailscale set --operator=$USER'\n"
+
+	// UnknownAccount is printed on stderr by `tailscale switch` for an account that does not exist
+	UnknownAccount = "no profile matching \"nobody@example.com\"\n"
+)
+
+// SwitchList builds the output of `tailscale switch --list` for the given accounts,
+// marking current as the active one.
+func SwitchList(current string, accounts ...string) string {
+	var out strings.Builder
+	out.WriteString("ID    Tailnet              Account\n")
+	for i, account := range accounts {
+		marker := ""
+		if account == current {
+			marker = "*"
+		}
+		tailnet := account
+		if at := strings.IndexByte(account, '@'); at >= 0 {
+			tailnet = account[:at] + "." + account[at+1:]
+		}
+		out.WriteString(fmt.Sprintf("%04x  %-20s %s%s\n", i+1, tailnet, account, marker))
+	}
+	return out.String()
+}
+
+// Status builds a minimal `tailscale status --json` document for a running node
+// with the given host name and no peers.
+func Status(hostName string) string {
+	return fmt.Sprintf(`{
+  "Version": "1.76.6",
+  "BackendState": "Running",
+  "Self": {"ID": "n1", "HostName": %q, "OS": "linux", "TailscaleIPs": ["100.101.102.103"], "Online": true},
+  "Peer": {},
+  "CurrentTailnet": {"Name": "example.com", "MagicDNSSuffix": "example.ts.net"}
+}
+`, hostName)
+}
@@ -0,0 +1,64 @@
+package fakerunner
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"sync"
+)
+
+// runner matches utils.Runner without importing the utils package.
+type runner interface {
+	Run(ctx context.Context, args ...string) (string, string, error)
+}
+
+// exitCoder matches errors exposing a process exit code, such as *exec.ExitError.
+type exitCoder interface {
+	ExitCode() int
+}
+
+// Recorder wraps a real runner and captures every invocation as a Response,
+// so sessions against a real tailscale can be saved and replayed by a Fake.
+type Recorder struct {
+	Runner runner // Runner that actually executes the commands
+
+	mu        sync.Mutex
+	responses []Response
+}
+
+// NewRecorder creates a Recorder in front of r.
+func NewRecorder(r runner) *Recorder {
+	return &Recorder{Runner: r}
+}
+
+// Run executes the command through the wrapped runner and records the result.
+func (r *Recorder) Run(ctx context.Context, args ...string) (string, string, error) {
+	stdout, stderr, err := r.Runner.Run(ctx, args...)
+
+	response := Response{Args: append([]string(nil), args...), Stdout: stdout, Stderr: stderr}
+	if err != nil {
+		response.ExitCode = 1
+		if coder, ok := err.(exitCoder); ok && coder.ExitCode() > 0 {
+			response.ExitCode = coder.ExitCode()
+		}
+	}
+
+	r.mu.Lock()
+	r.responses = append(r.responses, response)
+	r.mu.Unlock()
+	return stdout, stderr, err
+}
+
+// Responses returns a copy of the recorded responses.
+func (r *Recorder) Responses() []Response {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]Response(nil), r.responses...)
+}
+
+// Save writes the recorded responses as a JSON script that Fake.Load understands.
+func (r *Recorder) Save(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(r.Responses())
+}
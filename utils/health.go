@@ -0,0 +1,280 @@
+package utils
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+	"tailscale/i18n"
+	"tailscale/utils/drawer"
+	"time"
+
+	"github.com/nsf/termbox-go"
+)
+
+// DaemonState classifies the state of tailscaled.
+type DaemonState int
+
+// States of tailscaled, from the most to the least serious problem
+const (
+	DaemonUnknown          DaemonState = iota // The state could not be determined
+	DaemonNotRunning                          // tailscaled is not reachable
+	DaemonStopped                             // tailscaled runs but Tailscale was turned off with "tailscale down"
+	DaemonNeedsLogin                          // The machine is not logged in
+	DaemonNeedsMachineAuth                    // The login waits for approval by a tailnet admin
+	DaemonStarting                            // tailscaled is connecting to the tailnet
+	DaemonRunning                             // Connected to the tailnet
+)
+
+// String names the state in English, as reported by the health command.
+func (s DaemonState) String() string {
+	switch s {
+	case DaemonNotRunning:
+		return "not running"
+	case DaemonStopped:
+		return "stopped"
+	case DaemonNeedsLogin:
+		return "needs login"
+	case DaemonNeedsMachineAuth:
+		return "waiting for machine approval"
+	case DaemonStarting:
+		return "starting"
+	case DaemonRunning:
+		return "running"
+	}
+	return "unknown"
+}
+
+// daemonStateLabels are the messages describing each state in the status line.
+var daemonStateLabels = map[DaemonState]i18n.Key{
+	DaemonNotRunning:       i18n.HealthNotRunning,
+	DaemonStopped:          i18n.HealthStopped,
+	DaemonNeedsLogin:       i18n.HealthNeedsLogin,
+	DaemonNeedsMachineAuth: i18n.HealthNeedsMachineAuth,
+	DaemonStarting:         i18n.HealthStarting,
+	DaemonRunning:          i18n.HealthRunning,
+}
+
+// Label describes the state for the status line in the current language.
+func (s DaemonState) Label() string {
+	if label, found := daemonStateLabels[s]; found {
+		return i18n.T(label)
+	}
+	return i18n.T(i18n.HealthUnknown)
+}
+
+// backendStates maps the BackendState reported by tailscaled to a DaemonState.
+var backendStates = map[string]DaemonState{
+	"NoState":          DaemonNeedsLogin,
+	"NeedsLogin":       DaemonNeedsLogin,
+	"NeedsMachineAuth": DaemonNeedsMachineAuth,
+	"Stopped":          DaemonStopped,
+	"Starting":         DaemonStarting,
+	"Running":          DaemonRunning,
+}
+
+// Health is the result of a check of tailscaled.
+type Health struct {
+	State    DaemonState // Classified state of the daemon
+	Backend  string      // BackendState reported by the daemon, empty if it was not reached
+	Warnings []string    // Health warnings reported by the daemon
+	Err      error       // Why the state could not be read, if it could not
+	Checked  time.Time   // When the check ran
+}
+
+// String summarizes the health for the menu header, e.g. "tailscaled: running (1 warning)".
+func (h *Health) String() string {
+	state := h.State.Label()
+	if h.State == DaemonUnknown && h.Backend != "" {
+		state += " (" + h.Backend + ")"
+	}
+	switch len(h.Warnings) {
+	case 0:
+	case 1:
+		state += i18n.T(i18n.HealthOneWarning)
+	default:
+		state += i18n.T(i18n.HealthWarnings, len(h.Warnings))
+	}
+	return i18n.T(i18n.HealthLine, state)
+}
+
+// Hint returns what the user can do about the state, or an empty string when nothing is needed.
+func (h *Health) Hint() string {
+	switch h.State {
+	case DaemonNotRunning:
+		return i18n.T(i18n.HintNotRunning)
+	case DaemonStopped:
+		return i18n.T(i18n.HintStopped)
+	case DaemonNeedsLogin:
+		return i18n.T(i18n.HintNeedsLogin)
+	case DaemonNeedsMachineAuth:
+		return i18n.T(i18n.HintNeedsMachineAuth)
+	case DaemonUnknown:
+		if h.Err != nil {
+			return Hint(h.Err)
+		}
+	}
+	return ""
+}
+
+// CheckHealth reads the state of tailscaled from "tailscale status".
+func CheckHealth(ctx context.Context) *Health {
+	health := &Health{Checked: time.Now()}
+	status, err := GetStatusContext(ctx)
+	if err != nil {
+		health.Err = err
+		if IsDaemonNotRunning(err) {
+			health.State = DaemonNotRunning
+		}
+		return health
+	}
+
+	health.Backend = status.BackendState
+	health.Warnings = status.Health
+	if state, found := backendStates[status.BackendState]; found {
+		health.State = state
+	}
+	return health
+}
+
+// WatchHealth checks tailscaled now and then every interval until ctx is done or stop
+// is called. The channel holds only the latest result, so a slow reader never sees
+// stale health. stop cancels a running check and returns once the goroutine is done,
+// so no command runs behind the caller afterwards. stop may be called more than once.
+func WatchHealth(ctx context.Context, interval time.Duration) (results <-chan *Health, stop func()) {
+	ctx, cancel := context.WithCancel(ctx)
+	out := make(chan *Health, 1)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			checkCtx, cancelCheck := context.WithTimeout(ctx, HealthCheckTimeout)
+			health := CheckHealth(checkCtx)
+			cancelCheck()
+			if ctx.Err() != nil {
+				return
+			}
+
+			// Replace an unread result instead of blocking on it
+			select {
+			case <-out:
+			default:
+			}
+			out <- health
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+	return out, func() {
+		cancel()
+		<-done
+	}
+}
+
+// serviceCommand returns the command starting the Tailscale service on this system.
+func serviceCommand() ([]string, error) {
+	switch runtime.GOOS {
+	case "windows":
+		return []string{"net", "start", "Tailscale"}, nil
+	case "linux":
+		if _, err := exec.LookPath("systemctl"); err != nil {
+			return nil, errors.New("systemd was not found: start tailscaled with your init system")
+		}
+		command := []string{"systemctl", "start", "tailscaled"}
+		if os.Geteuid() != 0 {
+			// The command runs in the background and must not prompt for a password
+			command = append([]string{"sudo", "-n"}, command...)
+		}
+		return command, nil
+	}
+	return nil, fmt.Errorf("starting the Tailscale service is not supported on %s", runtime.GOOS)
+}
+
+// StartDaemon starts the Tailscale service and waits until tailscaled answers,
+// at most DaemonStartTimeout. It returns the health of the started daemon.
+func StartDaemon(ctx context.Context) (*Health, error) {
+	command, err := serviceCommand()
+	if err != nil {
+		return nil, err
+	}
+	output, err := exec.CommandContext(ctx, command[0], command[1:]...).CombinedOutput()
+	if err != nil {
+		detail := strings.TrimSpace(string(output))
+		if command[0] == "sudo" {
+			detail += " (run as root, or run 'sudo -v' first so sudo does not need a password)"
+		}
+		return nil, fmt.Errorf("%s failed: %w: %s", strings.Join(command, " "), err, detail)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, DaemonStartTimeout)
+	defer cancel()
+	for {
+		health := CheckHealth(ctx)
+		if health.State != DaemonNotRunning {
+			return health, nil
+		}
+		select {
+		case <-ctx.Done():
+			return health, fmt.Errorf("tailscaled did not answer within %s after starting the service", DaemonStartTimeout)
+		case <-time.After(DaemonPollInterval):
+		}
+	}
+}
+
+// EnsureDaemon checks that tailscaled is reachable before an operation that needs it.
+// When it is not, it offers to start the service. It reports whether the operation
+// can go ahead, which it cannot when the check is cancelled with Esc.
+func EnsureDaemon(d *drawer.Drawer) bool {
+	var health *Health
+	err := RunCancelable(d, i18n.T(i18n.ServiceChecking), func(ctx context.Context) error {
+		checkCtx, cancel := context.WithTimeout(ctx, HealthCheckTimeout)
+		defer cancel()
+		health = CheckHealth(checkCtx)
+		// A check that timed out leaves the state unknown and the operation goes ahead
+		return ctx.Err()
+	})
+	if err != nil {
+		return false
+	}
+	if health.State != DaemonNotRunning {
+		return true
+	}
+
+	d.Print(health.Hint(), drawer.WarningOptionNoFlush)
+	d.Print(i18n.T(i18n.ServiceStartPrompt), drawer.DefaultOption)
+	for {
+		event := d.PollEvent()
+		if event.Type != termbox.EventKey {
+			continue
+		}
+		if event.Key == termbox.KeyEsc {
+			return false
+		}
+		if event.Key == termbox.KeyEnter {
+			break
+		}
+	}
+
+	err = RunCancelable(d, i18n.T(i18n.ServiceStarting), func(ctx context.Context) error {
+		var err error
+		health, err = StartDaemon(ctx)
+		return err
+	})
+	if err != nil {
+		PrintError(d, i18n.T(i18n.ServiceStartFailed), err)
+		d.Print(i18n.T(i18n.PressEnter), drawer.DefaultOption)
+		d.WaitKey()
+		return false
+	}
+	d.Print(health.String(), drawer.DefaultOption)
+	return true
+}
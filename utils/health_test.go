@@ -0,0 +1,134 @@
+package utils
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nsf/termbox-go"
+
+	"tailscale/i18n"
+	"tailscale/utils/fakerunner"
+)
+
+// backend returns the output of "tailscale status --json" for a daemon in state with warnings.
+func backend(state string, warnings ...string) string {
+	data, err := json.Marshal(map[string]any{"BackendState": state, "Health": warnings})
+	if err != nil {
+		panic(err)
+	}
+	return string(data)
+}
+
+func TestCheckHealth(t *testing.T) {
+	tests := []struct {
+		name         string
+		fake         *fakerunner.Fake
+		want         DaemonState
+		wantBackend  string
+		wantWarnings int
+		wantErr      bool
+	}{
+		{name: "running", fake: fakerunner.New().On(fakerunner.Status("laptop"), "status", "--json"), want: DaemonRunning, wantBackend: "Running"},
+		{name: "warnings", fake: fakerunner.New().On(backend("Running", "dns", "clock"), "status", "--json"), want: DaemonRunning, wantBackend: "Running", wantWarnings: 2},
+		{name: "no state", fake: fakerunner.New().On(backend("NoState"), "status", "--json"), want: DaemonNeedsLogin, wantBackend: "NoState"},
+		{name: "needs login", fake: fakerunner.New().On(backend("NeedsLogin"), "status", "--json"), want: DaemonNeedsLogin, wantBackend: "NeedsLogin"},
+		{name: "needs approval", fake: fakerunner.New().On(backend("NeedsMachineAuth"), "status", "--json"), want: DaemonNeedsMachineAuth, wantBackend: "NeedsMachineAuth"},
+		{name: "stopped", fake: fakerunner.New().On(backend("Stopped"), "status", "--json"), want: DaemonStopped, wantBackend: "Stopped"},
+		{name: "starting", fake: fakerunner.New().On(backend("Starting"), "status", "--json"), want: DaemonStarting, wantBackend: "Starting"},
+		{name: "new backend state", fake: fakerunner.New().On(backend("InUseOtherUser"), "status", "--json"), want: DaemonUnknown, wantBackend: "InUseOtherUser"},
+		{name: "daemon not running", fake: fakerunner.New().Fail(1, fakerunner.DaemonNotRunning, "status", "--json"), want: DaemonNotRunning, wantErr: true},
+		{name: "permission denied", fake: fakerunner.New().Fail(1, fakerunner.PermissionDenied, "status", "--json"), want: DaemonUnknown, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			useRunner(t, tt.fake)
+			health := CheckHealth(context.Background())
+			if health.State != tt.want || health.Backend != tt.wantBackend || len(health.Warnings) != tt.wantWarnings {
+				t.Errorf("CheckHealth() = %v %q %q, want %v %q with %d warnings", health.State, health.Backend, health.Warnings, tt.want, tt.wantBackend, tt.wantWarnings)
+			}
+			if (health.Err != nil) != tt.wantErr {
+				t.Errorf("CheckHealth() error = %v, want error %v", health.Err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestWatchHealth(t *testing.T) {
+	fake := useRunner(t, fakerunner.New().On(backend("Stopped"), "status", "--json").On(fakerunner.Status("laptop"), "status", "--json"))
+	results, stop := WatchHealth(context.Background(), 10*time.Millisecond)
+
+	// Checks are repeated, so the daemon turned on is noticed
+	timeout := time.After(5 * time.Second)
+	for running := false; !running; {
+		select {
+		case health := <-results:
+			running = health.State == DaemonRunning
+		case <-timeout:
+			t.Fatal("WatchHealth did not report the running daemon")
+		}
+	}
+
+	stop()
+	calls := len(fake.Calls())
+	time.Sleep(50 * time.Millisecond)
+	if len(fake.Calls()) != calls {
+		t.Errorf("tailscale run %d times after stop returned", len(fake.Calls())-calls)
+	}
+	stop()
+}
+
+func TestWatchHealthStopCancelsCheck(t *testing.T) {
+	useRunner(t, fakerunner.New().Hang("status", "--json"))
+	_, stop := WatchHealth(context.Background(), time.Hour)
+	// Wait for the check to start before stopping
+	time.Sleep(20 * time.Millisecond)
+
+	stopped := make(chan struct{})
+	go func() {
+		stop()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+	case <-time.After(time.Second):
+		t.Fatal("stop did not cancel the running check")
+	}
+}
+
+func TestEnsureDaemon(t *testing.T) {
+	tests := []struct {
+		name     string
+		fake     *fakerunner.Fake
+		esc      bool   // Whether Esc is pressed
+		escAfter string // Text drawn before Esc is pressed, none when empty
+		want     bool
+	}{
+		{name: "running", fake: fakerunner.New().On(fakerunner.Status("laptop"), "status", "--json"), want: true},
+		{name: "needs login", fake: fakerunner.New().On(backend("NeedsLogin"), "status", "--json"), want: true},
+		{name: "start declined", fake: fakerunner.New().Fail(1, fakerunner.DaemonNotRunning, "status", "--json"), esc: true, escAfter: "Press Enter to start it", want: false},
+		{name: "check cancelled", fake: fakerunner.New().Hang("status", "--json"), esc: true, want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			useLanguage(t, i18n.English)
+			useRunner(t, tt.fake)
+			d, screen := newScreen(80, 10)
+			if tt.esc {
+				// Pressed before the prompt, Esc would cancel the check instead
+				go func() {
+					for !strings.Contains(screen.String(), tt.escAfter) {
+						time.Sleep(time.Millisecond)
+					}
+					screen.PressKey(termbox.KeyEsc)
+				}()
+			}
+
+			if got := EnsureDaemon(d); got != tt.want {
+				t.Errorf("EnsureDaemon() = %v, want %v\n%s", got, tt.want, screen.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,47 @@
+package utils
+
+import (
+	"bytes"
+	"context"
+	"os/exec"
+)
+
+// Runner executes the tailscale CLI with the given arguments.
+// Implementations return what the command printed on stdout and stderr,
+// report failures with an error exposing an ExitCode() int method when the
+// process exited, and must stop the command when ctx is done.
+type Runner interface {
+	Run(ctx context.Context, args ...string) (stdout string, stderr string, err error)
+}
+
+// ExecRunner runs the real tailscale binary.
+type ExecRunner struct {
+	Path string // Path or name of the tailscale executable
+}
+
+// NewExecRunner creates an ExecRunner that resolves "tailscale" from PATH.
+func NewExecRunner() *ExecRunner {
+	return &ExecRunner{Path: "tailscale"}
+}
+
+// Run executes the tailscale binary with args and returns its stdout and stderr.
+// The process is killed when ctx is done.
+func (r *ExecRunner) Run(ctx context.Context, args ...string) (string, string, error) {
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, r.Path, args...)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	err := cmd.Run()
+	return stdout.String(), stderr.String(), err
+}
+
+// runner is the Runner used by every function of the utils package.
+var runner Runner = NewExecRunner()
+
+// SetRunner replaces the Runner used by the utils package and returns the previous one,
+// so callers such as tests can restore it when done.
+func SetRunner(r Runner) Runner {
+	previous := runner
+	runner = r
+	return previous
+}
@@ -0,0 +1,131 @@
+package utils
+
+import (
+	"context"
+	"tailscale/broker"
+	"tailscale/config"
+	"tailscale/credentials"
+)
+
+var (
+	// credentialStore holds the saved broker sessions, created on first use
+	credentialStore *credentials.Store
+
+	// lockout counts failed interactive logins, mirrored in the credential
+	// store so it survives restarts
+	lockout credentials.Lockout
+)
+
+// SetCredentialStore replaces the store of saved broker sessions and returns the previous one.
+func SetCredentialStore(store *credentials.Store) *credentials.Store {
+	previous := credentialStore
+	credentialStore = store
+	return previous
+}
+
+// getCredentialStore returns the credential store, creating the default one on first use.
+func getCredentialStore() (*credentials.Store, error) {
+	if credentialStore == nil {
+		store, err := credentials.DefaultStore()
+		if err != nil {
+			return nil, err
+		}
+		credentialStore = store
+	}
+	return credentialStore, nil
+}
+
+// loadLockout returns the failed logins recorded in the credential store,
+// or the ones of this run when the store cannot be read.
+func loadLockout() credentials.Lockout {
+	if store, err := getCredentialStore(); err == nil {
+		if saved, err := store.Lockout(); err == nil {
+			lockout = saved
+		}
+	}
+	return lockout
+}
+
+// saveLockout records failed logins. Failing to persist them only lets a
+// restart lift the lockout early, so errors are ignored.
+func saveLockout(failures credentials.Lockout) {
+	lockout = failures
+	if store, err := getCredentialStore(); err == nil {
+		store.SaveLockout(failures)
+	}
+}
+
+// loadSession returns the saved session of profile, or nil if there is none.
+func loadSession(profile *config.Profile) (*credentials.Entry, error) {
+	store, err := getCredentialStore()
+	if err != nil {
+		return nil, err
+	}
+	return store.Load(profile.Name)
+}
+
+// saveSession stores the refresh token of a grant for profile. Failing to save
+// only costs the user a password prompt next time, so errors are ignored.
+func saveSession(profile *config.Profile, account string, grant *broker.Grant) {
+	if grant.RefreshToken == "" {
+		return
+	}
+	if store, err := getCredentialStore(); err == nil {
+		store.Save(profile.Name, credentials.Entry{Account: account, RefreshToken: grant.RefreshToken})
+	}
+}
+
+// RequestKey exchanges sky-tailscale credentials for a Tailscale authentication key
+// using the key broker described by profile. When the broker issues a refresh
+// token it is saved, so later logins do not need the password.
+func RequestKey(ctx context.Context, profile *config.Profile, account, password string) (string, error) {
+	client, err := broker.NewFromProfile(profile)
+	if err != nil {
+		return "", err
+	}
+	grant, err := client.Login(ctx, account, password)
+	if err != nil {
+		return "", err
+	}
+	saveSession(profile, account, grant)
+	return grant.Key, nil
+}
+
+// RequestKeyWithSavedSession obtains an authentication key with the saved refresh token of profile.
+// It returns an empty key and no error when nothing is saved. A token rejected by the broker is forgotten.
+func RequestKeyWithSavedSession(ctx context.Context, profile *config.Profile) (key string, account string, err error) {
+	entry, err := loadSession(profile)
+	if err != nil || entry == nil {
+		return "", "", err
+	}
+
+	client, err := broker.NewFromProfile(profile)
+	if err != nil {
+		return "", "", err
+	}
+	grant, err := client.Refresh(ctx, entry.RefreshToken)
+	if err != nil {
+		if broker.IsInvalidCredentials(err) || broker.IsAccountLocked(err) {
+			ForgetCredentials(profile)
+		}
+		return "", "", err
+	}
+
+	if grant.RefreshToken == "" {
+		grant.RefreshToken = entry.RefreshToken
+	}
+	saveSession(profile, entry.Account, grant)
+	return grant.Key, entry.Account, nil
+}
+
+// ForgetCredentials removes the saved session of profile, or of every profile when profile is nil.
+func ForgetCredentials(profile *config.Profile) error {
+	store, err := getCredentialStore()
+	if err != nil {
+		return err
+	}
+	if profile == nil {
+		return store.ForgetAll()
+	}
+	return store.Forget(profile.Name)
+}
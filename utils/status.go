@@ -0,0 +1,408 @@
+package utils
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/netip"
+	"sort"
+	"strings"
+	"tailscale/i18n"
+	"tailscale/utils/drawer"
+	"time"
+)
+
+// PeerStatus describes a single node of the tailnet as reported by `tailscale status --json`.
+type PeerStatus struct {
+	ID             string    `json:"ID"`             // Stable node identifier
+	HostName       string    `json:"HostName"`       // Host name reported by the node
+	DNSName        string    `json:"DNSName"`        // Fully qualified MagicDNS name
+	OS             string    `json:"OS"`             // Operating system of the node
+	TailscaleIPs   []string  `json:"TailscaleIPs"`   // Tailscale IPv4/IPv6 addresses
+	Online         bool      `json:"Online"`         // Whether the node is connected to the coordination server
+	Active         bool      `json:"Active"`         // Whether there is recent traffic with the node
+	ExitNode       bool      `json:"ExitNode"`       // Whether the node is the exit node currently in use
+	ExitNodeOption bool      `json:"ExitNodeOption"` // Whether the node offers itself as an exit node
+	LastSeen       time.Time `json:"LastSeen"`       // Last time the node was seen online
+	Relay          string    `json:"Relay"`          // Home DERP region of the node
+	CurAddr        string    `json:"CurAddr"`        // Direct endpoint in use, empty when relayed
+	RxBytes        int64     `json:"RxBytes"`        // Bytes received from the node
+	TxBytes        int64     `json:"TxBytes"`        // Bytes sent to the node
+}
+
+// TailscaleStatus is the typed model of `tailscale status --json`.
+type TailscaleStatus struct {
+	Version        string        // Version of the local tailscaled
+	BackendState   string        // Backend state such as "Running" or "NeedsLogin"
+	TailnetName    string        // Name of the current tailnet
+	MagicDNSSuffix string        // MagicDNS suffix of the current tailnet
+	Health         []string      // Health warnings reported by the daemon
+	Self           *PeerStatus   // The local node
+	Peers          []*PeerStatus // All other nodes of the tailnet
+}
+
+// rawStatus mirrors the JSON layout produced by `tailscale status --json`.
+type rawStatus struct {
+	Version        string                 `json:"Version"`
+	BackendState   string                 `json:"BackendState"`
+	Health         []string               `json:"Health"`
+	Self           *PeerStatus            `json:"Self"`
+	Peer           map[string]*PeerStatus `json:"Peer"`
+	MagicDNSSuffix string                 `json:"MagicDNSSuffix"`
+	CurrentTailnet *struct {
+		Name           string `json:"Name"`
+		MagicDNSSuffix string `json:"MagicDNSSuffix"`
+	} `json:"CurrentTailnet"`
+}
+
+// StatusSortKey selects the column used to order peers in the status table.
+type StatusSortKey int
+
+// Status table sort keys, in the order they are cycled through by the UI
+const (
+	SortByName       StatusSortKey = iota // Sort by host name
+	SortByIP                              // Sort by first Tailscale IP
+	SortByOS                              // Sort by operating system
+	SortByOnline                          // Sort online peers first
+	SortByConnection                      // Sort direct connections first
+	SortByLastSeen                        // Sort by most recently seen
+	sortKeyCount
+)
+
+// String returns the column title associated with the sort key, as accepted by ParseSortKey.
+func (key StatusSortKey) String() string {
+	switch key {
+	case SortByName:
+		return "Name"
+	case SortByIP:
+		return "IP"
+	case SortByOS:
+		return "OS"
+	case SortByOnline:
+		return "Online"
+	case SortByConnection:
+		return "Connection"
+	case SortByLastSeen:
+		return "Last Seen"
+	}
+	return "Unknown"
+}
+
+// sortKeyTitles are the messages of the column titles shown in the status table.
+var sortKeyTitles = map[StatusSortKey]i18n.Key{
+	SortByName:       i18n.StatusColumnName,
+	SortByIP:         i18n.StatusColumnIP,
+	SortByOS:         i18n.StatusColumnOS,
+	SortByOnline:     i18n.StatusColumnOnline,
+	SortByConnection: i18n.StatusColumnConnection,
+	SortByLastSeen:   i18n.StatusColumnLastSeen,
+}
+
+// Title returns the translated column title of the sort key.
+func (key StatusSortKey) Title() string {
+	if title, found := sortKeyTitles[key]; found {
+		return i18n.T(title)
+	}
+	return key.String()
+}
+
+// Next returns the sort key following key, wrapping around after the last one.
+func (key StatusSortKey) Next() StatusSortKey {
+	return (key + 1) % sortKeyCount
+}
+
+// Prev returns the sort key preceding key, wrapping around before the first one.
+func (key StatusSortKey) Prev() StatusSortKey {
+	return (key + sortKeyCount - 1) % sortKeyCount
+}
+
+// ParseSortKey returns the sort key whose column title matches name, ignoring case and spaces.
+func ParseSortKey(name string) (StatusSortKey, error) {
+	normalized := strings.ToLower(strings.ReplaceAll(name, " ", ""))
+	for key := StatusSortKey(0); key < sortKeyCount; key++ {
+		if strings.ToLower(strings.ReplaceAll(key.String(), " ", "")) == normalized {
+			return key, nil
+		}
+	}
+	return SortByName, fmt.Errorf("unknown sort column: %s", name)
+}
+
+// Name returns the short name of the peer, falling back to the DNS name when the host name is empty.
+func (p *PeerStatus) Name() string {
+	if p.HostName != "" {
+		return p.HostName
+	}
+	return strings.SplitN(p.DNSName, ".", 2)[0]
+}
+
+// PrimaryIP returns the first Tailscale IP of the peer or an empty string if it has none.
+func (p *PeerStatus) PrimaryIP() string {
+	if len(p.TailscaleIPs) == 0 {
+		return ""
+	}
+	return p.TailscaleIPs[0]
+}
+
+// IsDirect reports whether traffic to the peer flows over a direct connection rather than a DERP relay.
+func (p *PeerStatus) IsDirect() bool {
+	return p.CurAddr != ""
+}
+
+// Connection describes how the peer is reached: directly, through a relay, or not at all.
+func (p *PeerStatus) Connection() string {
+	switch {
+	case p.IsDirect():
+		return i18n.T(i18n.StatusDirect, p.CurAddr)
+	case p.Relay != "":
+		return i18n.T(i18n.StatusRelay, p.Relay)
+	}
+	return "-"
+}
+
+// LastSeenText formats the last-seen time of the peer relative to now.
+func (p *PeerStatus) LastSeenText(now time.Time) string {
+	if p.Online {
+		return i18n.T(i18n.StatusNow)
+	}
+	if p.LastSeen.IsZero() {
+		return i18n.T(i18n.StatusNever)
+	}
+	ago := now.Sub(p.LastSeen)
+	switch {
+	case ago < time.Minute:
+		return i18n.T(i18n.StatusJustNow)
+	case ago < time.Hour:
+		return i18n.T(i18n.StatusMinutesAgo, int(ago.Minutes()))
+	case ago < 24*time.Hour:
+		return i18n.T(i18n.StatusHoursAgo, int(ago.Hours()))
+	}
+	return i18n.T(i18n.StatusDaysAgo, int(ago.Hours()/24))
+}
+
+// ParseStatus decodes the output of `tailscale status --json` into a TailscaleStatus.
+// Peers are returned sorted by name.
+func ParseStatus(data []byte) (*TailscaleStatus, error) {
+	var raw rawStatus
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("failed to decode status: %w", err)
+	}
+
+	status := &TailscaleStatus{
+		Version:        raw.Version,
+		BackendState:   raw.BackendState,
+		MagicDNSSuffix: raw.MagicDNSSuffix,
+		Health:         raw.Health,
+		Self:           raw.Self,
+	}
+	if raw.CurrentTailnet != nil {
+		status.TailnetName = raw.CurrentTailnet.Name
+		if raw.CurrentTailnet.MagicDNSSuffix != "" {
+			status.MagicDNSSuffix = raw.CurrentTailnet.MagicDNSSuffix
+		}
+	}
+	for _, peer := range raw.Peer {
+		if peer != nil {
+			status.Peers = append(status.Peers, peer)
+		}
+	}
+	SortPeers(status.Peers, SortByName)
+	return status, nil
+}
+
+// GetStatus runs `tailscale status --json` and returns the parsed status model.
+func GetStatus() (*TailscaleStatus, error) {
+	return GetStatusContext(context.Background())
+}
+
+// GetStatusContext is like GetStatus but stops the command when ctx is done.
+func GetStatusContext(ctx context.Context) (*TailscaleStatus, error) {
+	output, err := ExecutionContext(ctx, "status", "--json")
+	if err != nil {
+		return nil, fmt.Errorf("failed to get status: %w", err)
+	}
+	return ParseStatus([]byte(output))
+}
+
+// SortPeers orders peers in place by the given key. Ties are broken by name.
+func SortPeers(peers []*PeerStatus, key StatusSortKey) {
+	sort.SliceStable(peers, func(i, j int) bool {
+		return peerLess(peers[i], peers[j], key)
+	})
+}
+
+// peerLess reports whether a comes before b when sorting by key. Ties are broken by name.
+func peerLess(a, b *PeerStatus, key StatusSortKey) bool {
+	switch key {
+	case SortByIP:
+		if c := compareIP(a.PrimaryIP(), b.PrimaryIP()); c != 0 {
+			return c < 0
+		}
+	case SortByOS:
+		if a.OS != b.OS {
+			return strings.ToLower(a.OS) < strings.ToLower(b.OS)
+		}
+	case SortByOnline:
+		if a.Online != b.Online {
+			return a.Online
+		}
+	case SortByConnection:
+		if a.IsDirect() != b.IsDirect() {
+			return a.IsDirect()
+		}
+	case SortByLastSeen:
+		if a.Online != b.Online {
+			return a.Online
+		}
+		if !a.LastSeen.Equal(b.LastSeen) {
+			return a.LastSeen.After(b.LastSeen)
+		}
+	}
+	return strings.ToLower(a.Name()) < strings.ToLower(b.Name())
+}
+
+// compareIP compares two IP address strings numerically, placing unparsable addresses last.
+func compareIP(a, b string) int {
+	ipA, errA := netip.ParseAddr(a)
+	ipB, errB := netip.ParseAddr(b)
+	switch {
+	case errA != nil && errB != nil:
+		return strings.Compare(a, b)
+	case errA != nil:
+		return 1
+	case errB != nil:
+		return -1
+	}
+	return ipA.Compare(ipB)
+}
+
+// statusColumns lists the status table columns in display order.
+var statusColumns = []struct {
+	key   StatusSortKey
+	width int
+}{
+	{SortByName, 20},
+	{SortByIP, 16},
+	{SortByOS, 10},
+	{SortByOnline, 10},
+	{SortByConnection, 24},
+	{SortByLastSeen, 12},
+}
+
+// statusRow is a row of the status table: a node and the text of its cells.
+// StatusLines and StatusTable both show the rows built by statusRows.
+type statusRow struct {
+	node  *PeerStatus // Node described by the row
+	cells []string    // Text of the cells, in the order of statusColumns
+}
+
+// statusCells returns the cells of a status table row for node, whose name is
+// marked with "*" when it is the local node and with its exit node role.
+func statusCells(node *PeerStatus, self bool, now time.Time) []string {
+	online := i18n.T(i18n.StatusNo)
+	if node.Online {
+		online = i18n.T(i18n.StatusYes)
+	}
+	name := node.Name()
+	switch {
+	case self:
+		name = "* " + name
+	case node.ExitNode:
+		name += i18n.T(i18n.StatusExitNode)
+	case node.ExitNodeOption:
+		name += i18n.T(i18n.StatusExitOffered)
+	}
+	return []string{name, node.PrimaryIP(), node.OS, online, node.Connection(), node.LastSeenText(now)}
+}
+
+// statusRows returns the rows of the local node and of the peers of status ordered
+// by sortKey. The local node comes first whatever the sort order.
+func statusRows(status *TailscaleStatus, sortKey StatusSortKey, now time.Time) []statusRow {
+	var rows []statusRow
+	if status.Self != nil {
+		rows = append(rows, statusRow{node: status.Self, cells: statusCells(status.Self, true, now)})
+	}
+	peers := append([]*PeerStatus(nil), status.Peers...)
+	SortPeers(peers, sortKey)
+	for _, peer := range peers {
+		rows = append(rows, statusRow{node: peer, cells: statusCells(peer, false, now)})
+	}
+	return rows
+}
+
+// formatStatusRow pads or truncates cells to the status column widths, measured
+// in screen cells so CJK host names stay aligned. One cell separates the columns.
+func formatStatusRow(cells []string) string {
+	var line strings.Builder
+	for i, column := range statusColumns {
+		line.WriteString(drawer.PadRight(drawer.Truncate(cells[i], column.width-1), column.width))
+	}
+	return strings.TrimRight(line.String(), " ")
+}
+
+// StatusSummary returns the lines describing the tailnet above the status table:
+// its name, state and number of peers, then the health warnings of the daemon.
+func StatusSummary(status *TailscaleStatus) []string {
+	lines := []string{i18n.T(i18n.StatusSummary, status.TailnetName, status.BackendState, len(status.Peers))}
+	for _, warning := range status.Health {
+		lines = append(lines, i18n.T(i18n.StatusHealth, warning))
+	}
+	return lines
+}
+
+// StatusLines formats the rows of StatusTable as text lines below the summary, for
+// output that cannot be interactive. The active sort column is marked in the header.
+func StatusLines(status *TailscaleStatus, sortKey StatusSortKey) []string {
+	lines := StatusSummary(status)
+
+	header := make([]string, len(statusColumns))
+	for i, column := range statusColumns {
+		header[i] = column.key.Title()
+		if column.key == sortKey {
+			header[i] += " v"
+		}
+	}
+	lines = append(lines, formatStatusRow(header))
+
+	for _, row := range statusRows(status, sortKey, time.Now()) {
+		lines = append(lines, formatStatusRow(row.cells))
+	}
+	return lines
+}
+
+// StatusTable creates a table drawn with d of the local node and the peers of status from line y
+// to the bottom of the screen, sorted by sortKey. The local node, marked with "*",
+// stays on top whatever the sort order.
+func StatusTable(d *drawer.Drawer, status *TailscaleStatus, sortKey StatusSortKey, y int) *drawer.Table {
+	rows := statusRows(status, sortKey, time.Now())
+	cells := make([][]string, len(rows))
+	for i, row := range rows {
+		cells[i] = row.cells
+	}
+
+	columns := make([]drawer.Column, len(statusColumns))
+	for i, column := range statusColumns {
+		key := column.key
+		columns[i] = drawer.Column{
+			Title:    key.Title(),
+			MaxWidth: column.width,
+			Less: func(a, b int) bool {
+				return peerLess(rows[a].node, rows[b].node, key)
+			},
+		}
+	}
+
+	table := drawer.NewFillTable(d, y, 0, columns...)
+	if status.Self != nil {
+		table.WithFixedRows(1)
+	}
+	table.SetRows(cells)
+	table.SortBy(int(sortKey), false)
+	return table
+}
+
+// RenderStatus prints the status model with d as a table with peers ordered by sortKey.
+// A table taller than the screen is shown in a scrollable viewport, leaving
+// reserve lines free below it.
+func RenderStatus(d *drawer.Drawer, status *TailscaleStatus, sortKey StatusSortKey, reserve int) {
+	d.Page(StatusLines(status, sortKey), reserve)
+}
@@ -0,0 +1,81 @@
+package utils
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"tailscale/i18n"
+)
+
+// tailnet returns a status with the local node and peers differing in every column.
+func tailnet() *TailscaleStatus {
+	return &TailscaleStatus{
+		BackendState: "Running",
+		TailnetName:  "example.com",
+		Self:         &PeerStatus{HostName: "zeta", OS: "windows", TailscaleIPs: []string{"100.64.0.9"}, Online: true},
+		Peers: []*PeerStatus{
+			{HostName: "beta", OS: "linux", TailscaleIPs: []string{"100.64.0.10"}, Online: true, CurAddr: "192.0.2.1:41641", ExitNode: true},
+			{HostName: "alpha", OS: "macOS", TailscaleIPs: []string{"100.64.0.2"}, LastSeen: time.Now().Add(-3 * time.Hour), Relay: "tok"},
+			{HostName: "gamma", OS: "android", TailscaleIPs: []string{"100.64.0.3"}, Online: true, Relay: "fra", ExitNodeOption: true},
+		},
+	}
+}
+
+// names returns the first cell of each row, the node names.
+func names(rows [][]string) string {
+	var result []string
+	for _, row := range rows {
+		result = append(result, row[0])
+	}
+	return strings.Join(result, ", ")
+}
+
+func TestStatusLinesAndTableShowTheSameRows(t *testing.T) {
+	useLanguage(t, i18n.English)
+	tests := []struct {
+		key  StatusSortKey
+		want string
+	}{
+		{key: SortByName, want: "* zeta, alpha, beta (exit), gamma (exit?)"},
+		{key: SortByIP, want: "* zeta, alpha, gamma (exit?), beta (exit)"},
+		{key: SortByOS, want: "* zeta, gamma (exit?), beta (exit), alpha"},
+		{key: SortByOnline, want: "* zeta, beta (exit), gamma (exit?), alpha"},
+		{key: SortByConnection, want: "* zeta, beta (exit), alpha, gamma (exit?)"},
+		{key: SortByLastSeen, want: "* zeta, beta (exit), gamma (exit?), alpha"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.key.String(), func(t *testing.T) {
+			status := tailnet()
+			d, _ := newScreen(120, 20)
+			table := StatusTable(d, status, tt.key, 0)
+
+			// The table lists its rows in display order from Selected after each move
+			var shown [][]string
+			for i := range table.Rows() {
+				if i > 0 {
+					table.Move(1)
+				}
+				shown = append(shown, table.Rows()[table.Selected()])
+			}
+			if got := names(shown); got != tt.want {
+				t.Errorf("table rows = %s, want %s", got, tt.want)
+			}
+
+			// The text version holds the same rows, formatted, below the summary and the header
+			lines := StatusLines(status, tt.key)
+			body := lines[len(StatusSummary(status))+1:]
+			if len(body) != len(shown) {
+				t.Fatalf("StatusLines has %d rows, the table %d", len(body), len(shown))
+			}
+			for i, row := range shown {
+				if body[i] != formatStatusRow(row) {
+					t.Errorf("line %d = %q, want %q", i, body[i], formatStatusRow(row))
+				}
+			}
+			if header := lines[len(StatusSummary(status))]; !strings.Contains(header, tt.key.Title()+" v") {
+				t.Errorf("header %q does not mark the %s column", header, tt.key.Title())
+			}
+		})
+	}
+}
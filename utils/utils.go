@@ -1,16 +1,17 @@
 package utils
 
 import (
-	"bytes"
-	"encoding/json"
+	"context"
+	"errors"
 	"fmt"
-	"net/http"
 	"os"
 	"os/exec"
-	"path/filepath"
 	"runtime"
 	"strings"
+	"tailscale/config"
+	"tailscale/credentials"
 	"tailscale/download"
+	"tailscale/i18n"
 	"tailscale/utils/drawer"
 	"time"
 
@@ -19,143 +20,360 @@ import (
 
 // HasTailscale checks if Tailscale is installed by executing the --version command.
 // It returns true if Tailscale is installed and false otherwise.
-func HasTailscale() bool {
-	drawer.Clear(drawer.DefaultOption)
-	cmd := exec.Command("tailscale", "--version")
-	output, err := cmd.CombinedOutput()
+func HasTailscale(d *drawer.Drawer) bool {
+	d.Clear(drawer.DefaultOption)
+	info, err := GetVersion(context.Background())
 	if err != nil {
-		drawer.Print(fmt.Sprintf("Command execution error: %v", err), drawer.DefaultOptionNoFlush)
+		d.Print(i18n.T(i18n.CommandFailed, err), drawer.ErrorOptionNoFlush)
 		return false
 	}
-	outputStr := string(output)
-	if strings.Contains(outputStr, "go version") {
-		drawer.Print(outputStr, drawer.DefaultOptionNoFlush)
-		return true
-	}
-	return false
+	d.Print(info.String(), drawer.DefaultOptionNoFlush)
+	return true
 }
 
+// Errors of StartMstsc the UI describes in the current language
+var (
+	ErrNotWindows    = errors.New("system is not Windows, cannot start mstsc.exe")                 // Remote Desktop Connection only exists on Windows
+	ErrMstscNotFound = errors.New("mstsc.exe not found, please install Remote Desktop Connection") // Remote Desktop Connection is not installed
+)
+
 // OpenMstsc launches the Windows Remote Desktop Connection (mstsc.exe).
 // This function only works on Windows systems.
-func OpenMstsc() {
+func OpenMstsc(d *drawer.Drawer) {
+	err := StartMstsc("")
+	switch {
+	case err == nil:
+	case errors.Is(err, ErrNotWindows):
+		d.Print(i18n.T(i18n.MstscNotWindows), drawer.ErrorOptionNoFlush)
+	case errors.Is(err, ErrMstscNotFound):
+		d.Print(i18n.T(i18n.MstscNotFound), drawer.ErrorOptionNoFlush)
+	default:
+		d.Print(i18n.T(i18n.MstscFailed, errors.Unwrap(err)), drawer.ErrorOptionNoFlush)
+	}
+}
+
+// StartMstsc launches mstsc.exe without waiting for it, connecting to host when it is not empty.
+// It returns an error on non-Windows systems or when mstsc.exe is missing.
+func StartMstsc(host string) error {
 	if runtime.GOOS != "windows" {
-		drawer.Print("System is not Windows, cannot start mstsc.exe", drawer.DefaultOptionNoFlush)
-		return
+		return ErrNotWindows
 	}
 
 	cmdPath := "C:\\WINDOWS\\system32\\mstsc.exe"
 	if _, err := os.Stat(cmdPath); os.IsNotExist(err) {
-		drawer.Print("mstsc.exe not found, please install Remote Desktop Connection", drawer.DefaultOptionNoFlush)
-		return
+		return ErrMstscNotFound
 	}
 
-	if err := exec.Command(cmdPath).Start(); err != nil {
-		drawer.Print(fmt.Sprintf("Error starting mstsc: %v", err), drawer.DefaultOptionNoFlush)
+	var args []string
+	if host != "" {
+		args = append(args, "/v:"+host)
 	}
+	if err := exec.Command(cmdPath, args...).Start(); err != nil {
+		return fmt.Errorf("failed to start mstsc: %w", err)
+	}
+	return nil
 }
 
-// Execution runs a Tailscale subcommand with the provided arguments.
+// Execution runs a Tailscale subcommand with the provided arguments through the configured Runner.
 // It validates the subcommand against allowed commands and returns the command output.
+// The command is bounded by the subcommand's default timeout.
 func Execution(args ...string) (string, error) {
+	return ExecutionContext(context.Background(), args...)
+}
+
+// ExecutionContext is like Execution but stops the command when ctx is done.
+// The subcommand's default timeout from SubcommandTimeouts still applies on top of ctx.
+// Only stdout is returned, so output meant for parsing is not mixed with warnings.
+func ExecutionContext(ctx context.Context, args ...string) (string, error) {
+	if err := checkSubcommand(args); err != nil {
+		return "", err
+	}
+	stdout, _, err := run(ctx, args...)
+	return stdout, err
+}
+
+// ExecutionOutput is like ExecutionContext but returns what the command printed on
+// stdout followed by stderr, for commands whose messages are shown to the user:
+// tailscale prints the outcome of login, switch and logout on stderr.
+func ExecutionOutput(ctx context.Context, args ...string) (string, error) {
+	if err := checkSubcommand(args); err != nil {
+		return "", err
+	}
+	stdout, stderr, err := run(ctx, args...)
+	if err != nil {
+		return "", err
+	}
+	var lines []string
+	for _, output := range []string{stdout, stderr} {
+		if output = strings.TrimSpace(output); output != "" {
+			lines = append(lines, output)
+		}
+	}
+	return strings.Join(lines, "\n"), nil
+}
+
+// checkSubcommand validates the subcommand of args against AllowedSubcommands.
+func checkSubcommand(args []string) error {
 	if len(args) < 1 {
-		return "", fmt.Errorf("no subcommand provided")
+		return fmt.Errorf("no subcommand provided")
 	}
+	if subcommand := args[0]; !AllowedSubcommands[subcommand] {
+		return fmt.Errorf("invalid subcommand: %s", subcommand)
+	}
+	return nil
+}
 
+// run executes the tailscale CLI through the runner, applying the subcommand timeout.
+// It returns stdout and stderr on success and a *CommandError describing the failure otherwise.
+func run(ctx context.Context, args ...string) (string, string, error) {
 	subcommand := args[0]
-	if !AllowedSubcommands[subcommand] {
-		return "", fmt.Errorf("invalid subcommand: %s", subcommand)
+	timeout, found := SubcommandTimeouts[subcommand]
+	if !found {
+		timeout = DefaultCommandTimeout
+	}
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
 	}
 
-	cmd := exec.Command("tailscale", args...)
-	output, err := cmd.CombinedOutput()
+	stdout, stderr, err := runner.Run(ctx, args...)
+	switch {
+	case errors.Is(ctx.Err(), context.DeadlineExceeded):
+		err = fmt.Errorf("timed out after %s: %w", timeout, context.DeadlineExceeded)
+	case errors.Is(ctx.Err(), context.Canceled):
+		err = fmt.Errorf("cancelled: %w", context.Canceled)
+	}
 	if err != nil {
-		return "", fmt.Errorf("command execution failed: %w", err)
+		return "", "", newCommandError(args, stdout, stderr, err)
 	}
 
-	return string(output), nil
+	return stdout, stderr, nil
 }
 
-// GetUserInput displays a prompt and reads user input from the terminal.
-// It handles special keys like Escape, Enter, and Backspace.
-func GetUserInput(prompt string) string {
-	var inputText strings.Builder
-	cursorX := len(prompt)
-	y := drawer.GetY()
+// RunCancelable runs fn while drawing a spinner labelled with message on the current line.
+// Pressing Esc cancels the context passed to fn. The spinner line is cleared once fn returns.
+func RunCancelable(d *drawer.Drawer, message string, fn func(ctx context.Context) error) error {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
 
+	done := make(chan error, 1)
+	go func() {
+		done <- fn(ctx)
+	}()
+
+	// Events are applied here, so a resize is handled between two frames of the spinner
+	events, stopPolling := d.Poll()
+	defer stopPolling()
+
+	ticker := time.NewTicker(SpinnerInterval)
+	defer ticker.Stop()
+
+	y := d.GetY()
+	frame := 0
 	for {
-		drawer.Render(y, 0, prompt+inputText.String())
-		event := termbox.PollEvent()
-		if event.Type == termbox.EventKey {
-			switch event.Key {
-			case termbox.KeyEsc:
-				return KeyEsc
-			case termbox.KeyEnter:
-				drawer.NextLine()
-				return inputText.String()
-			case termbox.KeyBackspace, termbox.KeyBackspace2:
-				if cursorX > len(prompt) && inputText.Len() > 0 {
-					cursorX--
-					str := inputText.String()
-					inputText.Reset()
-					inputText.WriteString(str[:len(str)-1])
-					drawer.Render(y, cursorX, " ")
-				}
-			default:
-				if event.Ch != 0 {
-					inputText.WriteRune(event.Ch)
-					cursorX++
-				}
+		d.DrawSpinner(y, frame, i18n.T(i18n.Cancelable, message), drawer.DefaultOption)
+		select {
+		case err := <-done:
+			stopPolling()
+			d.ClearLine(y, drawer.DefaultOption)
+			return err
+		case event := <-events:
+			event = d.Apply(event)
+			if event.Type == termbox.EventKey && event.Key == termbox.KeyEsc {
+				cancel()
 			}
+		case <-ticker.C:
+			frame++
 		}
 	}
 }
 
+// GetUserInput displays a prompt with d and reads a line of user input from the terminal.
+// It returns KeyEsc if the user presses Escape.
+func GetUserInput(d *drawer.Drawer, prompt string) string {
+	return readInput(drawer.NewInput(d, prompt).WithMaxLength(MaxInputLength))
+}
+
+// readInput runs input until it is submitted or cancelled, mapping cancellation to KeyEsc.
+func readInput(input *drawer.Input) string {
+	value, ok := input.Read()
+	if !ok {
+		return KeyEsc
+	}
+	return value
+}
+
+// requireValue is an input validator rejecting empty values.
+func requireValue(value string) error {
+	if strings.TrimSpace(value) == "" {
+		return errors.New(i18n.T(i18n.ValueRequired))
+	}
+	return nil
+}
+
 // CheckTailscale verifies if Tailscale is installed and installs it if not found.
-func CheckTailscale() {
-	drawer.Print("Checking for Tailscale...", drawer.DefaultOption)
+func CheckTailscale(d *drawer.Drawer) {
+	d.Print(i18n.T(i18n.InstallChecking), drawer.DefaultOption)
 	config := NewWaitAndExitConfig()
-	if !HasTailscale() {
-		installTailscale()
-		waitAndExit("Installation is complete. Please run it again.", config)
+	if !HasTailscale(d) {
+		installTailscale(d)
+		waitAndExit(d, i18n.T(i18n.InstallComplete), config)
 	}
-	waitAndExit("Environmental inspection complete.", config.WithShouldExit(false))
+	waitAndExit(d, i18n.T(i18n.InstallInspected), config.WithShouldExit(false))
 }
 
 // installTailscale handles the installation of Tailscale based on the operating system.
-func installTailscale() {
+func installTailscale(d *drawer.Drawer) {
+	var err error
 	if runtime.GOOS == "windows" {
-		// Use a temporary directory instead of current directory
-		tmpDir, err := os.MkdirTemp("", "tailscale-installer")
+		err = installWindows(d, InstallTarget())
+	} else if runtime.GOOS == "linux" {
+		err = installLinux(d, InstallTarget())
+	}
+	if err != nil {
+		d.Print(i18n.T(i18n.InstallFailed, err), drawer.ErrorOptionNoFlush)
+		d.Flush()
+		os.Exit(1)
+	}
+}
+
+// installWindows runs the Windows installer of target, downloading and verifying it
+// into the installer cache unless a valid copy is already cached.
+func installWindows(d *drawer.Drawer, target download.Target) error {
+	cache, err := InstallCache()
+	if err != nil {
+		return err
+	}
+	entry, err := cachedInstaller(d, cache, target, download.PlatformWindows)
+	if err != nil {
+		return fmt.Errorf("download error: %w", err)
+	}
+	return download.Install(d, cache.Path(entry), entry.Verification())
+}
+
+// cachedInstaller returns the cached installer of target for platform. It is downloaded
+// when missing, unless the configuration is offline.
+func cachedInstaller(d *drawer.Drawer, cache *download.Cache, target download.Target, platform string) (*download.CacheEntry, error) {
+	if config.Get().Install.Offline {
+		entry, err := cache.Lookup(target, platform)
 		if err != nil {
-			drawer.Print(fmt.Sprintf("Failed to create temp dir: %v", err), drawer.DefaultOption)
-			cleanupAndExit(tmpDir, 1)
+			return nil, fmt.Errorf("offline install: %w (run \"sky-tailscale cache fetch\" on a connected machine and copy the cache)", err)
 		}
+		d.Print(i18n.T(i18n.InstallUsingCached, entry.Name), drawer.DefaultOption)
+		return entry, nil
+	}
+	return cache.Download(context.Background(), d, target, platform)
+}
 
-		exe := filepath.Join(tmpDir, "tailscale-setup-latest.exe")
-		if _, notExist := os.Stat(exe); os.IsNotExist(notExist) {
-			if err := download.DownloadTailscaleWindows(exe); err != nil {
-				drawer.Print(fmt.Sprintf("Download error: %v", err), drawer.DefaultOption)
-				cleanupAndExit(tmpDir, 1)
-			}
-		}
-		if err := download.Install(exe); err != nil {
-			drawer.Print(fmt.Sprintf("Installation error: %v", err), drawer.DefaultOption)
-			cleanupAndExit(tmpDir, 1)
+// InstallTarget returns the Tailscale build selected by the install section of the configuration.
+func InstallTarget() download.Target {
+	install := config.Get().Install
+	return download.Target{Channel: install.Channel, Version: install.Version, Mirror: install.Mirror}
+}
+
+// InstallCache returns the installer cache selected by the install section of the configuration.
+func InstallCache() (*download.Cache, error) {
+	install := config.Get().Install
+	dir, err := install.Cache()
+	if err != nil {
+		return nil, err
+	}
+	return download.NewCache(dir), nil
+}
+
+// installLinux installs target with the package manager of the distribution, or from the
+// cached tarball when offline, showing the plan and then the output of each step in a
+// scrollable log pane.
+func installLinux(d *drawer.Drawer, target download.Target) error {
+	distro, err := download.DetectDistro()
+	if err != nil {
+		return err
+	}
+	plan := download.PlanLinux(distro, target)
+	if config.Get().Install.Offline {
+		cache, err := InstallCache()
+		if err != nil {
+			return err
 		}
-		cleanupAndExit(tmpDir, 0)
-	} else if runtime.GOOS == "linux" {
-		if err := download.DownloadTailscaleLinux(); err != nil {
-			drawer.Print(fmt.Sprintf("Installation error: %v", err), drawer.DefaultOption)
-			os.Exit(1)
+		entry, err := cachedInstaller(d, cache, target, download.LinuxPlatform(runtime.GOARCH))
+		if err != nil {
+			return err
 		}
+		plan = download.PlanLinuxArchive(distro, cache.Path(entry), entry.SHA256)
+	}
+	for _, line := range plan.Describe() {
+		d.Print(line, drawer.DefaultOptionNoFlush)
+	}
+	d.NextLine()
+	d.Flush()
+
+	// Leave room below the footer of the pane for the final message
+	pane := drawer.NewFillLogPane(d, d.GetY(), 2)
+	err = RunWithLog(d, pane, func(ctx context.Context) error {
+		return plan.Run(ctx, pane)
+	})
+
+	// Continue below the pane and its footer, whose height may have followed a resize
+	for i := 0; i <= pane.Height(); i++ {
+		d.NextLine()
+	}
+	if err != nil {
+		return err
 	}
+	d.Print(i18n.T(i18n.InstallSucceeded), drawer.SuccessOption)
+	return nil
 }
 
-// cleanupAndExit removes the temporary directory and exits the program with the given code.
-func cleanupAndExit(tmpDir string, code int) {
-	drawer.Print(fmt.Sprintf("Removing temp dir: %s", tmpDir), drawer.DefaultOption)
-	os.RemoveAll(tmpDir)
-	os.Exit(code)
+// RunWithLog runs fn while pane, drawn with d, shows its output. The user can scroll the pane,
+// cancel with Esc while fn runs, and review the log until pressing Enter. fn runs on its own
+// goroutine and only writes to the pane; the pane is drawn here, as the screen is not safe
+// for concurrent use.
+func RunWithLog(d *drawer.Drawer, pane *drawer.LogPane, fn func(ctx context.Context) error) error {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	pane.Draw()
+
+	done := make(chan error, 1)
+	go func() {
+		done <- fn(ctx)
+	}()
+
+	// Events are applied and handled here while fn writes to the pane, see RunCancelable
+	events, stopPolling := d.Poll()
+	var err error
+running:
+	for {
+		select {
+		case err = <-done:
+			break running
+		case <-pane.Changed():
+			pane.Draw()
+		case event := <-events:
+			event = d.Apply(event)
+			if event.Type == termbox.EventKey && event.Key == termbox.KeyEsc {
+				fmt.Fprintln(pane, i18n.T(i18n.LogCancelling))
+				cancel()
+				continue
+			}
+			pane.HandleEvent(event)
+		}
+	}
+	stopPolling()
+
+	if err != nil {
+		fmt.Fprintln(pane, i18n.T(i18n.LogFailed, err))
+	}
+	fmt.Fprintln(pane, i18n.T(i18n.PressEnter))
+	// The last output of fn is drawn along with the prompt
+	pane.Draw()
+	for {
+		event := d.PollEvent()
+		if event.Type == termbox.EventKey && event.Key == termbox.KeyEnter {
+			return err
+		}
+		pane.HandleEvent(event)
+	}
 }
 
 // NewWaitAndExitConfig creates a WaitAndExitConfig with default values
@@ -180,79 +398,97 @@ func (c *WaitAndExitConfig) WithCountdown(countdown int) *WaitAndExitConfig {
 
 // waitAndExit displays a message and waits for user input or timeout before exiting.
 // If config is nil, default configuration will be used.
-func waitAndExit(msg string, config *WaitAndExitConfig) {
-	drawer.Print(msg, drawer.DefaultOptionNoFlush)
+func waitAndExit(d *drawer.Drawer, msg string, config *WaitAndExitConfig) {
+	d.Print(msg, drawer.DefaultOptionNoFlush)
 
 	if config == nil {
 		config = NewWaitAndExitConfig()
 	}
 
-	done := make(chan struct{})
-	go func() {
-		termbox.PollEvent()
-		close(done)
-	}()
+	// Any key ends the countdown; events are applied here, see RunCancelable
+	events, stopPolling := d.Poll()
+	defer stopPolling()
 
 	ticker := time.NewTicker(time.Second)
 	defer ticker.Stop()
 
-	x := drawer.GetX()
-	y := drawer.GetY()
+	x := d.GetX()
+	y := d.GetY()
 	countdown := config.Countdown
 
 	for {
-		drawer.Render(y+1, x, fmt.Sprintf("\rPress Enter to continue (auto exit in %ds)...", countdown))
+		d.Render(y+1, x, "\r"+i18n.T(i18n.AutoExit, countdown))
 		select {
-		case <-done:
-			drawer.NextLine()
-			drawer.Clear(drawer.DefaultOption)
-			if config.ShouldExit {
-				os.Exit(0)
+		case event := <-events:
+			if d.Apply(event).Type != termbox.EventKey {
+				continue
 			}
-			return
 		case <-ticker.C:
 			countdown--
-			if countdown <= 0 {
-				drawer.NextLine()
-				drawer.Clear(drawer.DefaultOption)
-				if config.ShouldExit {
-					os.Exit(0)
-				}
-				return
+			if countdown > 0 {
+				continue
 			}
 		}
+
+		stopPolling()
+		d.NextLine()
+		d.Clear(drawer.DefaultOption)
+		if config.ShouldExit {
+			os.Exit(0)
+		}
+		return
 	}
 }
 
-// Status executes the Tailscale status command and displays the result.
-func Status() {
-	output, err := Execution("status")
+// Status retrieves the Tailscale status and displays it as a table sorted by peer name.
+func Status(d *drawer.Drawer) {
+	status, err := GetStatus()
 	if err != nil {
-		drawer.Print(fmt.Sprintf("Error getting status: %v", err), drawer.DefaultOption)
+		PrintError(d, i18n.T(i18n.StatusFailed), err)
 		return
 	}
-	drawer.Print(output, drawer.DefaultOption)
+	// Leave room for the Remote Desktop prompt and the final message below the table
+	RenderStatus(d, status, SortByName, 3)
 }
 
 // MyIP retrieves and displays the current Tailscale IP address.
-func MyIP() {
-	drawer.Print("My IP: ", drawer.DefaultOptionNoFlush)
-	ip, err := Execution("ip")
+func MyIP(d *drawer.Drawer) {
+	d.Print(i18n.T(i18n.MyIP), drawer.DefaultOptionNoFlush)
+	ips, err := GetIPs(context.Background())
 	if err != nil {
-		drawer.Print(fmt.Sprintf("Error getting IP: %v", err), drawer.DefaultOption)
+		PrintError(d, i18n.T(i18n.MyIPFailed), err)
 		return
 	}
-	drawer.Print(ip, drawer.DefaultOption)
+	d.Print(strings.Join(ips, "\n"), drawer.DefaultOption)
+}
+
+// GetIPs returns the Tailscale IP addresses of this machine.
+func GetIPs(ctx context.Context) ([]string, error) {
+	output, err := ExecutionContext(ctx, "ip")
+	if err != nil {
+		return nil, err
+	}
+	return strings.Fields(output), nil
 }
 
 // SwitchAccount changes the active Tailscale account to the specified account.
-func SwitchAccount(account string) {
-	output, err := Execution("switch", account)
+func SwitchAccount(d *drawer.Drawer, account string) {
+	var output string
+	err := RunCancelable(d, i18n.T(i18n.SwitchingAccount), func(ctx context.Context) error {
+		var err error
+		output, err = SwitchTo(ctx, account)
+		return err
+	})
 	if err != nil {
-		drawer.Print(fmt.Sprintf("Error switching account: %v", err), drawer.DefaultOption)
+		PrintError(d, i18n.T(i18n.SwitchAccountError), err)
 		return
 	}
-	drawer.Print(output, drawer.DefaultOption)
+	d.Print(output, drawer.DefaultOption)
+}
+
+// SwitchTo makes account the active Tailscale account and returns the command output.
+func SwitchTo(ctx context.Context, account string) (string, error) {
+	return ExecutionOutput(ctx, "switch", account)
 }
 
 // TailscaleAccount represents the structure for storing Tailscale account information.
@@ -293,77 +529,135 @@ func GetAccounts() (*TailscaleAccount, error) {
 }
 
 // GetKey prompts for user credentials and retrieves a Tailscale authentication key.
-func GetKey() (string, error) {
-	account := GetUserInput("Enter your account: ")
+func GetKey(d *drawer.Drawer) (string, error) {
+	account := readInput(drawer.NewInput(d, i18n.T(i18n.AccountPrompt)).WithMaxLength(MaxInputLength).WithValidate(requireValue))
 	if account == KeyEsc {
 		return account, nil
 	}
-	password := GetUserInput("Enter your password: ")
+	password := readInput(drawer.NewInput(d, i18n.T(i18n.PasswordPrompt)).WithMaxLength(MaxInputLength).WithMask('*').WithValidate(requireValue))
 	if password == KeyEsc {
 		return password, nil
 	}
 
-	data := map[string]string{
-		"account":  account,
-		"password": password,
-	}
-	payload, err := json.Marshal(data)
-	if err != nil {
-		return "", fmt.Errorf("failed to marshal credentials: %w", err)
-	}
-
-	resp, err := http.Post(LoginAPIEndpoint, "application/json", bytes.NewBuffer(payload))
-	if err != nil {
-		return "", fmt.Errorf("failed to send login request: %w", err)
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		return "", fmt.Errorf("login failed with status: %d", resp.StatusCode)
-	}
+	var key string
+	err := RunCancelable(d, i18n.T(i18n.Contacting, config.Get().Selected().Title()), func(ctx context.Context) error {
+		var err error
+		key, err = RequestKey(ctx, config.Get().Selected(), account, password)
+		return err
+	})
+	return key, err
+}
 
-	var result struct {
-		Key string `json:"key"`
+// Login handles the Tailscale login process using an authentication key.
+// Saved broker credentials are tried first; otherwise the user is prompted
+// until the login succeeds, is cancelled, or MaxLoginAttempts is reached.
+func Login(d *drawer.Drawer) bool {
+	failures := loadLockout()
+	if remaining := time.Until(failures.LockedUntil); remaining > 0 {
+		d.Print(i18n.T(i18n.LoginLockedWait, remaining.Round(time.Second)), drawer.WarningOptionNoFlush)
+		d.Print(i18n.T(i18n.PressEnter), drawer.DefaultOption)
+		d.WaitKey()
+		return false
 	}
 
-	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
-		return "", fmt.Errorf("failed to decode response: %w", err)
+	if key := savedSessionKey(d); key != "" && loginWithKey(d, key) {
+		return true
 	}
 
-	return result.Key, nil
-}
-
-// Login handles the Tailscale login process using an authentication key.
-func Login() bool {
 	for {
-		key, err := GetKey()
+		key, err := GetKey(d)
 		if key == KeyEsc {
 			return false
 		}
 		if err != nil {
-			drawer.Print(fmt.Sprintf("Login failed: %v", err), drawer.DefaultOption)
-			continue
+			PrintError(d, i18n.T(i18n.LoginFailed), err)
+		} else if loginWithKey(d, key) {
+			saveLockout(credentials.Lockout{})
+			return true
 		}
 
-		drawer.Print("Logging in...", drawer.DefaultOptionNoFlush)
-		output, err := Execution("login", "--authkey", key)
-		if err != nil {
-			drawer.Print(fmt.Sprintf("Login error: %v", err), drawer.DefaultOption)
-			continue
+		failures.Failures++
+		if failures.Failures >= MaxLoginAttempts {
+			saveLockout(credentials.Lockout{LockedUntil: time.Now().Add(LoginLockout)})
+			d.Print(i18n.T(i18n.LoginLocked, LoginLockout), drawer.WarningOptionNoFlush)
+			d.Print(i18n.T(i18n.PressEnter), drawer.DefaultOption)
+			d.WaitKey()
+			return false
 		}
+		saveLockout(failures)
+	}
+}
 
-		drawer.Print("Logged in successfully!", drawer.DefaultOptionNoFlush)
-		drawer.Print(output, drawer.DefaultOption)
-		return true
+// savedSessionKey obtains an auth key with the saved credentials of the selected profile,
+// returning an empty string when there are none or they cannot be used.
+func savedSessionKey(d *drawer.Drawer) string {
+	profile := config.Get().Selected()
+	entry, err := loadSession(profile)
+	if errors.Is(err, credentials.ErrCorrupted) {
+		d.Print(i18n.T(i18n.SavedReset), drawer.WarningOptionNoFlush)
+		d.Flush()
+		return ""
 	}
+	if err != nil {
+		PrintError(d, i18n.T(i18n.SavedUnreadable), err)
+		return ""
+	}
+	if entry == nil {
+		return ""
+	}
+
+	var key string
+	err = RunCancelable(d, i18n.T(i18n.SavedUsing, entry.Account), func(ctx context.Context) error {
+		var err error
+		key, _, err = RequestKeyWithSavedSession(ctx, profile)
+		return err
+	})
+	if err != nil {
+		PrintError(d, i18n.T(i18n.SavedUnusable), err)
+		return ""
+	}
+	return key
+}
+
+// loginWithKey logs in with key while showing a spinner, and reports the outcome.
+func loginWithKey(d *drawer.Drawer, key string) bool {
+	var output string
+	err := RunCancelable(d, i18n.T(i18n.LoggingIn), func(ctx context.Context) error {
+		var err error
+		output, err = LoginWithKey(ctx, key)
+		return err
+	})
+	if err != nil {
+		PrintError(d, i18n.T(i18n.LoginError), err)
+		return false
+	}
+
+	d.Print(i18n.T(i18n.LoggedIn), drawer.SuccessOption)
+	d.Print(output, drawer.DefaultOption)
+	return true
+}
+
+// LoginWithKey logs this machine in to Tailscale with an authentication key.
+func LoginWithKey(ctx context.Context, key string) (string, error) {
+	return ExecutionOutput(ctx, "login", "--authkey", key)
 }
 
 // Logout performs the Tailscale logout operation.
-func Logout() {
-	output, err := Execution("logout")
+func Logout(d *drawer.Drawer) {
+	var output string
+	err := RunCancelable(d, i18n.T(i18n.LoggingOut), func(ctx context.Context) error {
+		var err error
+		output, err = SignOut(ctx)
+		return err
+	})
 	if err != nil {
-		drawer.Print(fmt.Sprintf("Logout error: %v", err), drawer.DefaultOption)
+		PrintError(d, i18n.T(i18n.LogoutError), err)
 		return
 	}
-	drawer.Print(output, drawer.DefaultOption)
+	d.Print(output, drawer.DefaultOption)
+}
+
+// SignOut logs the current account out of Tailscale and returns the command output.
+func SignOut(ctx context.Context) (string, error) {
+	return ExecutionOutput(ctx, "logout")
 }
@@ -1,5 +1,7 @@
 package utils
 
+import "time"
+
 // AllowedSubcommands defines the allowed Tailscale subcommands list.
 var AllowedSubcommands = map[string]bool{
 	"up":        true,
@@ -28,12 +30,62 @@ var AllowedSubcommands = map[string]bool{
 	"update":    true,
 }
 
+// DefaultCommandTimeout bounds subcommands that have no entry in SubcommandTimeouts.
+const DefaultCommandTimeout = 30 * time.Second
+
+// SubcommandTimeouts defines how long each Tailscale subcommand may run before it is killed.
+// A zero duration means the subcommand is never timed out.
+var SubcommandTimeouts = map[string]time.Duration{
+	"--version": 10 * time.Second,
+	"ip":        10 * time.Second,
+	"status":    15 * time.Second,
+	"switch":    30 * time.Second,
+	"logout":    30 * time.Second,
+	"down":      30 * time.Second,
+	"ping":      30 * time.Second,
+	"netcheck":  45 * time.Second,
+	"login":     2 * time.Minute,
+	"up":        2 * time.Minute,
+	"bugreport": 2 * time.Minute,
+	"cert":      2 * time.Minute,
+	"update":    10 * time.Minute,
+	"ssh":       0,
+	"nc":        0,
+	"web":       0,
+	"serve":     0,
+	"funnel":    0,
+}
+
+// SpinnerInterval is the delay between two frames of the spinner shown while a command runs.
+const SpinnerInterval = 100 * time.Millisecond
+
 const (
 	// KeyEsc represents the identifier for the escape key, used for UI control and shortcuts
 	KeyEsc = "ESC"
 
-	// LoginAPIEndpoint defines an API endpoint for user login process
-	LoginAPIEndpoint = "https://sky-tailscale.sky1218.com/api/logIn"
+	// MaxInputLength limits the number of characters accepted by text prompts
+	MaxInputLength = 256
+
+	// MaxLoginAttempts is the number of consecutive failed logins allowed before login is locked
+	MaxLoginAttempts = 5
+
+	// LoginLockout is how long login stays locked after MaxLoginAttempts failures
+	LoginLockout = 5 * time.Minute
+
+	// UpgradeCheckTimeout bounds the check for a Tailscale upgrade at startup
+	UpgradeCheckTimeout = 10 * time.Second
+
+	// HealthCheckTimeout bounds a single check of tailscaled
+	HealthCheckTimeout = 5 * time.Second
+
+	// HealthInterval is the delay between two checks of tailscaled while the menu is shown
+	HealthInterval = 10 * time.Second
+
+	// DaemonStartTimeout is how long tailscaled may take to answer after its service is started
+	DaemonStartTimeout = 15 * time.Second
+
+	// DaemonPollInterval is the delay between two checks while waiting for tailscaled to start
+	DaemonPollInterval = 500 * time.Millisecond
 )
 
 // WaitAndExitConfig contains configuration options for waitAndExit function
@@ -0,0 +1,207 @@
+package utils
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"runtime"
+	"strings"
+	"tailscale/config"
+	"tailscale/download"
+	"tailscale/i18n"
+	"tailscale/utils/drawer"
+)
+
+// VersionInfo describes the installed Tailscale client and the daemon it talks to.
+type VersionInfo struct {
+	Client      download.Version // Version of the tailscale CLI
+	ClientLong  string           // Version line printed by the CLI
+	Commit      string           // Commit of the tailscale repository the CLI was built from
+	OtherCommit string           // Commit of the platform-specific repository, if any
+	GoVersion   string           // Go toolchain the CLI was built with
+	Daemon      download.Version // Version of tailscaled, zero if it could not be reached
+	DaemonLong  string           // Long version reported by tailscaled
+}
+
+// ParseVersionOutput parses the output of "tailscale --version", such as:
+//
+//	1.76.6
+//	  tailscale commit: 2cd4a5e5c...
+//	  other commit: 1c9d1bd1b...
+//	  go version: go1.23.1
+func ParseVersionOutput(output string) (*VersionInfo, error) {
+	lines := strings.Split(strings.TrimSpace(output), "\n")
+	if !strings.Contains(output, "go version") {
+		return nil, fmt.Errorf("unexpected tailscale --version output: %q", lines[0])
+	}
+
+	info := &VersionInfo{ClientLong: strings.TrimSpace(lines[0])}
+	client, err := download.ParseVersion(info.ClientLong)
+	if err != nil {
+		return nil, err
+	}
+	info.Client = client
+
+	for _, line := range lines[1:] {
+		key, value, found := strings.Cut(line, ":")
+		if !found {
+			continue
+		}
+		value = strings.TrimSpace(value)
+		switch strings.TrimSpace(key) {
+		case "tailscale commit":
+			info.Commit = value
+		case "other commit":
+			info.OtherCommit = value
+		case "go version":
+			info.GoVersion = value
+		}
+	}
+	return info, nil
+}
+
+// GetVersion returns the version of the installed client and, when tailscaled is reachable, of the daemon.
+func GetVersion(ctx context.Context) (*VersionInfo, error) {
+	output, _, err := run(ctx, "--version")
+	if err != nil {
+		return nil, err
+	}
+	info, err := ParseVersionOutput(output)
+	if err != nil {
+		return nil, err
+	}
+
+	// The daemon may be stopped or older than the CLI, which is worth knowing but not an error
+	if status, err := GetStatusContext(ctx); err == nil && status.Version != "" {
+		info.DaemonLong = status.Version
+		info.Daemon, _ = download.ParseVersion(status.Version)
+	}
+	return info, nil
+}
+
+// String summarizes the client and daemon versions.
+func (v *VersionInfo) String() string {
+	text := i18n.T(i18n.VersionSummary, v.Client, v.GoVersion)
+	switch {
+	case v.Daemon.IsZero():
+		text += i18n.T(i18n.VersionDaemonDown)
+	case v.Daemon != v.Client:
+		text += i18n.T(i18n.VersionDaemon, v.Daemon)
+	}
+	return text
+}
+
+// Upgrade describes a Tailscale release newer than, or pinned differently from, the installed one.
+type Upgrade struct {
+	Installed download.Version // Version of the installed client
+	Available download.Version // Version to install
+	Target    download.Target  // Target installing Available
+	Pinned    bool             // Whether Available is pinned by the configuration rather than the latest release
+}
+
+// String describes the upgrade for the menu banner.
+func (u *Upgrade) String() string {
+	if u.Available.Less(u.Installed) {
+		return i18n.T(i18n.UpgradePinned, u.Available, u.Installed)
+	}
+	return i18n.T(i18n.UpgradeAvailable, u.Available, u.Installed)
+}
+
+// CheckUpgrade compares the installed client with the configured install target:
+// the pinned version if any, otherwise the latest release of the channel, or the
+// newest cached installer when offline.
+// It returns nil when the installed version is already the right one.
+func CheckUpgrade(ctx context.Context) (*Upgrade, error) {
+	info, err := GetVersion(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	target := InstallTarget()
+	var available download.Version
+	if config.Get().Install.Offline {
+		if available, err = newestCached(target); err != nil {
+			return nil, err
+		}
+	} else if target.Pinned() {
+		if available, err = download.ParseVersion(target.Version); err != nil {
+			return nil, err
+		}
+	} else if available, err = download.LatestVersion(ctx, target); err != nil {
+		return nil, err
+	}
+
+	pinned := target.Pinned()
+	if pinned && available == info.Client || !pinned && !info.Client.Less(available) {
+		return nil, nil
+	}
+	target.Version = available.String()
+	return &Upgrade{Installed: info.Client, Available: available, Target: target, Pinned: pinned}, nil
+}
+
+// newestCached returns the version of the newest cached installer of target for this platform.
+func newestCached(target download.Target) (download.Version, error) {
+	cache, err := InstallCache()
+	if err != nil {
+		return download.Version{}, err
+	}
+	platform := download.PlatformWindows
+	if runtime.GOOS != "windows" {
+		platform = download.LinuxPlatform(runtime.GOARCH)
+	}
+	entry, err := cache.Lookup(target, platform)
+	if err != nil {
+		return download.Version{}, err
+	}
+	return download.ParseVersion(entry.Version)
+}
+
+// UpgradeWithUpdate upgrades through "tailscale update", which replaces the
+// client and daemon using the platform's own mechanism.
+func UpgradeWithUpdate(ctx context.Context, upgrade *Upgrade) (string, error) {
+	return ExecutionOutput(ctx, updateArgs(upgrade)...)
+}
+
+// updateArgs returns the arguments of "tailscale update" installing upgrade. A pinned
+// version is asked for with --version, otherwise the latest release of the channel with
+// --track, as tailscale refuses both together.
+func updateArgs(upgrade *Upgrade) []string {
+	args := []string{"update", "--yes"}
+	if upgrade.Pinned {
+		return append(args, "--version", upgrade.Target.Version)
+	}
+	if upgrade.Target.Channel != "" {
+		args = append(args, "--track", upgrade.Target.Channel)
+	}
+	return args
+}
+
+// UpgradeTailscale installs the release described by upgrade. It tries "tailscale update"
+// first and falls back to the installers of the download package where it is not supported.
+// Offline, it goes straight to the cached installers. Progress is drawn with d.
+func UpgradeTailscale(d *drawer.Drawer, upgrade *Upgrade) error {
+	if !config.Get().Install.Offline {
+		var output string
+		err := RunCancelable(d, i18n.T(i18n.UpgradeRunning, upgrade.Available), func(ctx context.Context) error {
+			var err error
+			output, err = UpgradeWithUpdate(ctx, upgrade)
+			return err
+		})
+		if err == nil {
+			d.Print(strings.TrimSpace(output), drawer.DefaultOption)
+			return nil
+		}
+		if errors.Is(err, context.Canceled) {
+			return err
+		}
+		PrintError(d, i18n.T(i18n.UpgradeFallback), err)
+	}
+
+	switch runtime.GOOS {
+	case "windows":
+		return installWindows(d, upgrade.Target)
+	case "linux":
+		return installLinux(d, upgrade.Target)
+	}
+	return fmt.Errorf("upgrading is not supported on %s", runtime.GOOS)
+}
@@ -0,0 +1,216 @@
+package utils
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"tailscale/config"
+	"tailscale/download"
+	"tailscale/i18n"
+	"tailscale/utils/fakerunner"
+)
+
+// useInstall replaces the install section of the configuration until the test ends.
+func useInstall(t *testing.T, install config.Install) {
+	t.Helper()
+	cfg := config.Get()
+	previous := cfg.Install
+	cfg.Install = install
+	t.Cleanup(func() { cfg.Install = previous })
+}
+
+// newPackageServer serves a package index whose latest release is latest on every channel.
+func newPackageServer(t *testing.T, latest string) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("mode") != "json" {
+			http.NotFound(w, r)
+			return
+		}
+		fmt.Fprintf(w, `{"TarballsVersion": %q, "Tarballs": {"amd64": "tailscale_%s_amd64.tgz"}}`, latest, latest)
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+// version parses a Tailscale version, failing the test if it is invalid.
+func version(t *testing.T, text string) download.Version {
+	t.Helper()
+	v, err := download.ParseVersion(text)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return v
+}
+
+func TestParseVersionOutput(t *testing.T) {
+	info, err := ParseVersionOutput(fakerunner.Version)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := &VersionInfo{
+		Client:      version(t, "1.76.6"),
+		ClientLong:  "1.76.6",
+		Commit:      "8f5e7a1b2c3d",
+		OtherCommit: "1a2b3c4d5e6f",
+		GoVersion:   "go1.23.4",
+	}
+	if !reflect.DeepEqual(info, want) {
+		t.Errorf("ParseVersionOutput = %+v, want %+v", info, want)
+	}
+
+	long, err := ParseVersionOutput("1.77.103-t2a9c7f1d3-g8e4b2a6c\n  tailscale commit: 2a9c7f1d3\n  go version: go1.23.1\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if long.Client != version(t, "1.77.103") || long.ClientLong != "1.77.103-t2a9c7f1d3-g8e4b2a6c" || long.OtherCommit != "" {
+		t.Errorf("ParseVersionOutput of an unstable build = %+v", long)
+	}
+
+	for _, output := range []string{"", "tailscale: command not found", "abc\n  go version: go1.23.4\n"} {
+		if _, err := ParseVersionOutput(output); err == nil {
+			t.Errorf("ParseVersionOutput(%q) succeeded", output)
+		}
+	}
+}
+
+func TestCheckUpgrade(t *testing.T) {
+	tests := []struct {
+		name    string
+		install config.Install
+		latest  string
+		want    *Upgrade
+		wantErr bool
+	}{
+		{
+			name:   "newer release",
+			latest: "1.78.1",
+			want:   &Upgrade{Available: download.Version{Major: 1, Minor: 78, Patch: 1}, Target: download.Target{Version: "1.78.1"}},
+		},
+		{
+			name:    "newer release on a channel",
+			install: config.Install{Channel: "unstable"},
+			latest:  "1.79.5",
+			want:    &Upgrade{Available: download.Version{Major: 1, Minor: 79, Patch: 5}, Target: download.Target{Channel: "unstable", Version: "1.79.5"}},
+		},
+		{name: "up to date", latest: "1.76.6"},
+		{name: "newer than the latest release", latest: "1.74.0"},
+		{name: "pinned to the installed version", install: config.Install{Version: "1.76.6"}, latest: "1.78.1"},
+		{
+			name:    "pinned to an older version",
+			install: config.Install{Version: "1.74.0"},
+			latest:  "1.78.1",
+			want:    &Upgrade{Available: download.Version{Major: 1, Minor: 74}, Target: download.Target{Version: "1.74.0"}, Pinned: true},
+		},
+		{name: "invalid package index", latest: "latest", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			useRunner(t, fakerunner.New().
+				On(fakerunner.Version, "--version").
+				On(fakerunner.Status("laptop"), "status", "--json"))
+			server := newPackageServer(t, tt.latest)
+			tt.install.Mirror = server.URL
+			useInstall(t, tt.install)
+
+			upgrade, err := CheckUpgrade(context.Background())
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("CheckUpgrade error = %v, want error %v", err, tt.wantErr)
+			}
+			if tt.want != nil {
+				tt.want.Installed = version(t, "1.76.6")
+				tt.want.Target.Mirror = server.URL
+			}
+			if !reflect.DeepEqual(upgrade, tt.want) {
+				t.Errorf("CheckUpgrade = %+v, want %+v", upgrade, tt.want)
+			}
+		})
+	}
+}
+
+func TestUpgradeWithUpdate(t *testing.T) {
+	tests := []struct {
+		name    string
+		upgrade *Upgrade
+		want    []string
+	}{
+		{
+			name:    "latest release",
+			upgrade: &Upgrade{Target: download.Target{Version: "1.78.1"}},
+			want:    []string{"update", "--yes"},
+		},
+		{
+			name:    "latest release of a channel",
+			upgrade: &Upgrade{Target: download.Target{Channel: "unstable", Version: "1.79.5"}},
+			want:    []string{"update", "--yes", "--track", "unstable"},
+		},
+		{
+			name:    "pinned version",
+			upgrade: &Upgrade{Target: download.Target{Channel: "stable", Version: "1.74.0"}, Pinned: true},
+			want:    []string{"update", "--yes", "--version", "1.74.0"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := useRunner(t, fakerunner.New().On("Success.\n", tt.want...))
+			output, err := UpgradeWithUpdate(context.Background(), tt.upgrade)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if output != "Success." {
+				t.Errorf("output = %q", output)
+			}
+			if calls := fake.Calls(); !reflect.DeepEqual(calls, [][]string{tt.want}) {
+				t.Errorf("tailscale called with %q, want %q", calls, tt.want)
+			}
+		})
+	}
+}
+
+func TestVersionInfoString(t *testing.T) {
+	tests := []struct {
+		name     string
+		language string
+		daemon   string
+		want     string
+	}{
+		{name: "same daemon", language: i18n.English, daemon: "1.76.6", want: "Tailscale 1.76.6 (go1.23.4)"},
+		{name: "other daemon", language: i18n.English, daemon: "1.74.0", want: "Tailscale 1.76.6 (go1.23.4), daemon 1.74.0"},
+		{name: "no daemon", language: i18n.English, want: "Tailscale 1.76.6 (go1.23.4), daemon not reachable"},
+		{name: "translated", language: i18n.TraditionalChinese, want: "Tailscale 1.76.6（go1.23.4），無法連線至服務"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			useLanguage(t, tt.language)
+			info := &VersionInfo{Client: version(t, "1.76.6"), GoVersion: "go1.23.4"}
+			if tt.daemon != "" {
+				info.Daemon = version(t, tt.daemon)
+			}
+			if got := info.String(); got != tt.want {
+				t.Errorf("String() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestOfflineInstallerNotCached(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("offline install requested %s", r.URL)
+	}))
+	defer server.Close()
+	useInstall(t, config.Install{Mirror: server.URL, CacheDir: t.TempDir(), Offline: true})
+
+	cache, err := InstallCache()
+	if err != nil {
+		t.Fatal(err)
+	}
+	d, _ := newScreen(80, 10)
+	if _, err := cachedInstaller(d, cache, InstallTarget(), download.PlatformWindows); !errors.Is(err, download.ErrNotCached) {
+		t.Errorf("cachedInstaller error = %v, want ErrNotCached", err)
+	}
+}